@@ -1,19 +1,36 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"os"
 
 	"github.com/yiannis54/go-socket-server/internal/app"
 	"github.com/yiannis54/go-socket-server/internal/config"
 )
 
 func main() {
-	cfg, err := config.LoadConfiguration()
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err := app.Run(cfg); err != nil {
+	if printConfig {
+		if err := cfg.WriteRedacted(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	reload := func() (*config.EnvConfig, error) {
+		cfg, _, err := config.Load(os.Args[1:])
+		return cfg, err
+	}
+	if err := app.Run(cfg, reload); err != nil {
 		log.Fatal(err)
 	}
 }
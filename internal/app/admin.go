@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/yiannis54/go-socket-server/internal/sockets"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// Page sizes of ListConnections.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// AdminServer lets operators inspect and manage the hub.
+type AdminServer struct {
+	pb.UnimplementedAdminServiceServer
+	hub *sockets.Hub
+}
+
+func (s *AdminServer) ListConnections(ctx context.Context, req *pb.ListConnectionsRequest) (*pb.ListConnectionsResponse, error) {
+	limit := int(req.GetPageSize())
+	switch {
+	case limit <= 0:
+		limit = defaultPageSize
+	case limit > maxPageSize:
+		limit = maxPageSize
+	}
+
+	connections, next, err := s.hub.Connections(ctx, sockets.ConnectionPage{
+		Tenant: req.GetTenant(),
+		UserID: req.GetUserId(),
+		After:  req.GetPageToken(),
+		Limit:  limit,
+	})
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	res := &pb.ListConnectionsResponse{NextPageToken: next}
+	for _, connection := range connections {
+		res.Connections = append(res.Connections, toProtoConnection(connection))
+	}
+	return res, nil
+}
+
+func (s *AdminServer) ListRooms(ctx context.Context, req *pb.ListRoomsRequest) (*pb.ListRoomsResponse, error) {
+	rooms, err := s.hub.Rooms(ctx, req.GetTenant())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	res := &pb.ListRoomsResponse{}
+	for _, room := range rooms {
+		res.Rooms = append(res.Rooms, &pb.RoomSummary{
+			Name:    room.Name,
+			Members: int32(room.Members), //nolint:gosec // connection counts fit in int32.
+		})
+	}
+	return res, nil
+}
+
+func (s *AdminServer) GetRoom(ctx context.Context, req *pb.GetRoomRequest) (*pb.Room, error) {
+	room, err := s.hub.Room(ctx, req.GetTenant(), req.GetName())
+	if err != nil {
+		return nil, adminError(err)
+	}
+
+	res := &pb.Room{Name: room.Name}
+	for _, member := range room.Members {
+		res.Members = append(res.Members, toProtoConnection(member))
+	}
+	return res, nil
+}
+
+func (s *AdminServer) DisconnectConnection(ctx context.Context, req *pb.DisconnectConnectionRequest) (*empty.Empty, error) {
+	if err := s.hub.DisconnectConnection(ctx, req.GetId()); err != nil {
+		return nil, adminError(err)
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *AdminServer) DisconnectUser(ctx context.Context, req *pb.DisconnectUserRequest) (*pb.DisconnectUserResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	n, err := s.hub.DisconnectUser(ctx, req.GetTenant(), req.GetUserId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &pb.DisconnectUserResponse{
+		Disconnected: int32(n), //nolint:gosec // connection counts fit in int32.
+	}, nil
+}
+
+func (s *AdminServer) KickFromRoom(ctx context.Context, req *pb.KickFromRoomRequest) (*pb.KickFromRoomResponse, error) {
+	if req.GetUserId() == "" && req.GetConnectionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id or connection id is required")
+	}
+
+	n, err := s.hub.KickFromRoom(ctx, req.GetTenant(), req.GetRoom(), req.GetUserId(), req.GetConnectionId())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &pb.KickFromRoomResponse{
+		Removed: int32(n), //nolint:gosec // connection counts fit in int32.
+	}, nil
+}
+
+func (s *AdminServer) CloseRoom(ctx context.Context, req *pb.CloseRoomRequest) (*pb.CloseRoomResponse, error) {
+	n, err := s.hub.CloseRoom(ctx, req.GetTenant(), req.GetName())
+	if err != nil {
+		return nil, adminError(err)
+	}
+	return &pb.CloseRoomResponse{
+		Removed: int32(n), //nolint:gosec // connection counts fit in int32.
+	}, nil
+}
+
+func adminError(err error) error {
+	if errors.Is(err, sockets.ErrConnectionNotFound) || errors.Is(err, sockets.ErrRoomNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return hubError(err)
+}
+
+func toProtoConnection(info sockets.ConnectionInfo) *pb.Connection {
+	return &pb.Connection{
+		Id:          info.ID,
+		Tenant:      info.Tenant,
+		UserId:      info.UserID,
+		Ip:          info.IP,
+		ConnectedAt: timestamppb.New(info.ConnectedAt),
+		Rooms:       info.Rooms,
+		QueueDepth:  int32(info.QueueDepth), //nolint:gosec // buffer sizes fit in int32.
+	}
+}
+
+// adminInterceptor requires the admin token, sent as "authorization: Bearer
+// <token>" metadata, on every AdminService call. Other services pass through.
+// The service is disabled while the token is empty, as it is only exposed
+// once it has a credential of its own.
+func adminInterceptor(keys *apiKeys) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, "/"+pb.AdminService_ServiceDesc.ServiceName+"/") {
+			return handler(ctx, req)
+		}
+		token := keys.load().adminToken
+		if token == "" {
+			return nil, status.Error(codes.Unimplemented, "admin service is disabled")
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			bearer, ok := strings.CutPrefix(value, "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "admin token required")
+	}
+}
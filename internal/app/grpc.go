@@ -2,15 +2,27 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"maps"
 	"net"
+	"slices"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 
+	"github.com/yiannis54/go-socket-server/internal/certs"
 	"github.com/yiannis54/go-socket-server/internal/config"
+	"github.com/yiannis54/go-socket-server/internal/metrics"
 	"github.com/yiannis54/go-socket-server/internal/notifications"
+	"github.com/yiannis54/go-socket-server/internal/scheduler"
 	"github.com/yiannis54/go-socket-server/internal/sockets"
 	pb "github.com/yiannis54/go-socket-server/notificationspb"
 )
@@ -18,78 +30,414 @@ import (
 type NotificationServer struct {
 	pb.UnimplementedNotificationServiceServer
 	notificationsClient *notifications.Client
+	scheduler           *scheduler.Scheduler
+	dedup               deduplicator
 }
 
-func runRpc(ctx context.Context, notificationsClient *notifications.Client, cfg *config.EnvConfig) error {
+// deduplicator detects repeated publishes by idempotency key. The local
+// idempotency.Cache implements it; a backplane shared by several nodes can
+// provide its own implementation so that retries landing on another node are
+// detected as well.
+type deduplicator interface {
+	Claim(key string) bool
+	Forget(key string)
+}
+
+// newRpcServer returns the server of the notification and admin services,
+// authenticating callers with the credentials in force. It serves TLS when
+// certStore is not nil, unless the HTTP server terminates it on a single port.
+func newRpcServer(notificationServer *NotificationServer, adminServer *AdminServer, keys *apiKeys, certStore *certs.Store, cfg *config.EnvConfig) *grpc.Server {
+	grpcServer := grpc.NewServer(rpcServerOptions(keys, certStore, cfg)...)
+	pb.RegisterNotificationServiceServer(grpcServer, notificationServer)
+	pb.RegisterAdminServiceServer(grpcServer, adminServer)
+	return grpcServer
+}
+
+// rpcServerOptions returns the credentials and interceptors of the gRPC
+// server.
+func rpcServerOptions(keys *apiKeys, certStore *certs.Store, cfg *config.EnvConfig) []grpc.ServerOption {
+	mutualTLS := certStore != nil && cfg.TLS.ClientCAFile != ""
+	// The identity is resolved first, so that the caller is authenticated
+	// before its credentials are checked.
+	identities := identityAuth{keys: keys, mutualTLS: mutualTLS}
+	tenants := tenantAuth{keys: keys}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(identities.unary, adminInterceptor(keys), tenants.unary),
+		grpc.ChainStreamInterceptor(identities.stream, tenants.stream),
+	}
+
+	// On a single port, browsers share the listener without client
+	// certificates, so the handshake only verifies those presented and
+	// identityAuth requires one on calls.
+	if certStore != nil && !cfg.SinglePort {
+		clientAuth := tls.NoClientCert
+		if mutualTLS {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certStore.ServerConfig(clientAuth))))
+	}
+	return opts
+}
+
+// runRpc serves grpcServer on the gRPC port until it is stopped, see stopRpc.
+func runRpc(ctx context.Context, grpcServer *grpc.Server, cfg *config.EnvConfig) error {
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
 	if err != nil {
 		return fmt.Errorf("failed to listen rpc: %w", err)
 	}
 
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(authInterceptor),
-	)
-	pb.RegisterNotificationServiceServer(grpcServer, &NotificationServer{
-		notificationsClient: notificationsClient,
-	})
+	slog.Info("Listening RPC server", "port", cfg.GRPCPort)
+	if err := grpcServer.Serve(lis); err != nil {
+		return fmt.Errorf("failed to serve rpc: %w", err)
+	}
+	if ctx.Err() == nil {
+		// Server exited unexpectedly
+		return fmt.Errorf("gRPC server exited unexpectedly")
+	}
+	return nil
+}
 
-	done := make(chan struct{})
+// stopRpc stops grpcServer once the calls in flight returned, cancelling
+// those still running when ctx is done.
+func stopRpc(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
 	go func() {
-		log.Printf("Listening RPC server on :%v\n", cfg.GRPCPort)
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Printf("failed to serve rpc: %v\n", err)
-		}
-		close(done)
+		grpcServer.GracefulStop()
+		close(stopped)
 	}()
-
 	select {
+	case <-stopped:
 	case <-ctx.Done():
-		log.Println("Received shutdown signal for RPC server")
-		grpcServer.GracefulStop()
-		<-done // Wait for Serve to return
-		return nil
-	case <-done:
-		// Server exited unexpectedly
-		return fmt.Errorf("gRPC server exited unexpectedly")
+		grpcServer.Stop()
+		<-stopped
 	}
 }
 
 func (s *NotificationServer) Broadcast(ctx context.Context, msg *pb.Message) (*empty.Empty, error) {
-	s.notificationsClient.Broadcast(ctx, &sockets.Message{
-		Type:        sockets.FromProtoEnum(msg.Type),
-		EntityID:    msg.EntityId,
-		MessageBody: msg.Message,
-	})
+	if handled, err := s.holdUnary(ctx, msg, &pb.PublishRequest{
+		Target: &pb.PublishRequest_Broadcast{Broadcast: msg},
+	}); handled || err != nil {
+		return &empty.Empty{}, err
+	}
+
+	message := fromProtoMessage(msg)
+	if err := s.notificationsClient.Broadcast(ctx, &message); err != nil {
+		s.forget(ctx, msg)
+		return nil, hubError(err)
+	}
 	return &empty.Empty{}, nil
 }
 
 func (s *NotificationServer) NotifyRoom(ctx context.Context, msg *pb.MessageWithRoom) (*empty.Empty, error) {
-	s.notificationsClient.NotifyRoom(ctx, &sockets.MessageWithRoom{
-		Message: sockets.Message{
-			Type:        sockets.FromProtoEnum(msg.Base.Type),
-			EntityID:    msg.Base.EntityId,
-			MessageBody: msg.Base.Message,
-		},
+	if handled, err := s.holdUnary(ctx, msg.Base, &pb.PublishRequest{
+		Target: &pb.PublishRequest_Room{Room: msg},
+	}); handled || err != nil {
+		return &empty.Empty{}, err
+	}
+
+	err := s.notificationsClient.NotifyRoom(ctx, &sockets.MessageWithRoom{
+		Message:  fromProtoMessage(msg.Base),
 		RoomName: msg.Room,
 	})
+	if err != nil {
+		s.forget(ctx, msg.Base)
+		return nil, hubError(err)
+	}
 	return &empty.Empty{}, nil
 }
 
 func (s *NotificationServer) PrivateNotify(ctx context.Context, msg *pb.MessageWithUser) (*empty.Empty, error) {
-	s.notificationsClient.PrivateNotify(ctx, &sockets.MessageWithUser{
-		Message: sockets.Message{
-			Type:        sockets.FromProtoEnum(msg.Base.Type),
-			EntityID:    msg.Base.EntityId,
-			MessageBody: msg.Base.Message,
-		},
-		UserID: msg.UserId,
+	if handled, err := s.holdUnary(ctx, msg.Base, &pb.PublishRequest{
+		Target: &pb.PublishRequest_User{User: msg},
+	}); handled || err != nil {
+		return &empty.Empty{}, err
+	}
+
+	err := s.notificationsClient.PrivateNotify(ctx, &sockets.MessageWithUser{
+		Message: fromProtoMessage(msg.Base),
+		UserID:  msg.UserId,
 	})
+	if err != nil {
+		s.forget(ctx, msg.Base)
+		return nil, hubError(err)
+	}
 	return &empty.Empty{}, nil
 }
 
-func authInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
-	// Implement gRPC authentication.
-	// return nil, errors.New("failed authentication")
+func (s *NotificationServer) NotifyUsers(ctx context.Context, msg *pb.MessageWithUsers) (*pb.DeliveryReport, error) {
+	return s.publishReport(ctx, &pb.PublishRequest{
+		Target: &pb.PublishRequest_Users{Users: msg},
+	})
+}
+
+func (s *NotificationServer) NotifyRooms(ctx context.Context, msg *pb.MessageWithRooms) (*pb.DeliveryReport, error) {
+	return s.publishReport(ctx, &pb.PublishRequest{
+		Target: &pb.PublishRequest_Rooms{Rooms: msg},
+	})
+}
+
+// PublishStream hands every received publish to the hub as its own batch and
+// replies with the delivery counts summed per target once the stream ends.
+func (s *NotificationServer) PublishStream(stream grpc.ClientStreamingServer[pb.PublishRequest, pb.DeliveryReport]) error {
+	ctx := stream.Context()
+	total := sockets.NewDeliveryReport()
+	var scheduled []string
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			res := toProtoReport(total)
+			res.Scheduled = scheduled
+			return stream.SendAndClose(res)
+		}
+		if err != nil {
+			return err
+		}
+
+		report, id, err := s.dispatch(ctx, req)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			scheduled = append(scheduled, id)
+		}
+		total.Merge(report)
+	}
+}
+
+// RevokeUser closes every connection of the user, whose token must not be
+// accepted anymore. Clients are told so with close code 4003.
+func (s *NotificationServer) RevokeUser(ctx context.Context, req *pb.RevokeUserRequest) (*pb.RevokeUserResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	n, err := s.notificationsClient.RevokeUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, hubError(err)
+	}
+	return &pb.RevokeUserResponse{
+		Disconnected: int32(n), //nolint:gosec // connection counts fit in int32.
+	}, nil
+}
+
+// SubscribeUser puts every session of the user in the rooms, including
+// sessions connecting later.
+func (s *NotificationServer) SubscribeUser(ctx context.Context, req *pb.UserRoomsRequest) (*pb.UserRoomsResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	n, err := s.notificationsClient.SubscribeUser(ctx, req.GetUserId(), req.GetRooms())
+	return userRoomsResponse(n, err)
+}
+
+// UnsubscribeUser takes every session of the user out of the rooms.
+func (s *NotificationServer) UnsubscribeUser(ctx context.Context, req *pb.UserRoomsRequest) (*pb.UserRoomsResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user id is required")
+	}
+
+	n, err := s.notificationsClient.UnsubscribeUser(ctx, req.GetUserId(), req.GetRooms())
+	return userRoomsResponse(n, err)
+}
 
-	return handler(ctx, req)
+// hubError returns the status of an error of the hub, or of the context of
+// the call.
+func hubError(err error) error {
+	if errors.Is(err, sockets.ErrHubStopped) {
+		return status.Error(codes.Unavailable, "server shutting down")
+	}
+	return status.FromContextError(err).Err()
+}
+
+func userRoomsResponse(sessions int, err error) (*pb.UserRoomsResponse, error) {
+	if errors.Is(err, sockets.ErrInvalidRoomName) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, hubError(err)
+	}
+	return &pb.UserRoomsResponse{
+		Sessions: int32(sessions), //nolint:gosec // connection counts fit in int32.
+	}, nil
+}
+
+// publishReport dispatches a single request and returns its delivery report.
+func (s *NotificationServer) publishReport(ctx context.Context, req *pb.PublishRequest) (*pb.DeliveryReport, error) {
+	report, id, err := s.dispatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res := toProtoReport(report)
+	if id != "" {
+		res.Scheduled = []string{id}
+	}
+	return res, nil
+}
+
+// dispatch publishes a request, or schedules it when its message asks for a
+// later delivery, in which case the scheduled ID is returned. Duplicate
+// publishes are acknowledged with an empty report.
+func (s *NotificationServer) dispatch(ctx context.Context, req *pb.PublishRequest) (*sockets.DeliveryReport, string, error) {
+	msg := baseMessage(req)
+	if !s.claim(ctx, msg) {
+		return sockets.NewDeliveryReport(), "", nil
+	}
+
+	id, err := s.schedule(ctx, msg, req)
+	if err != nil {
+		s.forget(ctx, msg)
+		return nil, "", err
+	}
+	if id != "" {
+		return sockets.NewDeliveryReport(), id, nil
+	}
+
+	report, err := s.publish(ctx, req)
+	if err != nil {
+		s.forget(ctx, msg)
+		return nil, "", err
+	}
+	return report, "", nil
+}
+
+// holdUnary handles the idempotency key and delivery time of an RPC returning
+// no body. It reports whether the publish was handled, as a duplicate or by
+// scheduling it, in which case the caller must not send it to the hub.
+func (s *NotificationServer) holdUnary(ctx context.Context, msg *pb.Message, req *pb.PublishRequest) (bool, error) {
+	if !s.claim(ctx, msg) {
+		return true, nil
+	}
+
+	scheduled, err := s.scheduleUnary(ctx, msg, req)
+	if err != nil {
+		s.forget(ctx, msg)
+	}
+	return scheduled, err
+}
+
+// claim reports whether a publish should go ahead, i.e. its message carries
+// no idempotency key or one not seen within the deduplication window.
+func (s *NotificationServer) claim(ctx context.Context, msg *pb.Message) bool {
+	key := msg.GetIdempotencyKey()
+	if key == "" || s.dedup == nil {
+		return true
+	}
+
+	if !s.dedup.Claim(idempotencyKey(ctx, key)) {
+		metrics.DuplicatePublishes.Add(1)
+		return false
+	}
+	return true
+}
+
+// forget releases the idempotency key of a publish that failed, so that it can be retried.
+func (s *NotificationServer) forget(ctx context.Context, msg *pb.Message) {
+	if key := msg.GetIdempotencyKey(); key != "" && s.dedup != nil {
+		s.dedup.Forget(idempotencyKey(ctx, key))
+	}
+}
+
+// idempotencyKey scopes an idempotency key to the tenant of the caller, so
+// that tenants picking the same keys do not suppress each other's publishes.
+func idempotencyKey(ctx context.Context, key string) string {
+	if tenant := notifications.TenantFromContext(ctx); tenant != "" {
+		return tenant + "\x00" + key
+	}
+	return key
+}
+
+// publish hands a request to the hub as one batch and waits for its delivery counts.
+func (s *NotificationServer) publish(ctx context.Context, req *pb.PublishRequest) (*sockets.DeliveryReport, error) {
+	batch, err := batchFromPublishRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.notificationsClient.NotifyBatch(ctx, batch)
+	if err != nil {
+		return nil, hubError(err)
+	}
+	return report, nil
+}
+
+func batchFromPublishRequest(req *pb.PublishRequest) (*sockets.MessageBatch, error) {
+	switch target := req.Target.(type) {
+	case *pb.PublishRequest_Broadcast:
+		batch := sockets.NewMessageBatch(fromProtoMessage(target.Broadcast), nil, nil)
+		batch.Broadcast = true
+		return batch, nil
+	case *pb.PublishRequest_Room:
+		if target.Room.Room == nil {
+			batch := sockets.NewMessageBatch(fromProtoMessage(target.Room.Base), nil, nil)
+			batch.Broadcast = true
+			return batch, nil
+		}
+		return sockets.NewMessageBatch(fromProtoMessage(target.Room.Base), nil, []string{*target.Room.Room}), nil
+	case *pb.PublishRequest_User:
+		return sockets.NewMessageBatch(fromProtoMessage(target.User.Base), []string{target.User.UserId}, nil), nil
+	case *pb.PublishRequest_Users:
+		return sockets.NewMessageBatch(fromProtoMessage(target.Users.Base), target.Users.UserIds, nil), nil
+	case *pb.PublishRequest_Rooms:
+		return sockets.NewMessageBatch(fromProtoMessage(target.Rooms.Base), nil, target.Rooms.Rooms), nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, "publish request has no target")
+	}
+}
+
+// baseMessage returns the message of a publish request, whatever its target.
+func baseMessage(req *pb.PublishRequest) *pb.Message {
+	switch target := req.Target.(type) {
+	case *pb.PublishRequest_Broadcast:
+		return target.Broadcast
+	case *pb.PublishRequest_Room:
+		return target.Room.GetBase()
+	case *pb.PublishRequest_User:
+		return target.User.GetBase()
+	case *pb.PublishRequest_Users:
+		return target.Users.GetBase()
+	case *pb.PublishRequest_Rooms:
+		return target.Rooms.GetBase()
+	default:
+		return nil
+	}
+}
+
+func fromProtoMessage(msg *pb.Message) sockets.Message {
+	message := sockets.Message{
+		Type:        sockets.FromProtoEnum(msg.GetType()),
+		EntityID:    msg.GetEntityId(),
+		MessageBody: msg.GetMessage(),
+	}
+
+	switch {
+	case msg.GetExpiresAt() != nil:
+		message.ExpiresAt = msg.GetExpiresAt().AsTime()
+	case msg.GetTtl() != nil:
+		message.ExpiresAt = time.Now().Add(msg.GetTtl().AsDuration())
+	}
+
+	return message
+}
+
+func toProtoReport(report *sockets.DeliveryReport) *pb.DeliveryReport {
+	res := &pb.DeliveryReport{
+		Broadcast: int32(report.Broadcast), //nolint:gosec // connection counts fit in int32.
+	}
+	res.Users = toProtoDeliveries(report.Users)
+	res.Rooms = toProtoDeliveries(report.Rooms)
+	return res
+}
+
+func toProtoDeliveries(counts map[string]int) []*pb.Delivery {
+	deliveries := make([]*pb.Delivery, 0, len(counts))
+	for _, target := range slices.Sorted(maps.Keys(counts)) {
+		deliveries = append(deliveries, &pb.Delivery{
+			Target:    target,
+			Delivered: int32(counts[target]), //nolint:gosec // connection counts fit in int32.
+		})
+	}
+	return deliveries
 }
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/yiannis54/go-socket-server/internal/idempotency"
+	"github.com/yiannis54/go-socket-server/internal/notifications"
+	"github.com/yiannis54/go-socket-server/internal/sockets"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+func TestStopRpc(t *testing.T) {
+	// serve starts a server whose Broadcast calls block until their identity
+	// is read, and returns the error of one of them.
+	serve := func(t *testing.T) (*grpc.Server, *identityServer, chan error) {
+		t.Helper()
+		srv := &identityServer{identities: make(chan string)}
+		grpcServer := grpc.NewServer()
+		pb.RegisterNotificationServiceServer(grpcServer, srv)
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go grpcServer.Serve(lis) //nolint:errcheck // stopped by the test.
+
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		errs := make(chan error, 1)
+		go func() {
+			_, err := pb.NewNotificationServiceClient(conn).Broadcast(context.Background(), &pb.Message{})
+			errs <- err
+		}()
+		time.Sleep(100 * time.Millisecond)
+		return grpcServer, srv, errs
+	}
+
+	t.Run("should wait for the calls in flight", func(t *testing.T) {
+		grpcServer, srv, errs := serve(t)
+		stopped := make(chan struct{})
+		go func() {
+			stopRpc(context.Background(), grpcServer)
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			t.Fatal("stopped before the call returned")
+		case <-time.After(100 * time.Millisecond):
+		}
+		<-srv.identities
+		require.NoError(t, <-errs)
+		<-stopped
+	})
+
+	t.Run("should cancel the calls past the deadline", func(t *testing.T) {
+		grpcServer, _, errs := serve(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		stopRpc(ctx, grpcServer)
+		assert.Error(t, <-errs)
+	})
+}
+
+func TestNotificationServer_retryFailedPublish(t *testing.T) {
+	// The hub is not run, so that the test receives the messages handed to it.
+	hub := sockets.NewHub()
+	srv := &NotificationServer{
+		notificationsClient: notifications.NewClient(hub),
+		dedup:               idempotency.NewCache(10, time.Minute),
+	}
+	room := "orders"
+
+	tests := []struct {
+		name    string
+		publish func(ctx context.Context, base *pb.Message) error
+		receive func() any
+	}{
+		{
+			name: "Broadcast",
+			publish: func(ctx context.Context, base *pb.Message) error {
+				_, err := srv.Broadcast(ctx, base)
+				return err
+			},
+			receive: func() any { return <-hub.Broadcast },
+		},
+		{
+			name: "NotifyRoom",
+			publish: func(ctx context.Context, base *pb.Message) error {
+				_, err := srv.NotifyRoom(ctx, &pb.MessageWithRoom{Base: base, Room: &room})
+				return err
+			},
+			receive: func() any { return <-hub.Broadcast },
+		},
+		{
+			name: "PrivateNotify",
+			publish: func(ctx context.Context, base *pb.Message) error {
+				_, err := srv.PrivateNotify(ctx, &pb.MessageWithUser{Base: base, UserId: "42"})
+				return err
+			},
+			receive: func() any { return <-hub.Private },
+		},
+	}
+	for _, tt := range tests {
+		t.Run("should deliver the retry of a failed "+tt.name, func(t *testing.T) {
+			base := &pb.Message{EntityId: "order-1", IdempotencyKey: "retry-" + tt.name}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			require.Equal(t, codes.DeadlineExceeded, status.Code(tt.publish(ctx, base)))
+
+			received := make(chan any, 1)
+			go func() { received <- tt.receive() }()
+			require.NoError(t, tt.publish(context.Background(), base))
+			select {
+			case <-received:
+			case <-time.After(time.Second):
+				t.Fatal("the retry was not handed to the hub")
+			}
+		})
+	}
+}
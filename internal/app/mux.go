@@ -0,0 +1,30 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// grpcMux serves the gRPC requests with grpcServer and the other requests,
+// websocket upgrades included, with handler, so that both share a port.
+func grpcMux(grpcServer *grpc.Server, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// singlePortProtocols are the protocols of a port shared with gRPC: HTTP/1.1
+// for websocket upgrades, and HTTP/2 for gRPC, over TLS or as h2c without it.
+func singlePortProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
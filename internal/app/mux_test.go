@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/yiannis54/go-socket-server/internal/certs/certstest"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// serveSinglePort serves websocket upgrades on /ws, plain requests and
+// gRPC calls on one listener, over TLS when tlsConfig is not nil. It returns
+// the address and the identities of the gRPC calls.
+func serveSinglePort(t *testing.T, tlsConfig *tls.Config) (string, chan string) {
+	t.Helper()
+	srv := &identityServer{identities: make(chan string, 1)}
+	grpcServer := grpc.NewServer()
+	pb.RegisterNotificationServiceServer(grpcServer, srv)
+
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		_ = ws.WriteMessage(websocket.TextMessage, []byte("upgraded"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+
+	server := &http.Server{
+		Handler:           grpcMux(grpcServer, mux),
+		Protocols:         singlePortProtocols(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: time.Second,
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		if tlsConfig != nil {
+			_ = server.ServeTLS(lis, "", "")
+		} else {
+			_ = server.Serve(lis)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = server.Close()
+		grpcServer.Stop()
+	})
+	return lis.Addr().String(), srv.identities
+}
+
+func TestGrpcMux(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// broadcast calls the gRPC service and waits for its handler.
+	broadcast := func(t *testing.T, addr string, creds credentials.TransportCredentials, identities chan string) {
+		t.Helper()
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = pb.NewNotificationServiceClient(conn).Broadcast(ctx, &pb.Message{})
+		require.NoError(t, err)
+		<-identities
+	}
+	// upgrade opens a websocket and reads its first message.
+	upgrade := func(t *testing.T, dialer *websocket.Dialer, url string) {
+		t.Helper()
+		ws, res, err := dialer.Dial(url, nil)
+		require.NoError(t, err)
+		res.Body.Close()
+		defer ws.Close()
+		_, message, err := ws.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, "upgraded", string(message))
+	}
+
+	t.Run("should serve websockets and h2c gRPC without TLS", func(t *testing.T) {
+		addr, identities := serveSinglePort(t, nil)
+
+		upgrade(t, websocket.DefaultDialer, "ws://"+addr+"/ws")
+		broadcast(t, addr, insecure.NewCredentials(), identities)
+
+		res, err := http.Get("http://" + addr + "/")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1", string(body))
+	})
+
+	t.Run("should serve websockets and HTTP/2 gRPC over TLS", func(t *testing.T) {
+		ca := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+		server := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "server"}, IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, &ca)
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.Leaf)
+
+		addr, identities := serveSinglePort(t, &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{server},
+			NextProtos:   []string{"h2", "http/1.1"},
+		})
+		clientTLS := &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}
+
+		upgrade(t, &websocket.Dialer{TLSClientConfig: clientTLS}, "wss://"+addr+"/ws")
+		broadcast(t, addr, credentials.NewTLS(clientTLS), identities)
+	})
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/config"
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/sockets"
+)
+
+// configPollInterval is how often the configuration file is checked for changes.
+const configPollInterval = 2 * time.Second
+
+// apiKeys are the keys of gRPC callers in force, replaced by reloads.
+type apiKeys struct {
+	current atomic.Pointer[keySet]
+}
+
+// keySet holds the keys of one configuration, replaced as a whole.
+type keySet struct {
+	adminToken string
+	tenantKeys map[string]string
+	// identityTenants are the tenants of mutual TLS callers by identity.
+	identityTenants map[string]string
+	// allowedIdentities are the service identities allowed with mutual TLS.
+	allowedIdentities []string
+}
+
+func newKeySet(cfg *config.EnvConfig) *keySet {
+	return &keySet{
+		adminToken:        cfg.Auth.AdminToken,
+		tenantKeys:        cfg.Tenancy.GRPCKeys,
+		identityTenants:   cfg.Tenancy.IdentityTenants,
+		allowedIdentities: cfg.TLS.AllowedIdentities,
+	}
+}
+
+func newAPIKeys(cfg *config.EnvConfig) *apiKeys {
+	keys := &apiKeys{}
+	keys.current.Store(newKeySet(cfg))
+	return keys
+}
+
+func (k *apiKeys) load() *keySet {
+	return k.current.Load()
+}
+
+// hubPolicy returns the limits and origins of the hub.
+func hubPolicy(cfg *config.EnvConfig) sockets.Policy {
+	limits := cfg.Limits
+	return sockets.Policy{
+		RateLimits: sockets.RateLimits{
+			Frames:            limits.RateLimits.Frames,
+			UserFrames:        limits.RateLimits.UserFrames,
+			Subscriptions:     limits.RateLimits.Subscriptions,
+			UserSubscriptions: limits.RateLimits.UserSubscriptions,
+			MaxViolations:     limits.RateLimits.MaxViolations,
+		},
+		ConnectionLimits: sockets.ConnectionLimits{
+			Total:          limits.Connections.Max,
+			PerTenant:      limits.Connections.MaxPerTenant,
+			PerUser:        limits.Connections.MaxPerUser,
+			PerIP:          limits.Connections.MaxPerIP,
+			EvictOldest:    limits.Connections.UserLimitMode == config.UserLimitEvictOldest,
+			RetryAfter:     limits.Connections.RetryAfter,
+			TrustedProxies: limits.Connections.TrustedProxies,
+		},
+		SubscriptionLimits: sockets.SubscriptionLimits{
+			PerClient: limits.MaxSubscriptions,
+			Rooms:     limits.MaxRooms,
+		},
+		OriginPolicy: sockets.OriginPolicy{
+			Allowed:    cfg.Auth.AllowedOrigins,
+			AllowEmpty: cfg.Auth.AllowEmptyOrigin,
+		},
+	}
+}
+
+// reloader reloads the configuration on SIGHUP and when its file changes,
+// without dropping connections. A configuration failing to load or validate
+// is rejected as a whole and the one in force is kept. Of a valid one, the
+// reloadable settings are applied and the others are reported as waiting
+// for a restart.
+type reloader struct {
+	// cfg is the configuration in force.
+	cfg  *config.EnvConfig
+	load func() (*config.EnvConfig, error)
+	hub  *sockets.Hub
+	keys *apiKeys
+	// level is the minimum level of the records logged.
+	level *slog.LevelVar
+}
+
+// run reloads the configuration until the context is cancelled.
+func (r *reloader) run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	modified := fileModified(r.cfg.File())
+
+	for {
+		select {
+		case <-hup:
+			r.reload("SIGHUP")
+		case <-ticker.C:
+			if r.cfg.File() == "" {
+				continue
+			}
+			if m := fileModified(r.cfg.File()); !m.Equal(modified) {
+				modified = m
+				r.reload(r.cfg.File() + " changed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reload loads the configuration again and applies it.
+func (r *reloader) reload(trigger string) {
+	next, err := r.load()
+	if err != nil {
+		metrics.ConfigReloads.Add(metrics.ReloadFailed, 1)
+		slog.Warn("config reload failed, keeping the current configuration", "trigger", trigger, "err", err)
+		return
+	}
+
+	cfg, changed, restart, err := config.Reloaded(r.cfg, next)
+	if err != nil {
+		metrics.ConfigReloads.Add(metrics.ReloadFailed, 1)
+		slog.Warn("config reload rejected, keeping the current configuration", "trigger", trigger, "err", err)
+		if len(changed) > 0 {
+			slog.Warn("config settings changed but were not applied", "settings", strings.Join(changed, ", "))
+		}
+		return
+	}
+	// Everything is built from the validated configuration first, then
+	// swapped in one go.
+	policy, keys, level := hubPolicy(cfg), newKeySet(cfg), cfg.Observability.Level()
+	r.hub.Reload(policy)
+	r.keys.current.Store(keys)
+	r.level.Set(level)
+	r.cfg = cfg
+
+	metrics.ConfigReloads.Add(metrics.ReloadSucceeded, 1)
+	metrics.RestartPending.Set(int64(len(restart)))
+	if len(changed) == 0 {
+		slog.Info("config reloaded, no change applied", "trigger", trigger)
+	} else {
+		slog.Info("config reloaded", "trigger", trigger, "applied", strings.Join(changed, ", "))
+	}
+	if len(restart) > 0 {
+		slog.Warn("config settings changed but need a restart", "settings", strings.Join(restart, ", "))
+	}
+}
+
+// fileModified returns the modification time of a file, zero when it cannot
+// be read, e.g. while an editor replaces it.
+func fileModified(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/config"
+	"github.com/yiannis54/go-socket-server/internal/sockets"
+)
+
+func TestReloader_reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(t *testing.T, content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+	write(t, `
+auth:
+  adminToken: s3cret
+  allowedOrigins: [https://app.example.com]
+tenancy:
+  claim: tid
+  grpcKeys:
+    key-a: acme
+limits:
+  maxRooms: 10
+  connections:
+    maxPerUser: 2
+`)
+	load := func() (*config.EnvConfig, error) {
+		cfg, _, err := config.Load([]string{"--config", path})
+		return cfg, err
+	}
+	cfg, err := load()
+	require.NoError(t, err)
+	before := *cfg
+	newReloader := func(cfg *config.EnvConfig) *reloader {
+		level := new(slog.LevelVar)
+		level.Set(cfg.Observability.Level())
+		return &reloader{cfg: cfg, load: load, hub: sockets.NewHub(), keys: newAPIKeys(cfg), level: level}
+	}
+
+	// Every reloadable setting changes, along with an invalid one.
+	invalid := `
+auth:
+  adminToken: changed
+  allowedOrigins: [https://other.example.com]
+  allowEmptyOrigin: true
+tenancy:
+  claim: tid
+  grpcKeys:
+    key-b: globex
+limits:
+  maxRooms: 20
+  maxSubscriptions: 5
+  rate:
+    frames:
+      rate: 2
+      burst: 4
+  connections:
+    maxPerUser: -1
+    userLimitMode: evict_oldest
+observability:
+  logLevel: debug
+`
+	tests := []struct {
+		name, content string
+	}{
+		{"should keep the configuration when the file does not validate", invalid},
+		{"should keep the configuration when the file does not parse", "limits: ["},
+		// The claim needs a restart, the keys are reloaded.
+		{"should keep the configuration invalid with the settings kept", "limits:\n  maxRooms: 20\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write(t, tt.content)
+			r := newReloader(cfg)
+			r.reload("test")
+
+			require.Equal(t, before, *r.cfg)
+			require.Equal(t, &keySet{adminToken: "s3cret", tenantKeys: map[string]string{"key-a": "acme"}}, r.keys.load())
+			require.Equal(t, slog.LevelInfo, r.level.Level())
+		})
+	}
+
+	t.Run("should apply the keys and the log level", func(t *testing.T) {
+		write(t, `
+auth:
+  adminToken: changed
+  allowedOrigins: [https://app.example.com]
+tenancy:
+  claim: tid
+  grpcKeys:
+    key-b: globex
+observability:
+  logLevel: debug
+`)
+		r := newReloader(cfg)
+		r.reload("test")
+
+		require.Equal(t, &keySet{adminToken: "changed", tenantKeys: map[string]string{"key-b": "globex"}}, r.keys.load())
+		require.Equal(t, slog.LevelDebug, r.level.Level())
+		require.Equal(t, "debug", r.cfg.Observability.LogLevel)
+	})
+}
@@ -2,31 +2,84 @@ package app
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/yiannis54/go-socket-server/internal/certs"
 	"github.com/yiannis54/go-socket-server/internal/config"
+	"github.com/yiannis54/go-socket-server/internal/idempotency"
+	"github.com/yiannis54/go-socket-server/internal/metrics"
 	"github.com/yiannis54/go-socket-server/internal/middleware"
 	"github.com/yiannis54/go-socket-server/internal/notifications"
+	"github.com/yiannis54/go-socket-server/internal/scheduler"
 	"github.com/yiannis54/go-socket-server/internal/sockets"
 )
 
 const shutdownTimeout = 10 * time.Second
 
-func Run(cfg *config.EnvConfig) error {
+// Run serves the hub over HTTP and gRPC until a termination signal. load
+// reads the configuration again when it is reloaded, see reloader.
+func Run(cfg *config.EnvConfig, load func() (*config.EnvConfig, error)) error {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, os.Interrupt)
 	defer stop()
 
-	socketHub := sockets.NewHub()
+	// The log package writes through slog at the info level.
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.Observability.Level())
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	autoJoin, err := sockets.ParseRoomTemplates(cfg.AutoJoinRooms)
+	if err != nil {
+		return fmt.Errorf("auto-join rooms: %w", err)
+	}
+
+	socketHub := sockets.NewHub(
+		sockets.WithAuth(sockets.AuthOptions{
+			Validator:     middleware.ValidateToken,
+			ExpiryWarning: cfg.Auth.TokenExpiryWarning,
+			TenantClaim:   cfg.Tenancy.Claim,
+		}),
+		sockets.WithAutoJoin(autoJoin),
+		sockets.WithTransport(sockets.NewTransport(cfg.Transport)),
+		sockets.WithCompression(sockets.Compression{
+			Enabled: cfg.Transport.Compression.Enabled,
+			Level:   cfg.Transport.Compression.Level,
+			MinSize: cfg.Transport.Compression.MinSize,
+		}),
+	)
+	socketHub.Reload(hubPolicy(cfg))
 	notificationsClient := notifications.NewClient(socketHub)
 
+	notificationServer := &NotificationServer{
+		notificationsClient: notificationsClient,
+		dedup:               idempotency.NewCache(cfg.Idempotency.CacheSize, cfg.Idempotency.Window),
+	}
+
+	var scheduleStore scheduler.Store
+	if cfg.ScheduleStorePath != "" {
+		scheduleStore = scheduler.NewFileStore(cfg.ScheduleStorePath)
+	}
+	notificationScheduler, err := scheduler.New(scheduleStore, notificationServer.releaseScheduled)
+	if err != nil {
+		return err
+	}
+	notificationServer.scheduler = notificationScheduler
+
+	if cfg.Auth.AdminToken == "" {
+		slog.Warn("auth adminToken (ADMIN_TOKEN) is not set, the admin gRPC service is disabled")
+	}
+	adminServer := &AdminServer{hub: socketHub}
+	keys := newAPIKeys(cfg)
+
 	router, err := initRoutes(socketHub, cfg)
 	if err != nil {
 		return err
@@ -37,41 +90,114 @@ func Run(cfg *config.EnvConfig) error {
 		ReadHeaderTimeout: 3 * time.Second, //nolint:mnd
 		Handler:           router,
 	}
+	// Shutdown waits for the event streams and long polls, which only end
+	// on their own once the hub drained them.
+	server.RegisterOnShutdown(socketHub.Drain)
+
+	var certStore *certs.Store
+	if cfg.TLS.CertFile != "" {
+		certStore, err = certs.NewStore(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		clientAuth := tls.NoClientCert
+		if cfg.SinglePort && cfg.TLS.ClientCAFile != "" {
+			// Verified for gRPC callers, see newRpcServer.
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+		server.TLSConfig = certStore.ServerConfig(clientAuth)
+	}
+
+	grpcServer := newRpcServer(notificationServer, adminServer, keys, certStore, cfg)
+	if cfg.SinglePort {
+		server.Handler = grpcMux(grpcServer, router)
+		server.Protocols = singlePortProtocols()
+	}
 
 	// errgroup: if any goroutine returns an error, the derived context is
 	// cancelled, which causes all the others to shut down as well.
 	g, ctx := errgroup.WithContext(ctx)
 
-	// Socket hub
+	// Socket hub, stopped once the servers are, so that the calls they
+	// drain still reach it.
+	hubCtx, stopHub := context.WithCancel(context.Background())
+	defer stopHub()
 	g.Go(func() error {
-		socketHub.Run(ctx)
+		socketHub.Run(hubCtx)
 		return nil
 	})
 
-	// gRPC server
+	// Scheduled notifications
 	g.Go(func() error {
-		return runRpc(ctx, notificationsClient, cfg)
+		notificationScheduler.Run(ctx)
+		return nil
+	})
+
+	// gRPC server, unless served by the HTTP server
+	if !cfg.SinglePort {
+		g.Go(func() error {
+			return runRpc(ctx, grpcServer, cfg)
+		})
+	}
+
+	// TLS certificate reloads
+	if certStore != nil {
+		g.Go(func() error {
+			certStore.Watch(ctx, certPollInterval)
+			return nil
+		})
+	}
+
+	// Configuration reloads
+	g.Go(func() error {
+		r := &reloader{cfg: cfg, load: load, hub: socketHub, keys: keys, level: logLevel}
+		r.run(ctx)
+		return nil
 	})
 
 	// HTTP server
 	g.Go(func() error {
-		log.Printf("Listening Socket server on :%v", cfg.HTTPPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if cfg.SinglePort {
+			slog.Info("Listening Socket and RPC server", "port", cfg.HTTPPort)
+		} else {
+			slog.Info("Listening Socket server", "port", cfg.HTTPPort)
+		}
+		serve := server.ListenAndServe
+		if server.TLSConfig != nil {
+			// The certificate comes from TLSConfig, reloaded by certStore.
+			serve = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			return fmt.Errorf("http server: %w", err)
 		}
 		return nil
 	})
 
 	// Graceful shutdown watcher: waits for context cancellation (signal or
-	// goroutine failure), then shuts down the HTTP server with a timeout.
+	// goroutine failure), then drains the HTTP and gRPC servers with a
+	// timeout, and stops the hub last.
 	g.Go(func() error {
 		<-ctx.Done()
-		log.Println("Shutting down servers")
+		slog.Info("Shutting down servers")
+		defer stopHub()
 
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
-		return server.Shutdown(shutdownCtx)
+		if cfg.SinglePort {
+			err := server.Shutdown(shutdownCtx)
+			// Shutdown waited for the calls in flight until the timeout,
+			// the remaining ones are cancelled. GracefulStop does not
+			// support the transports of ServeHTTP.
+			grpcServer.Stop()
+			return err
+		}
+
+		var wg sync.WaitGroup
+		wg.Go(func() { stopRpc(shutdownCtx, grpcServer) })
+		err := server.Shutdown(shutdownCtx)
+		wg.Wait()
+		return err
 	})
 
 	return g.Wait()
@@ -83,5 +209,29 @@ func initRoutes(socketHub *sockets.Hub, cfg *config.EnvConfig) (http.Handler, er
 		sockets.ServeWs(socketHub, w, r)
 	}))
 	mux.Handle("GET /ws", wsHandler)
+	// Websocket routes with their own timeouts and limits, e.g. for backends.
+	for _, route := range cfg.Transport.Routes {
+		handler, err := sockets.WsHandler(socketHub, sockets.NewTransport(cfg.Transport.Route(route)))
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", route.Path, err)
+		}
+		mux.Handle("GET "+route.Path, middleware.AuthMiddleware(cfg, handler))
+	}
+	// Server-sent events fallback for networks blocking websocket upgrades.
+	mux.Handle("GET /events", middleware.AuthMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sockets.ServeSSE(socketHub, w, r)
+	})))
+	// Long-polling fallback for clients without websockets nor event streams.
+	mux.Handle("GET /poll", middleware.AuthMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sockets.ServePoll(socketHub, w, r)
+	})))
+	sessionAction := middleware.AuthMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sockets.ServeSessionAction(socketHub, r.PathValue("connection"), w, r)
+	}))
+	mux.Handle("POST /events/{connection}", sessionAction)
+	mux.Handle("POST /poll/{connection}", sessionAction)
+	if path := cfg.Observability.MetricsPath; path != "" {
+		mux.Handle("GET "+path, metrics.Handler())
+	}
 	return mux, nil
 }
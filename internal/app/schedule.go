@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yiannis54/go-socket-server/internal/notifications"
+	"github.com/yiannis54/go-socket-server/internal/scheduler"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// scheduleIDHeader is the response header carrying the ID of a scheduled
+// notification for RPCs that return no body.
+const scheduleIDHeader = "schedule-id"
+
+func (s *NotificationServer) CancelScheduled(ctx context.Context, req *pb.CancelScheduledRequest) (*empty.Empty, error) {
+	if s.scheduler == nil {
+		return nil, status.Error(codes.FailedPrecondition, "scheduling is not enabled")
+	}
+
+	// Tenants can only cancel their own notifications.
+	if err := s.scheduler.Cancel(notifications.TenantFromContext(ctx), req.GetId()); err != nil {
+		if errors.Is(err, scheduler.ErrNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &empty.Empty{}, nil
+}
+
+func (s *NotificationServer) ListScheduled(ctx context.Context, _ *empty.Empty) (*pb.ScheduledList, error) {
+	if s.scheduler == nil {
+		return &pb.ScheduledList{}, nil
+	}
+
+	tenant := notifications.TenantFromContext(ctx)
+	list := &pb.ScheduledList{}
+	for _, n := range s.scheduler.List() {
+		if n.GetTenant() == tenant {
+			list.Notifications = append(list.Notifications, n)
+		}
+	}
+	return list, nil
+}
+
+// releaseScheduled publishes a scheduled notification once it is due, to the
+// tenant that scheduled it. Invalid publishes are dropped rather than retried.
+func (s *NotificationServer) releaseScheduled(ctx context.Context, n *pb.ScheduledNotification) error {
+	ctx = notifications.WithTenant(ctx, n.GetTenant())
+	_, err := s.publish(ctx, n.GetPublish())
+	if status.Code(err) == codes.InvalidArgument {
+		slog.Warn("could not release scheduled notification", "id", n.GetId(), "err", err)
+		return nil
+	}
+	return err
+}
+
+// schedule holds back a publish whose message asks for a later delivery and
+// returns the scheduled ID. It returns an empty ID when the publish is due now.
+func (s *NotificationServer) schedule(ctx context.Context, msg *pb.Message, req *pb.PublishRequest) (string, error) {
+	deliverAt, ok := deliveryTime(msg, time.Now())
+	if !ok {
+		return "", nil
+	}
+
+	if s.scheduler == nil {
+		return "", status.Error(codes.FailedPrecondition, "scheduling is not enabled")
+	}
+
+	n := scheduler.NewNotification(msg.GetScheduleId(), deliverAt, req)
+	n.Tenant = notifications.TenantFromContext(ctx)
+	id, err := s.scheduler.Schedule(n)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrDuplicateID) {
+			return "", status.Error(codes.AlreadyExists, err.Error())
+		}
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	return id, nil
+}
+
+// scheduleUnary schedules a publish of an RPC returning no body and reports
+// whether it was scheduled. The scheduled ID is sent as a response header.
+func (s *NotificationServer) scheduleUnary(ctx context.Context, msg *pb.Message, req *pb.PublishRequest) (bool, error) {
+	id, err := s.schedule(ctx, msg, req)
+	if err != nil || id == "" {
+		return false, err
+	}
+
+	if err := grpc.SetHeader(ctx, metadata.Pairs(scheduleIDHeader, id)); err != nil {
+		slog.Warn("could not set schedule id header", "err", err)
+	}
+	return true, nil
+}
+
+// deliveryTime returns when the message should be delivered, if that is in the future.
+func deliveryTime(msg *pb.Message, now time.Time) (time.Time, bool) {
+	var deliverAt time.Time
+	switch {
+	case msg.GetDeliverAt() != nil:
+		deliverAt = msg.GetDeliverAt().AsTime()
+	case msg.GetDelay() != nil:
+		deliverAt = now.Add(msg.GetDelay().AsDuration())
+	default:
+		return time.Time{}, false
+	}
+
+	return deliverAt, deliverAt.After(now)
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yiannis54/go-socket-server/internal/notifications"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// tenantAuth resolves the tenant of NotificationService callers from their
+// service identity, see ServiceIdentity, else from their
+// "authorization: Bearer <key>" metadata. Calls pass through untouched when
+// neither is configured, i.e. tenancy is disabled. keys is read on every call
+// so that reloads apply.
+type tenantAuth struct {
+	keys *apiKeys
+}
+
+func (a tenantAuth) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a tenantAuth) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate returns the context scoped to the tenant of the caller.
+func (a tenantAuth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	set := a.keys.load()
+	keys := set.tenantKeys
+	if len(keys) == 0 && len(set.identityTenants) == 0 ||
+		!strings.HasPrefix(method, "/"+pb.NotificationService_ServiceDesc.ServiceName+"/") {
+		return ctx, nil
+	}
+
+	if identity, ok := ServiceIdentity(ctx); ok {
+		if tenant, ok := set.identityTenants[identity]; ok {
+			return notifications.WithTenant(ctx, tenant), nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		bearer, ok := strings.CutPrefix(value, "Bearer ")
+		if !ok {
+			continue
+		}
+		for key, tenant := range keys {
+			if subtle.ConstantTimeCompare([]byte(bearer), []byte(key)) == 1 {
+				return notifications.WithTenant(ctx, tenant), nil
+			}
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "tenant key required")
+}
+
+// contextStream overrides the context of a server stream, e.g. with the
+// tenant one.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint:containedctx // the stream context is replaced, not stored.
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/yiannis54/go-socket-server/internal/certs"
+)
+
+// certPollInterval is how often the TLS files are checked for changes.
+const certPollInterval = 10 * time.Second
+
+// serviceIdentityKey is the context key of the identity of a gRPC caller.
+type serviceIdentityKey struct{}
+
+// ServiceIdentity returns the identity of the client certificate a gRPC caller
+// presented over mutual TLS, see certs.Identity.
+func ServiceIdentity(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(serviceIdentityKey{}).(string)
+	return identity, ok
+}
+
+// peerIdentity returns the identity of the verified client certificate of the
+// caller, if any.
+func peerIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return certs.Identity(info.State.VerifiedChains[0][0]), true
+}
+
+// identityAuth resolves the service identity of gRPC callers, see
+// ServiceIdentity, ahead of the other interceptors. With mutual TLS, calls
+// without a verified client certificate are rejected, as they are on a single
+// port where presenting one is optional, and so are the identities missing
+// from the allowed ones when those are set. keys is read on every call so
+// that reloads apply.
+type identityAuth struct {
+	keys *apiKeys
+	// mutualTLS requires a client certificate on every call.
+	mutualTLS bool
+}
+
+func (a identityAuth) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a identityAuth) stream(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate returns the context with the identity of the caller.
+func (a identityAuth) authenticate(ctx context.Context) (context.Context, error) {
+	identity, ok := peerIdentity(ctx)
+	if !ok {
+		if a.mutualTLS {
+			return nil, status.Error(codes.Unauthenticated, "client certificate required")
+		}
+		return ctx, nil
+	}
+
+	if allowed := a.keys.load().allowedIdentities; len(allowed) > 0 && !slices.Contains(allowed, identity) {
+		return nil, status.Errorf(codes.PermissionDenied, "service identity %q is not allowed", identity)
+	}
+	return context.WithValue(ctx, serviceIdentityKey{}, identity), nil
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yiannis54/go-socket-server/internal/certs"
+	"github.com/yiannis54/go-socket-server/internal/certs/certstest"
+	"github.com/yiannis54/go-socket-server/internal/config"
+	"github.com/yiannis54/go-socket-server/internal/notifications"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// identityServer answers with the service identity its handlers receive.
+type identityServer struct {
+	pb.UnimplementedNotificationServiceServer
+	identities chan string
+}
+
+func (s *identityServer) Broadcast(ctx context.Context, _ *pb.Message) (*empty.Empty, error) {
+	identity, _ := ServiceIdentity(ctx)
+	select {
+	case s.identities <- identity:
+		return &empty.Empty{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *identityServer) PublishStream(stream grpc.ClientStreamingServer[pb.PublishRequest, pb.DeliveryReport]) error {
+	identity, _ := ServiceIdentity(stream.Context())
+	s.identities <- identity
+	return stream.SendAndClose(&pb.DeliveryReport{})
+}
+
+func TestServiceIdentity(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.pem")
+
+	ca := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+	server := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "server"}, IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, &ca)
+	spiffeID, err := url.Parse("spiffe://example.com/billing")
+	require.NoError(t, err)
+	client := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}, URIs: []*url.URL{spiffeID}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, &ca)
+	certstest.WritePEM(t, server, certFile, keyFile)
+	certstest.WritePEM(t, ca, caFile, "")
+
+	certStore, err := certs.NewStore(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	cfg := &config.EnvConfig{TLS: config.TLSConfig{
+		CertFile:          certFile,
+		KeyFile:           keyFile,
+		ClientCAFile:      caFile,
+		AllowedIdentities: []string{spiffeID.String()},
+	}}
+
+	srv := &identityServer{identities: make(chan string, 1)}
+	grpcServer := grpc.NewServer(rpcServerOptions(newAPIKeys(cfg), certStore, cfg)...)
+	pb.RegisterNotificationServiceServer(grpcServer, srv)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServer.Serve(lis) //nolint:errcheck // stopped by the test.
+	defer grpcServer.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	// dial returns a client presenting the certificate.
+	dial := func(t *testing.T, cert tls.Certificate) pb.NotificationServiceClient {
+		t.Helper()
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{cert},
+		})))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewNotificationServiceClient(conn)
+	}
+	notifications := dial(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("should pass the identity to unary handlers", func(t *testing.T) {
+		_, err := notifications.Broadcast(ctx, &pb.Message{})
+		require.NoError(t, err)
+		assert.Equal(t, spiffeID.String(), <-srv.identities)
+	})
+
+	t.Run("should pass the identity to stream handlers", func(t *testing.T) {
+		stream, err := notifications.PublishStream(ctx)
+		require.NoError(t, err)
+		_, err = stream.CloseAndRecv()
+		require.NoError(t, err)
+		assert.Equal(t, spiffeID.String(), <-srv.identities)
+	})
+
+	t.Run("should reject the identities not allowed", func(t *testing.T) {
+		other := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "other"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, &ca)
+		notifications := dial(t, other)
+
+		_, err := notifications.Broadcast(ctx, &pb.Message{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		stream, err := notifications.PublishStream(ctx)
+		require.NoError(t, err)
+		_, err = stream.CloseAndRecv()
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Empty(t, srv.identities)
+	})
+}
+
+func TestTenantAuth_identity(t *testing.T) {
+	auth := tenantAuth{keys: newAPIKeys(&config.EnvConfig{Tenancy: config.TenancyConfig{
+		Claim:           "tenant",
+		GRPCKeys:        map[string]string{"key-a": "acme"},
+		IdentityTenants: config.IdentityTenants{"spiffe://example.com/billing": "globex"},
+	}})}
+	method := "/" + pb.NotificationService_ServiceDesc.ServiceName + "/Broadcast"
+	withIdentity := func(identity string) context.Context {
+		return context.WithValue(context.Background(), serviceIdentityKey{}, identity)
+	}
+
+	t.Run("should resolve the tenant of a mapped identity without a key", func(t *testing.T) {
+		ctx, err := auth.authenticate(withIdentity("spiffe://example.com/billing"), method)
+		require.NoError(t, err)
+		assert.Equal(t, "globex", notifications.TenantFromContext(ctx))
+	})
+
+	t.Run("should require a key from the identities not mapped", func(t *testing.T) {
+		_, err := auth.authenticate(withIdentity("spiffe://example.com/other"), method)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		ctx := metadata.NewIncomingContext(withIdentity("spiffe://example.com/other"), metadata.Pairs("authorization", "Bearer key-a"))
+		ctx, err = auth.authenticate(ctx, method)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", notifications.TenantFromContext(ctx))
+	})
+}
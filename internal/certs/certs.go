@@ -0,0 +1,171 @@
+// Package certs serves TLS certificates loaded from files, reloading them
+// when the files change so that renewed certificates apply without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+)
+
+// nextProtos are the protocols negotiated through ALPN.
+var nextProtos = []string{"h2", "http/1.1"}
+
+// Store holds a certificate and, for mutual TLS, the CAs client certificates
+// must be signed by.
+type Store struct {
+	certFile, keyFile, clientCAFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+
+	// modified is the latest modification time of the files loaded.
+	modified time.Time
+}
+
+// NewStore loads the certificate and key files, and the client CA file when
+// not empty.
+func NewStore(certFile, keyFile, clientCAFile string) (*Store, error) {
+	s := &Store{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the files, replacing the certificate and CAs once all of them
+// were read.
+func (s *Store) load() error {
+	modified := s.filesModified()
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return fmt.Errorf("certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if s.clientCAFile != "" {
+		pem, err := os.ReadFile(s.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("client CA: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.New("client CA: no certificate found")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cert = &cert
+	s.clientCAs = clientCAs
+	s.modified = modified
+	return nil
+}
+
+// filesModified returns the latest modification time of the files, zero when
+// one cannot be read, e.g. while it is replaced.
+func (s *Store) filesModified() time.Time {
+	var latest time.Time
+	for _, path := range []string{s.certFile, s.keyFile, s.clientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// Watch reloads the files every interval they changed, until the context is
+// cancelled. Files failing to load are logged and the certificate in use is
+// kept.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.reloadChanged()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reloadChanged reloads the files when they changed since the last load.
+func (s *Store) reloadChanged() {
+	s.mu.RLock()
+	loaded := s.modified
+	s.mu.RUnlock()
+	if modified := s.filesModified(); modified.IsZero() || modified.Equal(loaded) {
+		return
+	}
+
+	if err := s.load(); err != nil {
+		metrics.CertificateReloads.Add(metrics.ReloadFailed, 1)
+		slog.Warn("certs: reload failed, keeping the current certificate", "file", s.certFile, "err", err)
+		return
+	}
+	metrics.CertificateReloads.Add(metrics.ReloadSucceeded, 1)
+	slog.Info("certs: reloaded", "file", s.certFile)
+}
+
+// ServerConfig returns the TLS configuration of a server using the current
+// certificate, negotiating HTTP/2 and HTTP/1.1. Unless clientAuth is
+// tls.NoClientCert, client certificates are verified against the current
+// client CAs.
+func (s *Store) ServerConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: s.certificate,
+		NextProtos:     nextProtos,
+	}
+	if clientAuth != tls.NoClientCert {
+		// Each handshake verifies clients against the CAs loaded last. The
+		// config returned replaces cfg, so it repeats all of its settings.
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: s.certificate,
+				NextProtos:     nextProtos,
+				ClientAuth:     clientAuth,
+				ClientCAs:      s.clientCAs,
+			}, nil
+		}
+	}
+	return cfg
+}
+
+func (s *Store) certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, nil
+}
+
+// Identity returns the service identity of a client certificate: its first
+// URI SAN, e.g. a SPIFFE ID, else its first DNS SAN, else its common name.
+func Identity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,104 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/certs/certstest"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.pem")
+
+	ca := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "ca"}, IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil)
+	server := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "server"}, IPAddresses: []net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 1)}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, &ca)
+	certstest.WritePEM(t, server, certFile, keyFile)
+	certstest.WritePEM(t, ca, caFile, "")
+
+	s, err := NewStore(certFile, keyFile, caFile)
+	require.NoError(t, err)
+
+	t.Run("should reload changed files", func(t *testing.T) {
+		renewed := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "renewed"}}, &ca)
+		certstest.WritePEM(t, renewed, certFile, keyFile)
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(certFile, later, later))
+
+		s.reloadChanged()
+		cert, err := s.certificate(nil)
+		require.NoError(t, err)
+		assert.Equal(t, renewed.Certificate[0], cert.Certificate[0])
+	})
+
+	t.Run("should keep the certificate when the files are invalid", func(t *testing.T) {
+		before, err := s.certificate(nil)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+		later := time.Now().Add(2 * time.Minute)
+		require.NoError(t, os.Chtimes(keyFile, later, later))
+
+		s.reloadChanged()
+		cert, err := s.certificate(nil)
+		require.NoError(t, err)
+		assert.Same(t, before, cert)
+
+		certstest.WritePEM(t, server, certFile, keyFile)
+		later = time.Now().Add(3 * time.Minute)
+		require.NoError(t, os.Chtimes(keyFile, later, later))
+		s.reloadChanged()
+	})
+
+	t.Run("should require client certificates with mutual TLS", func(t *testing.T) {
+		lis, err := tls.Listen("tcp", "127.0.0.1:0", s.ServerConfig(tls.RequireAndVerifyClientCert))
+		require.NoError(t, err)
+		defer lis.Close()
+		go func() {
+			for {
+				conn, err := lis.Accept()
+				if err != nil {
+					return
+				}
+				_ = conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}
+		}()
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.Leaf)
+		dial := func(certs ...tls.Certificate) error {
+			conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{RootCAs: roots, Certificates: certs, MinVersion: tls.VersionTLS13})
+			if err != nil {
+				return err
+			}
+			defer conn.Close()
+			// TLS 1.3 reports the rejection of the client certificate on the first read.
+			_, err = conn.Read(make([]byte, 1))
+			return err
+		}
+
+		assert.ErrorContains(t, dial(), "certificate required")
+
+		client := certstest.Issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "publisher"}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, &ca)
+		assert.NotContains(t, dial(client).Error(), "certificate")
+	})
+}
+
+func TestIdentity(t *testing.T) {
+	spiffe, err := url.Parse("spiffe://example.com/billing")
+	require.NoError(t, err)
+
+	assert.Equal(t, "spiffe://example.com/billing", Identity(&x509.Certificate{URIs: []*url.URL{spiffe}, DNSNames: []string{"billing.internal"}}))
+	assert.Equal(t, "billing.internal", Identity(&x509.Certificate{DNSNames: []string{"billing.internal"}}))
+	assert.Equal(t, "billing", Identity(&x509.Certificate{Subject: pkix.Name{CommonName: "billing"}}))
+}
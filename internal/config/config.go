@@ -1,27 +1,586 @@
 package config
 
 import (
+	"compress/flate"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
-	"strconv"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/ratelimit"
+)
+
+// Defaults of the optional settings.
+const (
+	defaultHTTPPort             = 3003
+	defaultGRPCPort             = 9003
+	defaultTokenKey             = "t"
+	defaultIdempotencyCacheSize = 10000
+	defaultIdempotencyWindow    = 10 * time.Minute
+	defaultMaxViolations        = 5
+	defaultRetryAfter           = 30 * time.Second
+	defaultMaxSubscriptions     = 100
+	defaultTokenExpiryWarning   = time.Minute
+	defaultCompressionLevel     = 1
+	defaultCompressionMinSize   = 256
+	defaultLogLevel             = "info"
+)
+
+// Defaults of the websocket timeouts and buffer sizes.
+const (
+	defaultWriteWait       = 10 * time.Second
+	defaultPongWait        = 60 * time.Second
+	defaultPingPeriod      = 45 * time.Second
+	defaultMaxMessageSize  = 512
+	defaultSendBuffer      = 256
+	defaultReadBufferSize  = 1024
+	defaultWriteBufferSize = 1024
+)
+
+// Defaults of the long-polling and server-sent events timeouts.
+const (
+	defaultPollTimeout       = 25 * time.Second
+	defaultPollSessionWindow = 60 * time.Second
+	defaultSSEResumeWindow   = 30 * time.Second
+)
+
+// Sources a websocket auth token can be read from.
+const (
+	TokenSourceQuery       = "query"
+	TokenSourceHeader      = "header"
+	TokenSourceCookie      = "cookie"
+	TokenSourceSubprotocol = "subprotocol"
+)
+
+// defaultTokenSources is the default priority order of the token sources. The
+// query string comes last as it leaks into proxy access logs. The cookie is
+// left out: it needs an origin allow-list, see validate.
+var defaultTokenSources = []string{TokenSourceHeader, TokenSourceSubprotocol, TokenSourceQuery}
+
+const defaultTokenCookie = "token"
+
+// Modes of the per user connection limit.
+const (
+	UserLimitReject      = "reject"
+	UserLimitEvictOldest = "evict_oldest"
+)
+
+// Default inbound rate limits, in frames per second.
+var (
+	defaultFramesLimit            = ratelimit.Limit{Rate: 10, Burst: 20}
+	defaultUserFramesLimit        = ratelimit.Limit{Rate: 20, Burst: 50}
+	defaultSubscriptionsLimit     = ratelimit.Limit{Rate: 5, Burst: 20}
+	defaultUserSubscriptionsLimit = ratelimit.Limit{Rate: 10, Burst: 50}
 )
 
+// EnvConfig is the configuration of the server. The yaml keys are those of
+// the configuration file and, joined with dots, of the command-line flags.
+// Settings tagged reload are applied by reloads, see Reloaded, the others
+// need a restart.
 type EnvConfig struct {
-	TokenKey string
-	GRPCPort int
-	HTTPPort int
+	HTTPPort int `yaml:"httpPort"`
+	GRPCPort int `yaml:"grpcPort"`
+	// SinglePort serves gRPC on the HTTP port too, GRPCPort being unused.
+	SinglePort bool `yaml:"singlePort"`
+
+	Auth AuthConfig `yaml:"auth"`
+
+	Tenancy TenancyConfig `yaml:"tenancy"`
+
+	Limits LimitsConfig `yaml:"limits" reload:"true"`
+
+	Transport TransportConfig `yaml:"transport"`
+
+	TLS TLSConfig `yaml:"tls"`
+
+	Observability ObservabilityConfig `yaml:"observability"`
+
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+
+	// ScheduleStorePath is the file persisting scheduled notifications.
+	// Scheduled notifications are kept in memory only when empty.
+	ScheduleStorePath string `yaml:"scheduleStorePath"`
+
+	// AutoJoinRooms are room templates with {claim} placeholders, e.g.
+	// "tenant:{tid}", joined on connect and not leavable.
+	AutoJoinRooms []string `yaml:"autoJoinRooms"`
+
+	// file is the configuration file read, if any.
+	file string
+}
+
+// AuthConfig sets how websocket clients and gRPC operators authenticate.
+type AuthConfig struct {
+	// TokenKey is the query parameter holding a websocket auth token.
+	TokenKey string `yaml:"tokenKey"`
+	// TokenSources are the places a websocket auth token is looked for, in priority order.
+	TokenSources []string `yaml:"tokenSources"`
+	// TokenCookie is the name of the cookie holding the auth token.
+	TokenCookie string `yaml:"tokenCookie"`
+	// TokenExpiryWarning is how long before its token expires a connection
+	// is asked to re-authenticate.
+	TokenExpiryWarning time.Duration `yaml:"tokenExpiryWarning"`
+
+	// AdminToken is the bearer token required by the admin gRPC service,
+	// which is disabled when empty.
+	AdminToken string `yaml:"adminToken" secret:"true" reload:"true"`
+
+	// AllowedOrigins lists the origins allowed to open websockets, any when empty.
+	AllowedOrigins []string `yaml:"allowedOrigins" reload:"true"`
+	// AllowEmptyOrigin accepts websockets without Origin header, as opened by native apps.
+	AllowEmptyOrigin bool `yaml:"allowEmptyOrigin" reload:"true"`
+}
+
+// TenancyConfig isolates the rooms, users and broadcasts of several tenants
+// served by one deployment. Tenancy is disabled when Claim is empty.
+type TenancyConfig struct {
+	// Claim names the token claim holding the tenant of a websocket.
+	Claim string `yaml:"claim"`
+
+	// GRPCKeys maps the bearer tokens of gRPC callers to their tenant.
+	GRPCKeys map[string]string `yaml:"grpcKeys" secret:"true" reload:"true"`
+
+	// IdentityTenants maps the service identities of mutual TLS callers to
+	// their tenant, which they act on without a bearer token.
+	IdentityTenants IdentityTenants `yaml:"identityTenants" reload:"true"`
+}
+
+// LimitsConfig caps what clients can do and hold.
+type LimitsConfig struct {
+	RateLimits RateLimitConfig `yaml:"rate"`
+
+	Connections ConnectionConfig `yaml:"connections"`
+
+	// MaxSubscriptions is the maximum number of rooms a connection can be in.
+	MaxSubscriptions int `yaml:"maxSubscriptions"`
+	// MaxRooms is the maximum number of rooms with at least one member.
+	MaxRooms int `yaml:"maxRooms"`
+}
+
+// TransportConfig holds the timeouts and buffer sizes of websocket connections.
+type TransportConfig struct {
+	// WriteWait is the time allowed to write a message to the peer.
+	WriteWait time.Duration `yaml:"writeWait"`
+	// PongWait is the time allowed to read the next pong from the peer.
+	PongWait time.Duration `yaml:"pongWait"`
+	// PingPeriod is how often peers are pinged, less than PongWait.
+	PingPeriod time.Duration `yaml:"pingPeriod"`
+	// MaxMessageSize is the maximum size in bytes of a frame read from a peer.
+	MaxMessageSize int64 `yaml:"maxMessageSize"`
+	// SendBuffer is the number of messages queued per connection.
+	SendBuffer int `yaml:"sendBuffer"`
+	// ReadBufferSize and WriteBufferSize are the websocket I/O buffer sizes in bytes.
+	ReadBufferSize  int `yaml:"readBufferSize"`
+	WriteBufferSize int `yaml:"writeBufferSize"`
+
+	// PollTimeout is how long a long poll is held open without messages.
+	PollTimeout time.Duration `yaml:"pollTimeout"`
+	// PollSessionWindow is how long a long-polling client stays registered
+	// between two polls.
+	PollSessionWindow time.Duration `yaml:"pollSessionWindow"`
+	// SSEResumeWindow is how long a client whose event stream dropped stays
+	// registered, waiting for a reconnect to resume it.
+	SSEResumeWindow time.Duration `yaml:"sseResumeWindow"`
+
+	Compression CompressionConfig `yaml:"compression"`
+
+	// Routes are extra websocket routes with their own settings, e.g. a
+	// larger read limit for backends. They are only read from the file.
+	Routes []RouteConfig `yaml:"routes"`
+}
+
+// RouteConfig is a websocket route whose connections override the transport
+// settings. The fields left zero keep the transport ones.
+type RouteConfig struct {
+	// Path is the path the route is served on, e.g. /ws/backend.
+	Path string `yaml:"path"`
+
+	WriteWait       time.Duration `yaml:"writeWait"`
+	PongWait        time.Duration `yaml:"pongWait"`
+	PingPeriod      time.Duration `yaml:"pingPeriod"`
+	MaxMessageSize  int64         `yaml:"maxMessageSize"`
+	SendBuffer      int           `yaml:"sendBuffer"`
+	ReadBufferSize  int           `yaml:"readBufferSize"`
+	WriteBufferSize int           `yaml:"writeBufferSize"`
 }
 
-func LoadConfiguration() (*EnvConfig, error) {
-	grpcPort, err1 := strconv.Atoi(os.Getenv("GRPC_PORT"))
-	httpPort, err2 := strconv.Atoi(os.Getenv("HTTP_PORT"))
-	if errs := errors.Join(err1, err2); errs != nil {
-		return nil, errs
+// CompressionConfig negotiates permessage-deflate with websocket peers.
+type CompressionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Level is a compress/flate level, from -2 (Huffman only) to 9.
+	Level int `yaml:"level"`
+	// MinSize is the frame size in bytes from which frames are compressed.
+	MinSize int `yaml:"minSize"`
+}
+
+// TLSConfig serves the HTTP and gRPC listeners over TLS. The files are
+// reloaded when they change. TLS is disabled when CertFile is empty.
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+
+	// ClientCAFile enables mutual TLS on the gRPC listener: callers must
+	// present a certificate signed by one of its CAs.
+	ClientCAFile string `yaml:"clientCAFile"`
+	// AllowedIdentities are the service identities of the callers allowed
+	// with mutual TLS, any signed by the CAs when empty.
+	AllowedIdentities []string `yaml:"allowedIdentities" reload:"true"`
+}
+
+// ObservabilityConfig sets how the server exposes its state.
+type ObservabilityConfig struct {
+	// MetricsPath is the HTTP path of the expvar counters, not served when
+	// empty. The counters are public on the HTTP port, so this is opt-in.
+	MetricsPath string `yaml:"metricsPath"`
+
+	// LogLevel is the minimum level of the records logged: debug, info,
+	// warn or error.
+	LogLevel string `yaml:"logLevel" reload:"true"`
+}
+
+// Level returns the parsed LogLevel, info when it is invalid.
+func (o ObservabilityConfig) Level() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(o.LogLevel)); err != nil {
+		return slog.LevelInfo
 	}
+	return level
+}
+
+// IdempotencyConfig sets how publishes are deduplicated.
+type IdempotencyConfig struct {
+	// CacheSize is the maximum number of idempotency keys remembered.
+	CacheSize int `yaml:"cacheSize"`
+	// Window is how long an idempotency key is remembered.
+	Window time.Duration `yaml:"window"`
+}
+
+// ConnectionConfig caps the number of websocket connections. Zero is unlimited.
+type ConnectionConfig struct {
+	Max          int `yaml:"max"`
+	MaxPerTenant int `yaml:"maxPerTenant"`
+	MaxPerUser   int `yaml:"maxPerUser"`
+	MaxPerIP     int `yaml:"maxPerIP"`
 
+	// UserLimitMode is UserLimitReject or UserLimitEvictOldest.
+	UserLimitMode string `yaml:"userLimitMode"`
+
+	// RetryAfter is advertised to clients rejected over a limit.
+	RetryAfter time.Duration `yaml:"retryAfter"`
+
+	// TrustedProxies are the networks whose forwarding headers are honoured.
+	TrustedProxies Networks `yaml:"trustedProxies"`
+}
+
+// RateLimitConfig holds the inbound rate limits of socket clients.
+type RateLimitConfig struct {
+	Frames            ratelimit.Limit `yaml:"frames"`
+	UserFrames        ratelimit.Limit `yaml:"userFrames"`
+	Subscriptions     ratelimit.Limit `yaml:"subscriptions"`
+	UserSubscriptions ratelimit.Limit `yaml:"userSubscriptions"`
+
+	// MaxViolations is the number of frames over a limit tolerated before
+	// the connection is closed.
+	MaxViolations int `yaml:"maxViolations"`
+}
+
+// IdentityTenants maps service identities to tenants.
+type IdentityTenants map[string]string
+
+// Networks is a list of networks. A single address is read as a network of
+// that address only.
+type Networks []netip.Prefix
+
+// DefaultTransport returns the default connection settings, which the hub
+// also falls back to for the fields its options leave zero.
+func DefaultTransport() TransportConfig {
+	return TransportConfig{
+		WriteWait:       defaultWriteWait,
+		PongWait:        defaultPongWait,
+		PingPeriod:      defaultPingPeriod,
+		MaxMessageSize:  defaultMaxMessageSize,
+		SendBuffer:      defaultSendBuffer,
+		ReadBufferSize:  defaultReadBufferSize,
+		WriteBufferSize: defaultWriteBufferSize,
+
+		PollTimeout:       defaultPollTimeout,
+		PollSessionWindow: defaultPollSessionWindow,
+		SSEResumeWindow:   defaultSSEResumeWindow,
+
+		Compression: CompressionConfig{
+			Level:   defaultCompressionLevel,
+			MinSize: defaultCompressionMinSize,
+		},
+	}
+}
+
+// defaults returns the configuration used for the settings set nowhere else.
+func defaults() *EnvConfig {
 	return &EnvConfig{
-		TokenKey: os.Getenv("TOKEN_KEY"),
-		GRPCPort: grpcPort,
-		HTTPPort: httpPort,
-	}, nil
+		HTTPPort: defaultHTTPPort,
+		GRPCPort: defaultGRPCPort,
+		Auth: AuthConfig{
+			TokenKey:           defaultTokenKey,
+			TokenSources:       defaultTokenSources,
+			TokenCookie:        defaultTokenCookie,
+			TokenExpiryWarning: defaultTokenExpiryWarning,
+		},
+		Tenancy: TenancyConfig{
+			GRPCKeys: make(map[string]string),
+		},
+		Limits: LimitsConfig{
+			RateLimits: RateLimitConfig{
+				Frames:            defaultFramesLimit,
+				UserFrames:        defaultUserFramesLimit,
+				Subscriptions:     defaultSubscriptionsLimit,
+				UserSubscriptions: defaultUserSubscriptionsLimit,
+				MaxViolations:     defaultMaxViolations,
+			},
+			Connections: ConnectionConfig{
+				UserLimitMode: UserLimitReject,
+				RetryAfter:    defaultRetryAfter,
+			},
+			MaxSubscriptions: defaultMaxSubscriptions,
+		},
+		Transport: DefaultTransport(),
+		Observability: ObservabilityConfig{
+			LogLevel: defaultLogLevel,
+		},
+		Idempotency: IdempotencyConfig{
+			CacheSize: defaultIdempotencyCacheSize,
+			Window:    defaultIdempotencyWindow,
+		},
+	}
+}
+
+// Load reads the configuration file, if any, over the defaults, then the env
+// variables over the file and the command-line flags over the env variables.
+// The file is named by the --config flag, else by CONFIG_FILE. printConfig is
+// set by the --print-config flag.
+func Load(args []string) (cfg *EnvConfig, printConfig bool, err error) {
+	cfg = defaults()
+	fs, path := cfg.flagSet()
+	fs.BoolVar(&printConfig, "print-config", false, "print the effective configuration, secrets redacted, and exit")
+	// The flags are parsed twice: first to find the file, then over the
+	// file and the env variables.
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	if *path == "" {
+		*path = os.Getenv("CONFIG_FILE")
+	}
+
+	cfg = defaults()
+	if *path != "" {
+		if err := cfg.readFile(*path); err != nil {
+			return nil, false, err
+		}
+		cfg.file = *path
+	}
+	if err := cfg.readEnv(); err != nil {
+		return nil, false, err
+	}
+	fs, _ = cfg.flagSet()
+	fs.BoolVar(&printConfig, "print-config", false, "")
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+	return cfg, printConfig, cfg.validate()
+}
+
+// File returns the configuration file read, empty when there is none.
+func (c *EnvConfig) File() string {
+	return c.file
+}
+
+// validate checks the settings once every source was read, so that invalid
+// combinations are reported whichever sources they come from.
+func (c *EnvConfig) validate() error {
+	var errs []error
+	if c.HTTPPort <= 0 || c.GRPCPort <= 0 {
+		errs = append(errs, errors.New("httpPort (HTTP_PORT) and grpcPort (GRPC_PORT) must be positive"))
+	}
+	for _, source := range c.Auth.TokenSources {
+		switch source {
+		case TokenSourceQuery, TokenSourceHeader, TokenSourceCookie, TokenSourceSubprotocol:
+		default:
+			errs = append(errs, fmt.Errorf("invalid auth tokenSources (TOKEN_SOURCES) entry %q", source))
+		}
+	}
+	// Browsers send cookies with upgrades from any page, so any origin
+	// could otherwise open a socket authenticated as the user.
+	if slices.Contains(c.Auth.TokenSources, TokenSourceCookie) && len(c.Auth.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("auth tokenSources (TOKEN_SOURCES) cookie requires auth allowedOrigins (ALLOWED_ORIGINS)"))
+	}
+	switch c.Limits.Connections.UserLimitMode {
+	case UserLimitReject, UserLimitEvictOldest:
+	default:
+		errs = append(errs, fmt.Errorf("invalid limits connections userLimitMode (CONNECTION_USER_LIMIT_MODE) %q", c.Limits.Connections.UserLimitMode))
+	}
+	if c.Tenancy.Claim != "" && len(c.Tenancy.GRPCKeys) == 0 && len(c.Tenancy.IdentityTenants) == 0 {
+		errs = append(errs, errors.New("tenancy grpcKeys (GRPC_TENANT_KEYS) or identityTenants (GRPC_IDENTITY_TENANTS) are required with a tenancy claim (TENANT_CLAIM)"))
+	}
+	if len(c.Tenancy.IdentityTenants) > 0 && c.TLS.ClientCAFile == "" {
+		errs = append(errs, errors.New("tenancy identityTenants (GRPC_IDENTITY_TENANTS) require a tls clientCAFile (TLS_CLIENT_CA_FILE)"))
+	}
+	if level := c.Transport.Compression.Level; level < flate.HuffmanOnly || level > flate.BestCompression {
+		errs = append(errs, fmt.Errorf("invalid transport compression level (WS_COMPRESSION_LEVEL) %d, expected -2 to 9", level))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls certFile (TLS_CERT_FILE) and keyFile (TLS_KEY_FILE) must be set together"))
+	}
+	if c.TLS.ClientCAFile != "" && c.TLS.CertFile == "" {
+		errs = append(errs, errors.New("tls clientCAFile (TLS_CLIENT_CA_FILE) requires a certFile (TLS_CERT_FILE)"))
+	}
+	if len(c.TLS.AllowedIdentities) > 0 && c.TLS.ClientCAFile == "" {
+		errs = append(errs, errors.New("tls allowedIdentities (TLS_ALLOWED_IDENTITIES) require a clientCAFile (TLS_CLIENT_CA_FILE)"))
+	}
+	if path := c.Observability.MetricsPath; path != "" && !strings.HasPrefix(path, "/") {
+		errs = append(errs, fmt.Errorf("observability metricsPath (METRICS_PATH) %q must start with /", path))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Observability.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("invalid observability logLevel (LOG_LEVEL) %q, expected debug, info, warn or error", c.Observability.LogLevel))
+	}
+	errs = append(errs, c.validateNotNegative(), c.validateBursts(), c.Transport.Validate(), c.validateRoutes())
+	return errors.Join(errs...)
+}
+
+// validateNotNegative checks the limits, for which zero means unlimited or
+// disabled.
+func (c *EnvConfig) validateNotNegative() error {
+	conns, rate := c.Limits.Connections, c.Limits.RateLimits
+	var errs []error
+	for _, v := range []struct {
+		key, env string
+		value    float64
+	}{
+		{"limits connections max", "MAX_CONNECTIONS", float64(conns.Max)},
+		{"limits connections maxPerTenant", "MAX_CONNECTIONS_PER_TENANT", float64(conns.MaxPerTenant)},
+		{"limits connections maxPerUser", "MAX_CONNECTIONS_PER_USER", float64(conns.MaxPerUser)},
+		{"limits connections maxPerIP", "MAX_CONNECTIONS_PER_IP", float64(conns.MaxPerIP)},
+		{"limits connections retryAfter", "CONNECTION_RETRY_AFTER", float64(conns.RetryAfter)},
+		{"limits rate frames rate", "RATE_LIMIT_FRAMES", rate.Frames.Rate},
+		{"limits rate frames burst", "RATE_LIMIT_FRAMES_BURST", float64(rate.Frames.Burst)},
+		{"limits rate userFrames rate", "RATE_LIMIT_USER_FRAMES", rate.UserFrames.Rate},
+		{"limits rate userFrames burst", "RATE_LIMIT_USER_FRAMES_BURST", float64(rate.UserFrames.Burst)},
+		{"limits rate subscriptions rate", "RATE_LIMIT_SUBSCRIPTIONS", rate.Subscriptions.Rate},
+		{"limits rate subscriptions burst", "RATE_LIMIT_SUBSCRIPTIONS_BURST", float64(rate.Subscriptions.Burst)},
+		{"limits rate userSubscriptions rate", "RATE_LIMIT_USER_SUBSCRIPTIONS", rate.UserSubscriptions.Rate},
+		{"limits rate userSubscriptions burst", "RATE_LIMIT_USER_SUBSCRIPTIONS_BURST", float64(rate.UserSubscriptions.Burst)},
+		{"limits rate maxViolations", "RATE_LIMIT_MAX_VIOLATIONS", float64(rate.MaxViolations)},
+		{"limits maxSubscriptions", "MAX_SUBSCRIPTIONS_PER_CLIENT", float64(c.Limits.MaxSubscriptions)},
+		{"limits maxRooms", "MAX_ROOMS", float64(c.Limits.MaxRooms)},
+		{"transport compression minSize", "WS_COMPRESSION_MIN_SIZE", float64(c.Transport.Compression.MinSize)},
+		{"idempotency cacheSize", "IDEMPOTENCY_CACHE_SIZE", float64(c.Idempotency.CacheSize)},
+		{"idempotency window", "IDEMPOTENCY_WINDOW", float64(c.Idempotency.Window)},
+	} {
+		if v.value < 0 {
+			errs = append(errs, fmt.Errorf("%s (%s) must not be negative", v.key, v.env))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Route returns the settings of the connections of a route.
+func (t TransportConfig) Route(route RouteConfig) TransportConfig {
+	t.Routes = nil
+	if route.WriteWait != 0 {
+		t.WriteWait = route.WriteWait
+	}
+	if route.PongWait != 0 {
+		t.PongWait = route.PongWait
+	}
+	if route.PingPeriod != 0 {
+		t.PingPeriod = route.PingPeriod
+	}
+	if route.MaxMessageSize != 0 {
+		t.MaxMessageSize = route.MaxMessageSize
+	}
+	if route.SendBuffer != 0 {
+		t.SendBuffer = route.SendBuffer
+	}
+	if route.ReadBufferSize != 0 {
+		t.ReadBufferSize = route.ReadBufferSize
+	}
+	if route.WriteBufferSize != 0 {
+		t.WriteBufferSize = route.WriteBufferSize
+	}
+	return t
+}
+
+// validateBursts checks that the rate limits in force let some frames
+// through, as a bucket without burst never holds a token.
+func (c *EnvConfig) validateBursts() error {
+	rate := c.Limits.RateLimits
+	var errs []error
+	for _, v := range []struct {
+		key, env string
+		limit    ratelimit.Limit
+	}{
+		{"limits rate frames", "RATE_LIMIT_FRAMES", rate.Frames},
+		{"limits rate userFrames", "RATE_LIMIT_USER_FRAMES", rate.UserFrames},
+		{"limits rate subscriptions", "RATE_LIMIT_SUBSCRIPTIONS", rate.Subscriptions},
+		{"limits rate userSubscriptions", "RATE_LIMIT_USER_SUBSCRIPTIONS", rate.UserSubscriptions},
+	} {
+		if v.limit.Enabled() && v.limit.Burst < 1 {
+			errs = append(errs, fmt.Errorf("%s burst (%s_BURST) must be at least 1 when its rate is set", v.key, v.env))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that the values are positive and that peers are pinged
+// before their pong wait runs out.
+func (t TransportConfig) Validate() error {
+	var errs []error
+	for _, v := range []struct {
+		key, env string
+		value    int64
+	}{
+		{"writeWait", "WS_WRITE_WAIT", int64(t.WriteWait)},
+		{"pongWait", "WS_PONG_WAIT", int64(t.PongWait)},
+		{"pingPeriod", "WS_PING_PERIOD", int64(t.PingPeriod)},
+		{"maxMessageSize", "WS_MAX_MESSAGE_SIZE", t.MaxMessageSize},
+		{"sendBuffer", "WS_SEND_BUFFER", int64(t.SendBuffer)},
+		{"readBufferSize", "WS_READ_BUFFER_SIZE", int64(t.ReadBufferSize)},
+		{"writeBufferSize", "WS_WRITE_BUFFER_SIZE", int64(t.WriteBufferSize)},
+		{"pollTimeout", "POLL_TIMEOUT", int64(t.PollTimeout)},
+		{"pollSessionWindow", "POLL_SESSION_WINDOW", int64(t.PollSessionWindow)},
+		{"sseResumeWindow", "SSE_RESUME_WINDOW", int64(t.SSEResumeWindow)},
+	} {
+		if v.value <= 0 {
+			errs = append(errs, fmt.Errorf("transport %s (%s) must be positive", v.key, v.env))
+		}
+	}
+	if t.PingPeriod >= t.PongWait {
+		errs = append(errs, fmt.Errorf("transport pingPeriod (WS_PING_PERIOD) %v must be less than pongWait (WS_PONG_WAIT) %v", t.PingPeriod, t.PongWait))
+	}
+	return errors.Join(errs...)
+}
+
+// builtinRoutes are the paths served whatever the configuration.
+var builtinRoutes = []string{"/ws", "/events", "/poll"}
+
+// validateRoutes checks the websocket routes once merged with the transport.
+func (c *EnvConfig) validateRoutes() error {
+	var errs []error
+	seen := make(map[string]bool)
+	for _, route := range c.Transport.Routes {
+		switch {
+		case !strings.HasPrefix(route.Path, "/") || strings.ContainsAny(route.Path, "{} \t"):
+			errs = append(errs, fmt.Errorf("transport routes path %q must start with / and hold no pattern", route.Path))
+		case seen[route.Path] || slices.Contains(builtinRoutes, route.Path) || route.Path == c.Observability.MetricsPath:
+			errs = append(errs, fmt.Errorf("transport routes path %q is already served", route.Path))
+		}
+		seen[route.Path] = true
+		if err := c.Transport.Route(route).Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("transport routes %s: %w", route.Path, err))
+		}
+	}
+	return errors.Join(errs...)
 }
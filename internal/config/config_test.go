@@ -1,17 +1,30 @@
 package config
 
 import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
 
-func TestLoadConfiguration(t *testing.T) {
+// writeFile writes a configuration file to a temporary directory.
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_env(t *testing.T) {
 	t.Run("should error with invalid ports", func(t *testing.T) {
 		t.Setenv("GRPC_PORT", "a")
 		t.Setenv("HTTP_PORT", "b")
 		t.Setenv("TOKEN_KEY", "t")
-		_, err := LoadConfiguration()
+		_, _, err := Load(nil)
 		require.Error(t, err)
 	})
 
@@ -19,7 +32,388 @@ func TestLoadConfiguration(t *testing.T) {
 		t.Setenv("GRPC_PORT", "1001")
 		t.Setenv("HTTP_PORT", "1002")
 		t.Setenv("TOKEN_KEY", "t")
-		_, err := LoadConfiguration()
+		_, _, err := Load(nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("should default and parse optional settings", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "1001")
+		t.Setenv("HTTP_PORT", "1002")
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, defaultIdempotencyWindow, cfg.Idempotency.Window)
+		require.Equal(t, defaultTokenExpiryWarning, cfg.Auth.TokenExpiryWarning)
+
+		t.Setenv("IDEMPOTENCY_WINDOW", "30s")
+		t.Setenv("TOKEN_EXPIRY_WARNING", "2m")
+		cfg, _, err = Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, 30*time.Second, cfg.Idempotency.Window)
+		require.Equal(t, 2*time.Minute, cfg.Auth.TokenExpiryWarning)
+
+		t.Setenv("IDEMPOTENCY_CACHE_SIZE", "many")
+		_, _, err = Load(nil)
+		require.Error(t, err)
+	})
+
+	t.Run("should require allowed origins with cookie tokens", func(t *testing.T) {
+		cfg, _, err := Load(nil)
 		require.NoError(t, err)
+		require.NotContains(t, cfg.Auth.TokenSources, TokenSourceCookie)
+
+		t.Setenv("TOKEN_SOURCES", "header,cookie")
+		_, _, err = Load(nil)
+		require.ErrorContains(t, err, "ALLOWED_ORIGINS")
+
+		t.Setenv("ALLOWED_ORIGINS", "https://app.example.com")
+		cfg, _, err = Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, []string{TokenSourceHeader, TokenSourceCookie}, cfg.Auth.TokenSources)
+	})
+
+	t.Run("should parse connection limits", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "1001")
+		t.Setenv("HTTP_PORT", "1002")
+		t.Setenv("CONNECTION_USER_LIMIT_MODE", UserLimitEvictOldest)
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, UserLimitEvictOldest, cfg.Limits.Connections.UserLimitMode)
+		require.Len(t, cfg.Limits.Connections.TrustedProxies, 2)
+
+		t.Setenv("CONNECTION_USER_LIMIT_MODE", "kick")
+		_, _, err = Load(nil)
+		require.Error(t, err)
+	})
+
+	t.Run("should reject negative values", func(t *testing.T) {
+		tests := []struct {
+			env, value string
+		}{
+			{"MAX_CONNECTIONS", "-1"},
+			{"MAX_CONNECTIONS_PER_TENANT", "-1"},
+			{"MAX_CONNECTIONS_PER_USER", "-1"},
+			{"MAX_CONNECTIONS_PER_IP", "-1"},
+			{"CONNECTION_RETRY_AFTER", "-1s"},
+			{"RATE_LIMIT_FRAMES", "-0.5"},
+			{"RATE_LIMIT_FRAMES_BURST", "-1"},
+			{"RATE_LIMIT_USER_FRAMES", "-1"},
+			{"RATE_LIMIT_USER_FRAMES_BURST", "-1"},
+			{"RATE_LIMIT_SUBSCRIPTIONS", "-1"},
+			{"RATE_LIMIT_SUBSCRIPTIONS_BURST", "-1"},
+			{"RATE_LIMIT_USER_SUBSCRIPTIONS", "-1"},
+			{"RATE_LIMIT_USER_SUBSCRIPTIONS_BURST", "-1"},
+			{"RATE_LIMIT_MAX_VIOLATIONS", "-1"},
+			{"MAX_SUBSCRIPTIONS_PER_CLIENT", "-1"},
+			{"MAX_ROOMS", "-1"},
+			{"IDEMPOTENCY_CACHE_SIZE", "-1"},
+			{"IDEMPOTENCY_WINDOW", "-1m"},
+			{"WS_SEND_BUFFER", "-1"},
+			{"WS_COMPRESSION_MIN_SIZE", "-1"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.env, func(t *testing.T) {
+				t.Setenv(tt.env, tt.value)
+				_, _, err := Load(nil)
+				require.ErrorContains(t, err, tt.env)
+			})
+		}
+	})
+
+	t.Run("should reject a zero burst with a rate set", func(t *testing.T) {
+		for _, env := range []string{
+			"RATE_LIMIT_FRAMES_BURST",
+			"RATE_LIMIT_USER_FRAMES_BURST",
+			"RATE_LIMIT_SUBSCRIPTIONS_BURST",
+			"RATE_LIMIT_USER_SUBSCRIPTIONS_BURST",
+		} {
+			t.Run(env, func(t *testing.T) {
+				t.Setenv(env, "0")
+				_, _, err := Load(nil)
+				require.ErrorContains(t, err, env)
+			})
+		}
+
+		t.Setenv("RATE_LIMIT_FRAMES", "0")
+		t.Setenv("RATE_LIMIT_FRAMES_BURST", "0")
+		_, _, err := Load(nil)
+		require.NoError(t, err, "a disabled limit needs no burst")
+	})
+
+	t.Run("should default the ports", func(t *testing.T) {
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, 3003, cfg.HTTPPort)
+		require.Equal(t, 9003, cfg.GRPCPort)
+	})
+
+	t.Run("should not serve the metrics by default", func(t *testing.T) {
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Empty(t, cfg.Observability.MetricsPath)
+
+		t.Setenv("METRICS_PATH", "/debug/vars")
+		cfg, _, err = Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, "/debug/vars", cfg.Observability.MetricsPath)
+	})
+
+	t.Run("should parse the log level", func(t *testing.T) {
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, slog.LevelInfo, cfg.Observability.Level())
+
+		t.Setenv("LOG_LEVEL", "debug")
+		cfg, _, err = Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, slog.LevelDebug, cfg.Observability.Level())
+
+		t.Setenv("LOG_LEVEL", "verbose")
+		_, _, err = Load(nil)
+		require.ErrorContains(t, err, "LOG_LEVEL")
+	})
+
+	t.Run("should parse tenancy", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "1001")
+		t.Setenv("HTTP_PORT", "1002")
+		t.Setenv("TENANT_CLAIM", "tid")
+		t.Setenv("GRPC_TENANT_KEYS", "key-a:acme, key-g:globex")
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, "tid", cfg.Tenancy.Claim)
+		require.Equal(t, map[string]string{"key-a": "acme", "key-g": "globex"}, cfg.Tenancy.GRPCKeys)
+
+		t.Setenv("GRPC_TENANT_KEYS", "")
+		_, _, err = Load(nil)
+		require.Error(t, err)
+
+		t.Setenv("GRPC_IDENTITY_TENANTS", "spiffe://example.com/billing:acme")
+		_, _, err = Load(nil)
+		require.ErrorContains(t, err, "TLS_CLIENT_CA_FILE")
+
+		t.Setenv("TLS_CERT_FILE", "server.pem")
+		t.Setenv("TLS_KEY_FILE", "server.key")
+		t.Setenv("TLS_CLIENT_CA_FILE", "ca.pem")
+		cfg, _, err = Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, IdentityTenants{"spiffe://example.com/billing": "acme"}, cfg.Tenancy.IdentityTenants)
+	})
+	t.Run("should parse compression", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "1001")
+		t.Setenv("HTTP_PORT", "1002")
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, CompressionConfig{Level: 1, MinSize: 256}, cfg.Transport.Compression)
+
+		t.Setenv("WS_COMPRESSION", "true")
+		t.Setenv("WS_COMPRESSION_LEVEL", "6")
+		t.Setenv("WS_COMPRESSION_MIN_SIZE", "1024")
+		cfg, _, err = Load(nil)
+		require.NoError(t, err)
+		require.Equal(t, CompressionConfig{Enabled: true, Level: 6, MinSize: 1024}, cfg.Transport.Compression)
+
+		t.Setenv("WS_COMPRESSION_LEVEL", "10")
+		_, _, err = Load(nil)
+		require.Error(t, err)
+	})
+	t.Run("should parse transport", func(t *testing.T) {
+		t.Setenv("GRPC_PORT", "1001")
+		t.Setenv("HTTP_PORT", "1002")
+		t.Setenv("SINGLE_PORT", "true")
+		t.Setenv("WS_PONG_WAIT", "30s")
+		t.Setenv("WS_PING_PERIOD", "20s")
+		t.Setenv("WS_MAX_MESSAGE_SIZE", "4096")
+		t.Setenv("POLL_TIMEOUT", "10s")
+		cfg, _, err := Load(nil)
+		require.NoError(t, err)
+		require.True(t, cfg.SinglePort)
+		require.Equal(t, TransportConfig{
+			WriteWait:       10 * time.Second,
+			PongWait:        30 * time.Second,
+			PingPeriod:      20 * time.Second,
+			MaxMessageSize:  4096,
+			SendBuffer:      256,
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+
+			PollTimeout:       10 * time.Second,
+			PollSessionWindow: 60 * time.Second,
+			SSEResumeWindow:   30 * time.Second,
+
+			Compression: CompressionConfig{Level: 1, MinSize: 256},
+		}, cfg.Transport)
+
+		t.Setenv("WS_PING_PERIOD", "30s")
+		_, _, err = Load(nil)
+		require.ErrorContains(t, err, "WS_PING_PERIOD")
+
+		t.Setenv("WS_PING_PERIOD", "20s")
+		t.Setenv("WS_SEND_BUFFER", "0")
+		_, _, err = Load(nil)
+		require.ErrorContains(t, err, "WS_SEND_BUFFER")
+
+		t.Setenv("WS_SEND_BUFFER", "256")
+		t.Setenv("SSE_RESUME_WINDOW", "0s")
+		_, _, err = Load(nil)
+		require.ErrorContains(t, err, "SSE_RESUME_WINDOW")
+	})
+}
+
+func TestLoad(t *testing.T) {
+	path := writeFile(t, `
+httpPort: 8080
+auth:
+  adminToken: s3cret
+tenancy:
+  claim: tid
+  grpcKeys:
+    key-a: acme
+limits:
+  rate:
+    frames:
+      rate: 2
+      burst: 4
+  connections:
+    trustedProxies: [10.0.0.0/8, 192.168.1.1]
+transport:
+  pingPeriod: 20s
+  pongWait: 30s
+`)
+
+	t.Run("should layer the file, env and flags over the defaults", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", path)
+		t.Setenv("WS_PONG_WAIT", "40s")
+		t.Setenv("MAX_ROOMS", "50")
+		cfg, printConfig, err := Load([]string{"--limits.maxRooms=60", "--transport.compression.enabled"})
+		require.NoError(t, err)
+		require.False(t, printConfig)
+
+		require.Equal(t, 8080, cfg.HTTPPort)
+		require.Equal(t, defaultGRPCPort, cfg.GRPCPort)
+		require.Equal(t, 2.0, cfg.Limits.RateLimits.Frames.Rate)
+		require.Equal(t, 4, cfg.Limits.RateLimits.Frames.Burst)
+		require.Equal(t, defaultUserFramesLimit, cfg.Limits.RateLimits.UserFrames)
+		require.Len(t, cfg.Limits.Connections.TrustedProxies, 2)
+		require.Equal(t, 20*time.Second, cfg.Transport.PingPeriod)
+		require.Equal(t, 40*time.Second, cfg.Transport.PongWait)
+		require.Equal(t, 60, cfg.Limits.MaxRooms)
+		require.True(t, cfg.Transport.Compression.Enabled)
+	})
+
+	t.Run("should prefer the config flag to CONFIG_FILE", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		cfg, _, err := Load([]string{"--config", path})
+		require.NoError(t, err)
+		require.Equal(t, 8080, cfg.HTTPPort)
+	})
+
+	t.Run("should reject unknown keys", func(t *testing.T) {
+		_, _, err := Load([]string{"--config", writeFile(t, "transport:\n  pingPeriods: 20s\n")})
+		require.ErrorContains(t, err, "pingPeriods")
+
+		_, _, err = Load([]string{"--transport.pingPeriods=20s"})
+		require.ErrorContains(t, err, "pingPeriods")
+	})
+
+	t.Run("should validate the merged settings", func(t *testing.T) {
+		_, _, err := Load([]string{"--config", path, "--transport.pingPeriod=1m"})
+		require.ErrorContains(t, err, "pingPeriod")
+
+		_, _, err = Load([]string{"--tenancy.claim=tid"})
+		require.ErrorContains(t, err, "grpcKeys")
+
+		_, _, err = Load([]string{"--tls.certFile=server.pem"})
+		require.ErrorContains(t, err, "TLS_KEY_FILE")
+
+		_, _, err = Load([]string{"--tls.clientCAFile=ca.pem"})
+		require.ErrorContains(t, err, "TLS_CLIENT_CA_FILE")
+
+		_, _, err = Load([]string{"--tls.allowedIdentities=billing"})
+		require.ErrorContains(t, err, "TLS_ALLOWED_IDENTITIES")
+	})
+
+	t.Run("should merge the routes with the transport", func(t *testing.T) {
+		cfg, _, err := Load([]string{"--config", writeFile(t, `
+transport:
+  pingPeriod: 20s
+  pongWait: 30s
+  routes:
+    - path: /ws/backend
+      maxMessageSize: 65536
+`)})
+		require.NoError(t, err)
+		require.Equal(t, []RouteConfig{{Path: "/ws/backend", MaxMessageSize: 65536}}, cfg.Transport.Routes)
+
+		route := cfg.Transport.Route(cfg.Transport.Routes[0])
+		require.Equal(t, int64(65536), route.MaxMessageSize)
+		require.Equal(t, 20*time.Second, route.PingPeriod)
+		require.Empty(t, route.Routes)
+
+		_, _, err = Load([]string{"--config", writeFile(t, "transport:\n  routes:\n    - path: /ws/slow\n      pingPeriod: 2m\n")})
+		require.ErrorContains(t, err, "/ws/slow")
+		require.ErrorContains(t, err, "pingPeriod")
+
+		_, _, err = Load([]string{"--config", writeFile(t, "transport:\n  routes:\n    - path: /ws\n")})
+		require.ErrorContains(t, err, "already served")
+
+		_, _, err = Load([]string{"--config", writeFile(t, "transport:\n  routes:\n    - path: ws/backend\n")})
+		require.ErrorContains(t, err, "must start with /")
+	})
+
+	t.Run("should print the config with secrets redacted", func(t *testing.T) {
+		cfg, printConfig, err := Load([]string{"--config", path, "--print-config"})
+		require.NoError(t, err)
+		require.True(t, printConfig)
+
+		var out bytes.Buffer
+		require.NoError(t, cfg.WriteRedacted(&out))
+		require.NotContains(t, out.String(), "s3cret")
+		require.NotContains(t, out.String(), "key-a")
+		require.Contains(t, out.String(), "adminToken: REDACTED")
+		require.Contains(t, out.String(), "REDACTED-1: acme")
+		require.Contains(t, out.String(), "pingPeriod: 20s")
+		require.Equal(t, "s3cret", cfg.Auth.AdminToken)
+
+		printed := writeFile(t, out.String())
+		_, _, err = Load([]string{"--config", printed})
+		require.NoError(t, err, "the printed config should be readable")
+	})
+}
+
+func TestReloaded(t *testing.T) {
+	t.Run("should apply the reloadable settings", func(t *testing.T) {
+		current := defaults()
+		next := defaults()
+		next.HTTPPort = 8080
+		next.Auth.AllowedOrigins = []string{"https://app.example.com"}
+		next.Limits.RateLimits.Frames.Burst = 40
+		next.Transport.PingPeriod = 20 * time.Second
+
+		cfg, changed, restart, err := Reloaded(current, next)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []string{"auth.allowedOrigins", "limits.rate.frames.burst"}, changed)
+		require.ElementsMatch(t, []string{"httpPort", "transport.pingPeriod"}, restart)
+
+		require.Equal(t, next.Auth.AllowedOrigins, cfg.Auth.AllowedOrigins)
+		require.Equal(t, 40, cfg.Limits.RateLimits.Frames.Burst)
+		require.Equal(t, defaultHTTPPort, cfg.HTTPPort)
+		require.Equal(t, defaultPingPeriod, cfg.Transport.PingPeriod)
+		require.Empty(t, current.Auth.AllowedOrigins, "the current configuration should be left untouched")
+	})
+
+	t.Run("should reject settings invalid with the ones kept", func(t *testing.T) {
+		current := defaults()
+		current.Auth.TokenSources = []string{TokenSourceCookie}
+		current.Auth.AllowedOrigins = []string{"https://app.example.com"}
+		// The token sources need a restart, the origins are reloaded.
+		next := defaults()
+		next.Limits.MaxRooms = 10
+
+		cfg, changed, _, err := Reloaded(current, next)
+		require.ErrorContains(t, err, "ALLOWED_ORIGINS")
+		require.Same(t, current, cfg)
+		require.ElementsMatch(t, []string{"auth.allowedOrigins", "limits.maxRooms"}, changed)
+		require.Equal(t, []string{"https://app.example.com"}, current.Auth.AllowedOrigins)
+		require.Zero(t, current.Limits.MaxRooms)
 	})
 }
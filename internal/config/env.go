@@ -0,0 +1,263 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/ratelimit"
+)
+
+// readEnv overrides the settings whose env variable is set.
+func (c *EnvConfig) readEnv() error {
+	var errs [6]error
+	c.HTTPPort, errs[0] = envInt("HTTP_PORT", c.HTTPPort)
+	c.GRPCPort, errs[1] = envInt("GRPC_PORT", c.GRPCPort)
+	errs[2] = c.readAuthEnv()
+	errs[3] = c.readLimitsEnv()
+	errs[4] = c.readTransportEnv()
+	errs[5] = c.readTenancyEnv()
+
+	var err1, err2, err3 error
+	c.SinglePort, err3 = envBool("SINGLE_PORT", c.SinglePort)
+	c.Idempotency.CacheSize, err1 = envInt("IDEMPOTENCY_CACHE_SIZE", c.Idempotency.CacheSize)
+	c.Idempotency.Window, err2 = envDuration("IDEMPOTENCY_WINDOW", c.Idempotency.Window)
+	c.ScheduleStorePath = envString("SCHEDULE_STORE_PATH", c.ScheduleStorePath)
+	c.AutoJoinRooms = envListOr("AUTO_JOIN_ROOMS", c.AutoJoinRooms)
+	c.Observability.MetricsPath = envString("METRICS_PATH", c.Observability.MetricsPath)
+	c.Observability.LogLevel = envString("LOG_LEVEL", c.Observability.LogLevel)
+	c.TLS.CertFile = envString("TLS_CERT_FILE", c.TLS.CertFile)
+	c.TLS.KeyFile = envString("TLS_KEY_FILE", c.TLS.KeyFile)
+	c.TLS.ClientCAFile = envString("TLS_CLIENT_CA_FILE", c.TLS.ClientCAFile)
+	c.TLS.AllowedIdentities = envListOr("TLS_ALLOWED_IDENTITIES", c.TLS.AllowedIdentities)
+
+	return errors.Join(append(errs[:], err1, err2, err3)...)
+}
+
+func (c *EnvConfig) readAuthEnv() error {
+	auth := &c.Auth
+	auth.TokenKey = envString("TOKEN_KEY", auth.TokenKey)
+	auth.TokenSources = envListOr("TOKEN_SOURCES", auth.TokenSources)
+	auth.TokenCookie = envString("TOKEN_COOKIE", auth.TokenCookie)
+	auth.AdminToken = envString("ADMIN_TOKEN", auth.AdminToken)
+	auth.AllowedOrigins = envListOr("ALLOWED_ORIGINS", auth.AllowedOrigins)
+
+	var err1, err2 error
+	auth.TokenExpiryWarning, err1 = envDuration("TOKEN_EXPIRY_WARNING", auth.TokenExpiryWarning)
+	auth.AllowEmptyOrigin, err2 = envBool("ALLOW_EMPTY_ORIGIN", auth.AllowEmptyOrigin)
+	return errors.Join(err1, err2)
+}
+
+func (c *EnvConfig) readLimitsEnv() error {
+	limits := &c.Limits
+	rate := &limits.RateLimits
+	conns := &limits.Connections
+
+	var errs [12]error
+	rate.Frames, errs[0] = envLimit("RATE_LIMIT_FRAMES", rate.Frames)
+	rate.UserFrames, errs[1] = envLimit("RATE_LIMIT_USER_FRAMES", rate.UserFrames)
+	rate.Subscriptions, errs[2] = envLimit("RATE_LIMIT_SUBSCRIPTIONS", rate.Subscriptions)
+	rate.UserSubscriptions, errs[3] = envLimit("RATE_LIMIT_USER_SUBSCRIPTIONS", rate.UserSubscriptions)
+	rate.MaxViolations, errs[4] = envInt("RATE_LIMIT_MAX_VIOLATIONS", rate.MaxViolations)
+
+	conns.Max, errs[5] = envInt("MAX_CONNECTIONS", conns.Max)
+	conns.MaxPerTenant, errs[6] = envInt("MAX_CONNECTIONS_PER_TENANT", conns.MaxPerTenant)
+	conns.MaxPerUser, errs[7] = envInt("MAX_CONNECTIONS_PER_USER", conns.MaxPerUser)
+	conns.MaxPerIP, errs[8] = envInt("MAX_CONNECTIONS_PER_IP", conns.MaxPerIP)
+	conns.RetryAfter, errs[9] = envDuration("CONNECTION_RETRY_AFTER", conns.RetryAfter)
+	conns.UserLimitMode = envString("CONNECTION_USER_LIMIT_MODE", conns.UserLimitMode)
+	if v := envList("TRUSTED_PROXIES"); len(v) > 0 {
+		conns.TrustedProxies, errs[10] = parseNetworks(v)
+		if errs[10] != nil {
+			errs[10] = fmt.Errorf("TRUSTED_PROXIES: %w", errs[10])
+		}
+	}
+
+	limits.MaxSubscriptions, errs[11] = envInt("MAX_SUBSCRIPTIONS_PER_CLIENT", limits.MaxSubscriptions)
+	var err error
+	limits.MaxRooms, err = envInt("MAX_ROOMS", limits.MaxRooms)
+	return errors.Join(append(errs[:], err)...)
+}
+
+func (c *EnvConfig) readTransportEnv() error {
+	t := &c.Transport
+
+	var errs [13]error
+	t.WriteWait, errs[0] = envDuration("WS_WRITE_WAIT", t.WriteWait)
+	t.PongWait, errs[1] = envDuration("WS_PONG_WAIT", t.PongWait)
+	t.PingPeriod, errs[2] = envDuration("WS_PING_PERIOD", t.PingPeriod)
+	var maxMessageSize int
+	maxMessageSize, errs[3] = envInt("WS_MAX_MESSAGE_SIZE", int(t.MaxMessageSize))
+	t.MaxMessageSize = int64(maxMessageSize)
+	t.SendBuffer, errs[4] = envInt("WS_SEND_BUFFER", t.SendBuffer)
+	t.ReadBufferSize, errs[5] = envInt("WS_READ_BUFFER_SIZE", t.ReadBufferSize)
+	t.WriteBufferSize, errs[6] = envInt("WS_WRITE_BUFFER_SIZE", t.WriteBufferSize)
+
+	t.Compression.Enabled, errs[7] = envBool("WS_COMPRESSION", t.Compression.Enabled)
+	t.Compression.Level, errs[8] = envInt("WS_COMPRESSION_LEVEL", t.Compression.Level)
+	t.Compression.MinSize, errs[9] = envInt("WS_COMPRESSION_MIN_SIZE", t.Compression.MinSize)
+
+	t.PollTimeout, errs[10] = envDuration("POLL_TIMEOUT", t.PollTimeout)
+	t.PollSessionWindow, errs[11] = envDuration("POLL_SESSION_WINDOW", t.PollSessionWindow)
+	t.SSEResumeWindow, errs[12] = envDuration("SSE_RESUME_WINDOW", t.SSEResumeWindow)
+	return errors.Join(errs[:]...)
+}
+
+// readTenancyEnv reads TENANT_CLAIM, and GRPC_TENANT_KEYS and
+// GRPC_IDENTITY_TENANTS, comma separated lists of key:tenant and
+// identity:tenant pairs.
+func (c *EnvConfig) readTenancyEnv() error {
+	c.Tenancy.Claim = envString("TENANT_CLAIM", c.Tenancy.Claim)
+	if v := envList("GRPC_TENANT_KEYS"); len(v) > 0 {
+		keys, err := parseTenantKeys(v)
+		if err != nil {
+			return fmt.Errorf("GRPC_TENANT_KEYS: %w", err)
+		}
+		c.Tenancy.GRPCKeys = keys
+	}
+	if v := envList("GRPC_IDENTITY_TENANTS"); len(v) > 0 {
+		identities, err := parseIdentityTenants(v)
+		if err != nil {
+			return fmt.Errorf("GRPC_IDENTITY_TENANTS: %w", err)
+		}
+		c.Tenancy.IdentityTenants = identities
+	}
+	return nil
+}
+
+// parseTenantKeys parses key:tenant pairs.
+func parseTenantKeys(entries []string) (map[string]string, error) {
+	keys := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		key, tenant, ok := strings.Cut(entry, ":")
+		if !ok || key == "" || tenant == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected key:tenant", entry)
+		}
+		keys[key] = tenant
+	}
+	return keys, nil
+}
+
+// parseIdentityTenants parses identity:tenant pairs. Identities may hold
+// colons, e.g. SPIFFE IDs, so pairs are split at their last one.
+func parseIdentityTenants(entries []string) (IdentityTenants, error) {
+	identities := make(IdentityTenants, len(entries))
+	for _, entry := range entries {
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 || i == len(entry)-1 {
+			return nil, fmt.Errorf("invalid entry %q, expected identity:tenant", entry)
+		}
+		identities[entry[:i]] = entry[i+1:]
+	}
+	return identities, nil
+}
+
+// parseNetworks parses networks, a single address being read as a network of
+// that address only.
+func parseNetworks(values []string) (Networks, error) {
+	var networks Networks
+	for _, v := range values {
+		if addr, err := netip.ParseAddr(v); err == nil {
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network: %w", err)
+		}
+		networks = append(networks, prefix)
+	}
+	return networks, nil
+}
+
+// envLimit returns the rate limit of the optional env variables key, in
+// tokens per second, and key_BURST. A rate of 0 disables the limit.
+func envLimit(key string, fallback ratelimit.Limit) (ratelimit.Limit, error) {
+	rate, err1 := envFloat(key, fallback.Rate)
+	burst, err2 := envInt(key+"_BURST", fallback.Burst)
+	return ratelimit.Limit{Rate: rate, Burst: burst}, errors.Join(err1, err2)
+}
+
+// envBool returns the boolean value of an optional env variable.
+func envBool(key string, fallback bool) (bool, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	return b, envError(key, err)
+}
+
+// envList returns the comma separated values of an optional env variable.
+func envList(key string) []string {
+	return splitList(os.Getenv(key))
+}
+
+// envListOr returns the comma separated values of an optional env variable,
+// or fallback when it has none.
+func envListOr(key string, fallback []string) []string {
+	if values := envList(key); len(values) > 0 {
+		return values
+	}
+	return fallback
+}
+
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// envFloat returns the float value of an optional env variable.
+func envFloat(key string, fallback float64) (float64, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f, envError(key, err)
+}
+
+// envString returns the value of an optional env variable.
+func envString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// envInt returns the integer value of an optional env variable.
+func envInt(key string, fallback int) (int, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback, nil
+	}
+	i, err := strconv.Atoi(v)
+	return i, envError(key, err)
+}
+
+// envDuration returns the duration value of an optional env variable, e.g. "10m".
+func envDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	return d, envError(key, err)
+}
+
+// envError names the env variable an error comes from.
+func envError(key string, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the secrets of a printed configuration.
+const redacted = "REDACTED"
+
+// readFile reads a YAML configuration file over the current settings. Keys
+// the configuration does not have are rejected, as they are most likely
+// typos of settings that would otherwise silently keep their default.
+func (c *EnvConfig) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteRedacted writes the configuration as YAML, the values of the fields
+// tagged secret replaced so that it can be shared.
+func (c *EnvConfig) WriteRedacted(w io.Writer) error {
+	cfg := *c
+	redactSecrets(reflect.ValueOf(&cfg).Elem())
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&cfg); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// redactSecrets replaces the non-empty secret strings of the struct v, and
+// the keys of its secret maps, recursing into nested structs.
+func redactSecrets(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field, value := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			redactSecrets(value)
+			continue
+		}
+		if field.Tag.Get("secret") != "true" {
+			continue
+		}
+		switch value.Kind() {
+		case reflect.String:
+			if value.Len() > 0 {
+				value.SetString(redacted)
+			}
+		case reflect.Map:
+			// Sorted so that the output is stable.
+			keys := value.MapKeys()
+			slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+			m := reflect.MakeMapWithSize(value.Type(), len(keys))
+			for i, key := range keys {
+				m.SetMapIndex(reflect.ValueOf(fmt.Sprintf("%s-%d", redacted, i+1)), value.MapIndex(key))
+			}
+			value.Set(m)
+		}
+	}
+}
+
+// UnmarshalYAML reads a sequence of networks or addresses.
+func (n *Networks) UnmarshalYAML(value *yaml.Node) error {
+	var values []string
+	if err := value.Decode(&values); err != nil {
+		return err
+	}
+	networks, err := parseNetworks(values)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+	*n = networks
+	return nil
+}
+
+// MarshalYAML writes the networks as strings.
+func (n Networks) MarshalYAML() (any, error) {
+	values := make([]string, len(n))
+	for i, prefix := range n {
+		values[i] = prefix.String()
+	}
+	return values, nil
+}
+
+// String returns the comma separated networks.
+func (n Networks) String() string {
+	var s string
+	for i, prefix := range n {
+		if i > 0 {
+			s += ","
+		}
+		s += prefix.String()
+	}
+	return s
+}
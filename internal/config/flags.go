@@ -0,0 +1,131 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	networksType        = reflect.TypeFor[Networks]()
+	identityTenantsType = reflect.TypeFor[IdentityTenants]()
+)
+
+// flagSet returns the command-line flags of the settings, named by their
+// configuration file keys joined with dots, e.g. --transport.pingPeriod, and
+// writing to c. path is set by the --config flag.
+func (c *EnvConfig) flagSet() (fs *flag.FlagSet, path *string) {
+	fs = flag.NewFlagSet("go-socket-server", flag.ContinueOnError)
+	path = fs.String("config", "", "YAML configuration `file`, read before the env variables; defaults to CONFIG_FILE")
+	addFlags(fs, "", reflect.ValueOf(c).Elem())
+	return fs, path
+}
+
+// addFlags adds a flag per field of the struct v, recursing into nested structs.
+func addFlags(fs *flag.FlagSet, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + flagName(field)
+		value := v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			addFlags(fs, name+".", value)
+			continue
+		}
+		// Lists of sections, e.g. the websocket routes, are only read from the file.
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			continue
+		}
+		fs.Var(fieldValue{value}, name, flagUsage(field.Type))
+	}
+}
+
+// flagName returns the yaml key of a field, which defaults to its lowercased
+// name like yaml does.
+func flagName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func flagUsage(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "a `duration`, e.g. 30s"
+	case t == networksType:
+		return "comma separated `networks` or addresses"
+	case t.Kind() == reflect.Slice:
+		return "comma separated `values`"
+	case t.Kind() == reflect.Map:
+		return "comma separated `key:value` pairs"
+	}
+	return ""
+}
+
+// fieldValue is the flag.Value of a configuration field.
+type fieldValue struct {
+	v reflect.Value
+}
+
+func (f fieldValue) String() string {
+	// The flag package calls String on zero values to print the defaults.
+	if !f.v.IsValid() {
+		return ""
+	}
+	switch v := f.v.Interface().(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case map[string]string:
+		// Maps only hold secrets, which the flag usage must not print.
+		return ""
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+func (f fieldValue) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
+
+func (f fieldValue) Set(s string) error {
+	var (
+		value any
+		err   error
+	)
+	switch t := f.v.Type(); {
+	case t == durationType:
+		value, err = time.ParseDuration(s)
+	case t == networksType:
+		value, err = parseNetworks(splitList(s))
+	case t == identityTenantsType:
+		value, err = parseIdentityTenants(splitList(s))
+	case t.Kind() == reflect.String:
+		value = s
+	case t.Kind() == reflect.Bool:
+		value, err = strconv.ParseBool(s)
+	case t.Kind() == reflect.Int:
+		value, err = strconv.Atoi(s)
+	case t.Kind() == reflect.Int64:
+		value, err = strconv.ParseInt(s, 10, 64)
+	case t.Kind() == reflect.Float64:
+		value, err = strconv.ParseFloat(s, 64)
+	case t.Kind() == reflect.Slice:
+		value = splitList(s)
+	case t.Kind() == reflect.Map:
+		value, err = parseTenantKeys(splitList(s))
+	default:
+		return fmt.Errorf("unsupported setting type %s", t)
+	}
+	if err != nil {
+		return err
+	}
+	f.v.Set(reflect.ValueOf(value))
+	return nil
+}
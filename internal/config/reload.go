@@ -0,0 +1,55 @@
+package config
+
+import "reflect"
+
+// Reloaded returns the configuration in force once next is reloaded over
+// current: the settings tagged reload are taken from next, the others are
+// kept until a restart. changed lists the keys of the settings applied, and
+// restart those which differ but need a restart, joined with dots like the
+// flags.
+//
+// The settings kept may not validate with the ones reloaded, e.g. cookie
+// tokens without the allowed origins of next. Such a reload is rejected as
+// a whole: current is returned with an error, and changed lists the keys
+// which were not applied.
+func Reloaded(current, next *EnvConfig) (cfg *EnvConfig, changed, restart []string, err error) {
+	cfg = new(EnvConfig)
+	*cfg = *current
+	r := reloader{}
+	r.walk("", reflect.ValueOf(cfg).Elem(), reflect.ValueOf(next).Elem(), false)
+	if err := cfg.validate(); err != nil {
+		return current, r.changed, r.restart, err
+	}
+	return cfg, r.changed, r.restart, nil
+}
+
+type reloader struct {
+	changed, restart []string
+}
+
+// walk compares the fields of the structs cfg and next, copying the changed
+// reloadable ones into cfg.
+func (r *reloader) walk(prefix string, cfg, next reflect.Value, reload bool) {
+	t := cfg.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + flagName(field)
+		reloadable := reload || field.Tag.Get("reload") == "true"
+		if field.Type.Kind() == reflect.Struct {
+			r.walk(name+".", cfg.Field(i), next.Field(i), reloadable)
+			continue
+		}
+		if reflect.DeepEqual(cfg.Field(i).Interface(), next.Field(i).Interface()) {
+			continue
+		}
+		if reloadable {
+			cfg.Field(i).Set(next.Field(i))
+			r.changed = append(r.changed, name)
+		} else {
+			r.restart = append(r.restart, name)
+		}
+	}
+}
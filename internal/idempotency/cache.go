@@ -0,0 +1,97 @@
+// Package idempotency detects repeated publishes carrying the same idempotency key.
+package idempotency
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache remembers idempotency keys for a time window. It holds at most size
+// keys, evicting the oldest first, so memory stays bounded under load.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	window  time.Duration
+	entries map[string]*list.Element
+
+	// order holds the entries oldest first.
+	order *list.List
+
+	now func() time.Time
+}
+
+type entry struct {
+	key    string
+	seenAt time.Time
+}
+
+// NewCache returns a cache of at most size keys remembered for window. A size
+// of zero or less disables the cache: every key is claimed.
+func NewCache(size int, window time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		window:  window,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Claim records the key and reports whether it was not seen within the window.
+// A false result means the publish is a duplicate and should be skipped.
+func (c *Cache) Claim(key string) bool {
+	if c.size <= 0 {
+		return true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.expire(now)
+
+	if _, ok := c.entries[key]; ok {
+		return false
+	}
+
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Front())
+	}
+	c.entries[key] = c.order.PushBack(&entry{key: key, seenAt: now})
+
+	return true
+}
+
+// Forget removes the key, so that a publish which failed can be retried.
+func (c *Cache) Forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len returns the number of remembered keys.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// expire removes the keys seen before the window. It must be called with c.mu held.
+func (c *Cache) expire(now time.Time) {
+	for el := c.order.Front(); el != nil; el = c.order.Front() {
+		if now.Sub(el.Value.(*entry).seenAt) < c.window { //nolint:forcetypeassert // order only holds entries.
+			return
+		}
+		c.remove(el)
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*entry).key) //nolint:forcetypeassert // order only holds entries.
+}
@@ -0,0 +1,54 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("should reject duplicate keys within the window", func(t *testing.T) {
+		c := NewCache(10, time.Minute)
+		assert.True(t, c.Claim("a"))
+		assert.False(t, c.Claim("a"))
+		assert.True(t, c.Claim("b"))
+	})
+
+	t.Run("should accept keys again after the window", func(t *testing.T) {
+		now := time.Now()
+		c := NewCache(10, time.Minute)
+		c.now = func() time.Time { return now }
+		assert.True(t, c.Claim("a"))
+
+		now = now.Add(time.Minute)
+		assert.True(t, c.Claim("a"))
+	})
+
+	t.Run("should evict the oldest key when full", func(t *testing.T) {
+		c := NewCache(2, time.Minute)
+		assert.True(t, c.Claim("a"))
+		assert.True(t, c.Claim("b"))
+		assert.True(t, c.Claim("c"))
+		assert.Equal(t, 2, c.Len())
+		assert.True(t, c.Claim("a"))
+		assert.False(t, c.Claim("c"))
+	})
+
+	t.Run("should accept forgotten keys", func(t *testing.T) {
+		c := NewCache(10, time.Minute)
+		assert.True(t, c.Claim("a"))
+		c.Forget("a")
+		assert.True(t, c.Claim("a"))
+	})
+
+	t.Run("should claim every key when disabled", func(t *testing.T) {
+		for _, size := range []int{0, -1} {
+			c := NewCache(size, time.Minute)
+			assert.True(t, c.Claim("a"))
+			assert.True(t, c.Claim("a"))
+			assert.Zero(t, c.Len())
+			c.Forget("a")
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// hiddenVars are the expvar variables Handler does not serve: cmdline holds
+// the command-line flags, secrets included.
+var hiddenVars = map[string]bool{"cmdline": true}
+
+// Handler serves the expvar variables as JSON like expvar.Handler, except for
+// the hidden ones.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{\n")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if hiddenVars[kv.Key] {
+				return
+			}
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+		})
+		fmt.Fprint(w, "\n}\n")
+	})
+}
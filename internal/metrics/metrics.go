@@ -0,0 +1,167 @@
+// Package metrics holds the server counters, published through expvar.
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Reasons a message was dropped instead of being written to a connection.
+const (
+	DropExpired        = "expired"
+	DropSlowConsumer   = "slow_consumer"
+	DropReplayOverflow = "replay_overflow"
+)
+
+// Reasons a connection was closed by the server.
+const (
+	CloseRateLimited  = "rate_limited"
+	CloseEvicted      = "evicted"
+	CloseTokenExpired = "token_expired"
+	CloseTokenRevoked = "token_revoked"
+	CloseAdmin        = "admin"
+	CloseSlowConsumer = "slow_consumer"
+)
+
+// Outcomes of a live connection re-authenticating.
+const (
+	ReauthSucceeded = "succeeded"
+	ReauthFailed    = "failed"
+)
+
+// Reasons a websocket upgrade was rejected, besides the connection limits.
+const (
+	RejectOrigin = "origin"
+)
+
+// Counters of the frames written to websockets, see Compression.
+const (
+	FramesCompressed       = "compressed_frames"
+	FramesUncompressed     = "uncompressed_frames"
+	CompressedPayloadBytes = "compressed_payload_bytes"
+	CompressedWireBytes    = "compressed_wire_bytes"
+)
+
+// Outcomes of a configuration reload.
+const (
+	ReloadSucceeded = "succeeded"
+	ReloadFailed    = "failed"
+)
+
+// DroppedMessages counts messages not delivered, per drop reason.
+var DroppedMessages = expvar.NewMap("dropped_messages")
+
+// DuplicatePublishes counts publishes skipped because their idempotency key was already seen.
+var DuplicatePublishes = expvar.NewInt("duplicate_publishes")
+
+// RateLimitedFrames counts inbound frames discarded for going over a rate limit, per limit.
+var RateLimitedFrames = expvar.NewMap("rate_limited_frames")
+
+// ClosedConnections counts connections closed by the server, per reason.
+var ClosedConnections = expvar.NewMap("closed_connections")
+
+// RejectedConnections counts websocket upgrades rejected, per connection limit or reason.
+var RejectedConnections = expvar.NewMap("rejected_connections")
+
+// RejectedSubscriptions counts room subscriptions rejected over a limit, per reason.
+var RejectedSubscriptions = expvar.NewMap("rejected_subscriptions")
+
+// Compression counts the frames written to websockets with and without
+// permessage-deflate. The ratio of CompressedWireBytes to
+// CompressedPayloadBytes is the compression ratio achieved.
+var Compression = expvar.NewMap("compression")
+
+// CompressionDuration is the time taken to write compressed frames, which
+// deflating them dominates, see Compression.
+var CompressionDuration = NewHistogram("compression_duration",
+	50*time.Microsecond, 100*time.Microsecond, 250*time.Microsecond,
+	500*time.Microsecond, time.Millisecond, 5*time.Millisecond)
+
+// Reauthentications counts reauth actions of live connections, per outcome.
+var Reauthentications = expvar.NewMap("reauthentications")
+
+// ConfigReloads counts configuration reloads, per outcome.
+var ConfigReloads = expvar.NewMap("config_reloads")
+
+// CertificateReloads counts reloads of the TLS certificate files, per outcome.
+var CertificateReloads = expvar.NewMap("certificate_reloads")
+
+// RestartPending is the number of changed settings a reload could not apply,
+// which wait for a restart.
+var RestartPending = expvar.NewInt("config_restart_pending")
+
+// Per tenant counters, see AddTenant.
+const (
+	TenantConnections           = "connections"
+	TenantRejectedConnections   = "rejected_connections"
+	TenantRejectedSubscriptions = "rejected_subscriptions"
+	TenantRateLimitedFrames     = "rate_limited_frames"
+	TenantDroppedMessages       = "dropped_messages"
+)
+
+// DefaultTenant labels the counters of clients without tenant.
+const DefaultTenant = "default"
+
+// Tenants holds the counters of each tenant.
+var Tenants = expvar.NewMap("tenants")
+
+var tenantsMu sync.Mutex
+
+// AddTenant adds delta to a counter of the tenant. TenantConnections is a
+// gauge of the open connections; the other counters only grow.
+func AddTenant(tenant, name string, delta int64) {
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+
+	counters, ok := Tenants.Get(tenant).(*expvar.Map)
+	if !ok {
+		tenantsMu.Lock()
+		if counters, ok = Tenants.Get(tenant).(*expvar.Map); !ok {
+			counters = new(expvar.Map)
+			Tenants.Set(tenant, counters)
+		}
+		tenantsMu.Unlock()
+	}
+	counters.Add(name, delta)
+}
+
+// Histogram counts durations by the upper bounds of its buckets. It is
+// published as a map of cumulative counts named after their bound, such as
+// "le_100us", along with the "count" and "sum_us" of every duration.
+type Histogram struct {
+	m      *expvar.Map
+	bounds []time.Duration
+	keys   []string
+}
+
+// NewHistogram publishes a histogram with the bucket bounds in increasing
+// order.
+func NewHistogram(name string, bounds ...time.Duration) *Histogram {
+	h := &Histogram{m: expvar.NewMap(name), bounds: bounds}
+	for _, bound := range bounds {
+		h.keys = append(h.keys, fmt.Sprintf("le_%dus", bound.Microseconds()))
+	}
+	return h
+}
+
+// Observe counts a duration.
+func (h *Histogram) Observe(d time.Duration) {
+	for i, bound := range h.bounds {
+		if d <= bound {
+			h.m.Add(h.keys[i], 1)
+		}
+	}
+	h.m.Add("count", 1)
+	h.m.Add("sum_us", d.Microseconds())
+}
+
+// Get returns a counter of the histogram, 0 when nothing was counted yet.
+func (h *Histogram) Get(key string) int64 {
+	if v, ok := h.m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
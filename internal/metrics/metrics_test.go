@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram("test_histogram", 100*time.Microsecond, time.Millisecond)
+	h.Observe(50 * time.Microsecond)
+	h.Observe(500 * time.Microsecond)
+	h.Observe(2 * time.Millisecond)
+
+	assert.Equal(t, int64(1), h.Get("le_100us"))
+	assert.Equal(t, int64(2), h.Get("le_1000us"))
+	assert.Equal(t, int64(3), h.Get("count"))
+	assert.Equal(t, int64(2550), h.Get("sum_us"))
+	assert.Zero(t, h.Get("le_5000us"))
+}
@@ -3,34 +3,135 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/yiannis54/go-socket-server/internal/config"
 )
 
 type contextKey string
 
-const UserIDContextKey contextKey = "userID"
+const (
+	UserIDContextKey      contextKey = "userID"
+	ClaimsContextKey      contextKey = "claims"
+	TenantContextKey      contextKey = "tenant"
+	SubprotocolContextKey contextKey = "subprotocol"
+)
+
+// Claims are the validated contents of an auth token.
+type Claims struct {
+	// Subject is the user ID the token was issued for.
+	Subject string
+
+	// ExpiresAt is when the token expires. Zero means it does not expire.
+	ExpiresAt time.Time
+
+	// Values holds every claim of the token by name.
+	Values map[string]any
+}
+
+// Tenant returns the tenant named by the given claim, or an empty string when
+// the claim is missing or not a string.
+func (c *Claims) Tenant(claim string) string {
+	tenant, _ := c.Values[claim].(string)
+	return tenant
+}
+
+// TokenValidator validates an auth token and returns its claims.
+type TokenValidator func(ctx context.Context, token string) (*Claims, error)
+
+// SubprotocolTokenPrefix prefixes a token sent as a Sec-WebSocket-Protocol entry.
+const SubprotocolTokenPrefix = "bearer."
 
 func AuthMiddleware(cfg *config.EnvConfig, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.URL.Query().Get(cfg.TokenKey)
-		if !validateToken(r, token) {
+		token, source := extractToken(r, cfg)
+		claims, err := ValidateToken(r.Context(), token)
+		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		// Optional: set userID when token validation provides it.
-		// r = r.WithContext(context.WithValue(r.Context(), UserIDContextKey, userID))
+		r = r.WithContext(context.WithValue(r.Context(), ClaimsContextKey, claims))
+		if claims.Subject != "" {
+			r = r.WithContext(context.WithValue(r.Context(), UserIDContextKey, claims.Subject))
+		}
+		// With tenancy enabled, every connection must belong to a tenant.
+		if cfg.Tenancy.Claim != "" {
+			tenant := claims.Tenant(cfg.Tenancy.Claim)
+			if tenant == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), TenantContextKey, tenant))
+		}
+		// The handshake must echo the subprotocol carrying the token, or
+		// browsers fail the connection.
+		if source == config.TokenSourceSubprotocol {
+			r = r.WithContext(context.WithValue(r.Context(), SubprotocolContextKey, SubprotocolTokenPrefix+token))
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func validateToken(r *http.Request, _ string) bool {
-	// Implement token authorization based on strategy used.
+// extractToken returns the first token found in the configured sources, and
+// the source it was found in.
+func extractToken(r *http.Request, cfg *config.EnvConfig) (string, string) {
+	for _, source := range cfg.Auth.TokenSources {
+		if token := tokenFromSource(r, cfg, source); token != "" {
+			return token, source
+		}
+	}
+	return "", ""
+}
+
+func tokenFromSource(r *http.Request, cfg *config.EnvConfig, source string) string {
+	switch source {
+	case config.TokenSourceQuery:
+		return r.URL.Query().Get(cfg.Auth.TokenKey)
+	case config.TokenSourceHeader:
+		scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(value)
+		}
+	case config.TokenSourceCookie:
+		if cookie, err := r.Cookie(cfg.Auth.TokenCookie); err == nil {
+			return cookie.Value
+		}
+	case config.TokenSourceSubprotocol:
+		for _, protocol := range websocketProtocols(r) {
+			if value, ok := strings.CutPrefix(protocol, SubprotocolTokenPrefix); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// websocketProtocols returns the subprotocols offered by the client.
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+// ValidateToken validates an auth token and returns its claims. It is used
+// both when upgrading and when a live connection re-authenticates.
+func ValidateToken(_ context.Context, _ string) (*Claims, error) {
+	// Implement token authorization based on strategy used, returning the
+	// subject and expiry of the token. Revoked tokens must be rejected here.
 	//
-	// if err := tokenService.ValidateAccessToken(token); err != nil {
-	// 	return false
+	// claims, err := tokenService.ValidateAccessToken(token)
+	// if err != nil {
+	// 	return nil, err
 	// }
-	return true
+	// return &Claims{Subject: claims.Subject, ExpiresAt: claims.ExpiresAt.Time, Values: claims.Map()}, nil
+	return &Claims{}, nil
 }
 
 // UserIDFromRequest returns the userID stored in the request context, if any.
@@ -42,3 +143,21 @@ func UserIDFromRequest(ctx context.Context) (string, bool) {
 	s, ok := v.(string)
 	return s, ok
 }
+
+// TenantFromRequest returns the tenant stored in the request context, if any.
+func TenantFromRequest(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(TenantContextKey).(string)
+	return s, ok
+}
+
+// ClaimsFromRequest returns the validated token claims stored in the request context, if any.
+func ClaimsFromRequest(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(ClaimsContextKey).(*Claims)
+	return c, ok
+}
+
+// SubprotocolFromRequest returns the subprotocol the token was sent in, if any.
+func SubprotocolFromRequest(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(SubprotocolContextKey).(string)
+	return s, ok
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yiannis54/go-socket-server/internal/config"
+)
+
+func TestExtractToken(t *testing.T) {
+	cfg := &config.EnvConfig{Auth: config.AuthConfig{
+		TokenKey:    "t",
+		TokenCookie: "token",
+		TokenSources: []string{
+			config.TokenSourceHeader,
+			config.TokenSourceSubprotocol,
+			config.TokenSourceCookie,
+			config.TokenSourceQuery,
+		},
+	}}
+
+	t.Run("should read each source", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/ws?t=from-query", nil)
+		token, source := extractToken(r, cfg)
+		assert.Equal(t, "from-query", token)
+		assert.Equal(t, config.TokenSourceQuery, source)
+
+		r.AddCookie(&http.Cookie{Name: "token", Value: "from-cookie"})
+		token, _ = extractToken(r, cfg)
+		assert.Equal(t, "from-cookie", token)
+
+		r.Header.Set("Sec-WebSocket-Protocol", "json, bearer.from-subprotocol")
+		token, source = extractToken(r, cfg)
+		assert.Equal(t, "from-subprotocol", token)
+		assert.Equal(t, config.TokenSourceSubprotocol, source)
+
+		r.Header.Set("Authorization", "Bearer from-header")
+		token, _ = extractToken(r, cfg)
+		assert.Equal(t, "from-header", token)
+	})
+
+	t.Run("should follow the configured priority", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/ws?t=from-query", nil)
+		r.Header.Set("Authorization", "Bearer from-header")
+
+		token, _ := extractToken(r, &config.EnvConfig{Auth: config.AuthConfig{TokenKey: "t", TokenSources: []string{config.TokenSourceQuery, config.TokenSourceHeader}}})
+		assert.Equal(t, "from-query", token)
+	})
+
+	t.Run("should ignore sources not configured", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/ws?t=from-query", nil)
+		token, _ := extractToken(r, &config.EnvConfig{Auth: config.AuthConfig{TokenKey: "t", TokenSources: []string{config.TokenSourceHeader}}})
+		assert.Empty(t, token)
+	})
+}
+
+func TestAuthMiddleware_Subprotocol(t *testing.T) {
+	cfg := &config.EnvConfig{Auth: config.AuthConfig{TokenSources: []string{config.TokenSourceSubprotocol}}}
+
+	var protocol string
+	handler := AuthMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protocol, _ = SubprotocolFromRequest(r.Context())
+	}))
+
+	r := httptest.NewRequest("GET", "/ws", nil)
+	r.Header.Set("Sec-WebSocket-Protocol", "bearer.abc")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "bearer.abc", protocol)
+}
+
+func TestAuthMiddleware_Tenant(t *testing.T) {
+	cfg := &config.EnvConfig{
+		Auth:    config.AuthConfig{TokenSources: []string{config.TokenSourceQuery}},
+		Tenancy: config.TenancyConfig{Claim: "tid"},
+	}
+	handler := AuthMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/ws", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	t.Run("should use remote address without trusted proxy", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.RemoteAddr = "203.0.113.7:5000"
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		assert.Equal(t, "203.0.113.7", ClientIP(r, proxies))
+	})
+
+	t.Run("should use right-most untrusted forwarded address", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.RemoteAddr = "10.0.0.2:5000"
+		r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 10.0.0.1")
+		assert.Equal(t, "203.0.113.7", ClientIP(r, proxies))
+	})
+
+	t.Run("should fall back to real ip header", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.RemoteAddr = "10.0.0.2:5000"
+		r.Header.Set("X-Real-IP", "203.0.113.7")
+		assert.Equal(t, "203.0.113.7", ClientIP(r, proxies))
+	})
+}
@@ -2,10 +2,26 @@ package notifications
 
 import (
 	"context"
+	"errors"
 
 	"github.com/yiannis54/go-socket-server/internal/sockets"
 )
 
+var errNoHub = errors.New("notifications: no hub to send to")
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context scoping the notifications sent with it to the tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the context, empty without tenancy.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
 // Client is the notification service consumed for sending messages to server.
 type Client struct {
 	hub *sockets.Hub
@@ -19,32 +35,77 @@ func NewClient(hub *sockets.Hub) *Client {
 }
 
 // Broadcast is called from clients for broadcasting a message.
-func (c *Client) Broadcast(ctx context.Context, message *sockets.Message) {
+func (c *Client) Broadcast(ctx context.Context, message *sockets.Message) error {
 	if c == nil || c.hub == nil || message == nil {
-		return
+		return errNoHub
 	}
 
 	withRoom := &sockets.MessageWithRoom{
 		Message:  *message,
 		RoomName: nil,
+		Tenant:   TenantFromContext(ctx),
 	}
-	c.hub.Broadcast <- withRoom
+	return c.hub.SendBroadcast(ctx, withRoom)
 }
 
 // PrivateNotify is called from clients to return a notification back to caller.
-func (c *Client) PrivateNotify(ctx context.Context, message *sockets.MessageWithUser) {
+func (c *Client) PrivateNotify(ctx context.Context, message *sockets.MessageWithUser) error {
 	if c == nil || c.hub == nil {
-		return
+		return errNoHub
 	}
 
-	c.hub.Private <- message
+	message.Tenant = TenantFromContext(ctx)
+	return c.hub.SendPrivate(ctx, message)
 }
 
 // NotifyRoom is called for broadcasting to a specific room.
-func (c *Client) NotifyRoom(ctx context.Context, message *sockets.MessageWithRoom) {
+func (c *Client) NotifyRoom(ctx context.Context, message *sockets.MessageWithRoom) error {
+	if c == nil || c.hub == nil {
+		return errNoHub
+	}
+
+	message.Tenant = TenantFromContext(ctx)
+	return c.hub.SendBroadcast(ctx, message)
+}
+
+// NotifyBatch is called for sending one message to several users and rooms.
+// It blocks until the hub has handled the batch and returns the delivery counts.
+func (c *Client) NotifyBatch(ctx context.Context, batch *sockets.MessageBatch) (*sockets.DeliveryReport, error) {
+	if c == nil || c.hub == nil || batch == nil {
+		return nil, errNoHub
+	}
+
+	batch.Tenant = TenantFromContext(ctx)
+	if err := c.hub.SendBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	return batch.Report(ctx)
+}
+
+// RevokeUser closes every connection of a user and returns their number.
+func (c *Client) RevokeUser(ctx context.Context, userID string) (int, error) {
+	if c == nil || c.hub == nil {
+		return 0, errNoHub
+	}
+
+	return c.hub.RevokeUser(ctx, TenantFromContext(ctx), userID)
+}
+
+// SubscribeUser puts every session of a user, current and future, in the given rooms.
+func (c *Client) SubscribeUser(ctx context.Context, userID string, rooms []string) (int, error) {
+	if c == nil || c.hub == nil {
+		return 0, errNoHub
+	}
+
+	return c.hub.SubscribeUser(ctx, TenantFromContext(ctx), userID, rooms)
+}
+
+// UnsubscribeUser takes every session of a user out of the given rooms.
+func (c *Client) UnsubscribeUser(ctx context.Context, userID string, rooms []string) (int, error) {
 	if c == nil || c.hub == nil {
-		return
+		return 0, errNoHub
 	}
 
-	c.hub.Broadcast <- message
+	return c.hub.UnsubscribeUser(ctx, TenantFromContext(ctx), userID, rooms)
 }
@@ -12,20 +12,35 @@ import (
 func TestEmptyClient(t *testing.T) {
 	c := Client{}
 
-	t.Run("should return directly with no client at broadcast", func(t *testing.T) {
-		assert.NotPanics(t, func() {
-			c.Broadcast(context.Background(), &sockets.Message{})
-		})
+	t.Run("should return error with no client at broadcast", func(t *testing.T) {
+		assert.Error(t, c.Broadcast(context.Background(), &sockets.Message{}))
 	})
 
-	t.Run("should return directly with no client at private notify", func(t *testing.T) {
-		assert.NotPanics(t, func() {
-			c.PrivateNotify(context.Background(), &sockets.MessageWithUser{})
-		})
+	t.Run("should return error with no client at private notify", func(t *testing.T) {
+		assert.Error(t, c.PrivateNotify(context.Background(), &sockets.MessageWithUser{}))
 	})
-	t.Run("should return directly with no client at event change", func(t *testing.T) {
-		assert.NotPanics(t, func() {
-			c.NotifyRoom(context.Background(), &sockets.MessageWithRoom{})
-		})
+	t.Run("should return error with no client at event change", func(t *testing.T) {
+		assert.Error(t, c.NotifyRoom(context.Background(), &sockets.MessageWithRoom{}))
 	})
+
+	t.Run("should return error with no client at batch", func(t *testing.T) {
+		_, err := c.NotifyBatch(context.Background(), sockets.NewMessageBatch(sockets.Message{}, nil, nil))
+		assert.Error(t, err)
+	})
+}
+
+func TestClientAfterClose(t *testing.T) {
+	hub := sockets.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hub.Run(ctx)
+	c := NewClient(hub)
+
+	assert.ErrorIs(t, c.Broadcast(context.Background(), &sockets.Message{}), sockets.ErrHubStopped)
+	assert.ErrorIs(t, c.PrivateNotify(context.Background(), &sockets.MessageWithUser{}), sockets.ErrHubStopped)
+	assert.ErrorIs(t, c.NotifyRoom(context.Background(), &sockets.MessageWithRoom{}), sockets.ErrHubStopped)
+	_, err := c.NotifyBatch(context.Background(), sockets.NewMessageBatch(sockets.Message{}, nil, nil))
+	assert.ErrorIs(t, err, sockets.ErrHubStopped)
+	_, err = c.RevokeUser(context.Background(), "user")
+	assert.ErrorIs(t, err, sockets.ErrHubStopped)
 }
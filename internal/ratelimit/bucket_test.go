@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket(t *testing.T) {
+	t.Run("should allow everything when disabled", func(t *testing.T) {
+		b := NewBucket(Limit{})
+		assert.Nil(t, b)
+		for range 100 {
+			assert.True(t, b.Allow())
+		}
+	})
+
+	t.Run("should allow burst then refill at rate", func(t *testing.T) {
+		b := NewBucket(Limit{Rate: 2, Burst: 3})
+		now := b.last
+		for range 3 {
+			assert.True(t, b.AllowAt(now))
+		}
+		assert.False(t, b.AllowAt(now))
+
+		now = now.Add(500 * time.Millisecond)
+		assert.True(t, b.AllowAt(now))
+		assert.False(t, b.AllowAt(now))
+
+		now = now.Add(time.Hour)
+		for range 3 {
+			assert.True(t, b.AllowAt(now))
+		}
+		assert.False(t, b.AllowAt(now))
+	})
+}
+
+func TestKeyed(t *testing.T) {
+	k := NewKeyed(Limit{Rate: 1, Burst: 1})
+	assert.True(t, k.Allow("a"))
+	assert.False(t, k.Allow("a"))
+	assert.True(t, k.Allow("b"))
+
+	k.sweep(time.Now().Add(time.Hour))
+	assert.Empty(t, k.buckets)
+
+	var disabled *Keyed
+	assert.True(t, disabled.Allow("a"))
+}
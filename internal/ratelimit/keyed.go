@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are removed from a Keyed limiter.
+const sweepInterval = time.Minute
+
+// Keyed holds one bucket per key, e.g. per user ID. A nil Keyed allows everything.
+type Keyed struct {
+	mu        sync.Mutex
+	limit     Limit
+	buckets   map[string]*Bucket
+	lastSweep time.Time
+}
+
+// NewKeyed returns a keyed limiter, or nil when the limit is disabled.
+func NewKeyed(limit Limit) *Keyed {
+	if !limit.Enabled() {
+		return nil
+	}
+	return &Keyed{
+		limit:     limit,
+		buckets:   make(map[string]*Bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket of the key and reports whether one was available.
+func (k *Keyed) Allow(key string) bool {
+	if k == nil {
+		return true
+	}
+
+	now := time.Now()
+
+	k.mu.Lock()
+	if now.Sub(k.lastSweep) >= sweepInterval {
+		k.sweep(now)
+	}
+	bucket, ok := k.buckets[key]
+	if !ok {
+		bucket = NewBucket(k.limit)
+		k.buckets[key] = bucket
+	}
+	k.mu.Unlock()
+
+	return bucket.AllowAt(now)
+}
+
+// sweep removes full buckets, which behave the same as new ones, so that
+// keys no longer in use do not accumulate. It must be called with k.mu held.
+func (k *Keyed) sweep(now time.Time) {
+	for key, bucket := range k.buckets {
+		if bucket.full(now) {
+			delete(k.buckets, key)
+		}
+	}
+	k.lastSweep = now
+}
@@ -0,0 +1,62 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+type item struct {
+	notification *pb.ScheduledNotification
+	deliverAt    time.Time
+
+	// index of the item in the queue, maintained by the heap methods. It is
+	// -1 while the item is being released.
+	index int
+}
+
+// releasing reports whether the item was taken from the queue to be released.
+func (it *item) releasing() bool { return it.index < 0 }
+
+// queue is a min-heap of items ordered by delivery time.
+type queue []*item
+
+func (q queue) Len() int { return len(q) }
+
+func (q queue) Less(i, j int) bool { return q[i].deliverAt.Before(q[j].deliverAt) }
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	it := x.(*item) //nolint:forcetypeassert // queue only holds items.
+	it.index = len(*q)
+	*q = append(*q, it)
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = nil
+	it.index = -1
+	*q = old[:n-1]
+	return it
+}
+
+// notifications returns the queued notifications ordered by delivery time.
+func (q queue) notifications() []*pb.ScheduledNotification {
+	items := make([]*item, len(q))
+	copy(items, q)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].deliverAt.Before(items[j].deliverAt) })
+
+	notifications := make([]*pb.ScheduledNotification, len(items))
+	for i, it := range items {
+		notifications[i] = it.notification
+	}
+	return notifications
+}
@@ -0,0 +1,253 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+var (
+	// ErrNotFound is returned when cancelling an unknown scheduled notification.
+	ErrNotFound = errors.New("scheduler: scheduled notification not found")
+
+	// ErrDuplicateID is returned when scheduling with an ID that is already pending.
+	ErrDuplicateID = errors.New("scheduler: scheduled notification id already exists")
+)
+
+// defaultRetryDelay is how long a notification whose release failed waits
+// before the next attempt.
+const defaultRetryDelay = 5 * time.Second
+
+// ReleaseFunc publishes a scheduled notification once it is due. A
+// notification whose release fails is released again later.
+type ReleaseFunc func(ctx context.Context, n *pb.ScheduledNotification) error
+
+// key identifies a pending notification. IDs can be chosen by the tenants, so
+// they are only unique within a tenant.
+type key struct {
+	tenant, id string
+}
+
+func keyOf(n *pb.ScheduledNotification) key {
+	return key{tenant: n.GetTenant(), id: n.GetId()}
+}
+
+// Scheduler holds notifications until their delivery time and releases them.
+type Scheduler struct {
+	mu      sync.Mutex
+	pending map[key]*item
+	queue   queue
+
+	// wake signals Run that the earliest delivery time may have changed.
+	wake chan struct{}
+
+	store      Store
+	release    ReleaseFunc
+	retryDelay time.Duration
+}
+
+// New returns a scheduler releasing notifications through the given function.
+// Pending notifications are loaded from the store, which may be nil.
+func New(store Store, release ReleaseFunc) (*Scheduler, error) {
+	s := &Scheduler{
+		pending:    make(map[key]*item),
+		wake:       make(chan struct{}, 1),
+		store:      store,
+		release:    release,
+		retryDelay: defaultRetryDelay,
+	}
+
+	if store == nil {
+		return s, nil
+	}
+
+	notifications, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("scheduler: load pending notifications: %w", err)
+	}
+	for _, n := range notifications {
+		s.push(n)
+	}
+
+	return s, nil
+}
+
+// Schedule holds the notification until its delivery time and returns its ID.
+// An ID is generated when the notification has none. IDs must be unique
+// within the tenant of the notification.
+func (s *Scheduler) Schedule(n *pb.ScheduledNotification) (string, error) {
+	if n.GetId() == "" {
+		n.Id = uuid.NewString()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pending[keyOf(n)]; ok {
+		return "", ErrDuplicateID
+	}
+	s.push(n)
+	s.persist()
+	s.notify()
+
+	return n.Id, nil
+}
+
+// Cancel removes a pending notification of the tenant. Notifications being
+// released can no longer be cancelled.
+func (s *Scheduler) Cancel(tenant, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := key{tenant: tenant, id: id}
+	it, ok := s.pending[k]
+	if !ok || it.releasing() {
+		return ErrNotFound
+	}
+	heap.Remove(&s.queue, it.index)
+	delete(s.pending, k)
+	s.persist()
+	s.notify()
+
+	return nil
+}
+
+// Get returns a pending notification of the tenant.
+func (s *Scheduler) Get(tenant, id string) (*pb.ScheduledNotification, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	it, ok := s.pending[key{tenant: tenant, id: id}]
+	if !ok {
+		return nil, false
+	}
+	return it.notification, true
+}
+
+// List returns the pending notifications ordered by delivery time.
+func (s *Scheduler) List() []*pb.ScheduledNotification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queue.notifications()
+}
+
+// Run releases notifications as they become due.
+// It blocks until the given context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		for _, it := range s.takeDue(time.Now()) {
+			err := ctx.Err()
+			if err == nil {
+				err = s.release(ctx, it.notification)
+			}
+			s.released(it, err)
+		}
+
+		var due <-chan time.Time
+		if next, ok := s.next(); ok {
+			timer.Reset(time.Until(next))
+			due = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-due:
+		}
+	}
+}
+
+// takeDue removes the due items from the queue. They stay pending, and
+// persisted, until released.
+func (s *Scheduler) takeDue(now time.Time) []*item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []*item
+	for s.queue.Len() > 0 && !s.queue[0].deliverAt.After(now) {
+		due = append(due, heap.Pop(&s.queue).(*item)) //nolint:forcetypeassert // queue only holds items.
+	}
+	return due
+}
+
+// released removes a released item, or queues it again after the retry delay
+// when its release failed.
+func (s *Scheduler) released(it *item, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		slog.Warn("scheduler: release failed", "id", it.notification.Id, "retry", s.retryDelay, "err", err)
+		it.deliverAt = time.Now().Add(s.retryDelay)
+		heap.Push(&s.queue, it)
+		return
+	}
+	delete(s.pending, keyOf(it.notification))
+	s.persist()
+}
+
+func (s *Scheduler) next() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queue.Len() == 0 {
+		return time.Time{}, false
+	}
+	return s.queue[0].deliverAt, true
+}
+
+func (s *Scheduler) push(n *pb.ScheduledNotification) {
+	it := &item{
+		notification: n,
+		deliverAt:    n.GetDeliverAt().AsTime(),
+	}
+	heap.Push(&s.queue, it)
+	s.pending[keyOf(n)] = it
+}
+
+// persist saves the pending notifications, those being released first. It
+// must be called with s.mu held.
+func (s *Scheduler) persist() {
+	if s.store == nil {
+		return
+	}
+	var notifications []*pb.ScheduledNotification
+	for _, it := range s.pending {
+		if it.releasing() {
+			notifications = append(notifications, it.notification)
+		}
+	}
+	if err := s.store.Save(append(notifications, s.queue.notifications()...)); err != nil {
+		slog.Error("scheduler: could not persist pending notifications", "err", err)
+	}
+}
+
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// NewNotification returns a notification of the publish to be delivered at the given time.
+func NewNotification(id string, deliverAt time.Time, publish *pb.PublishRequest) *pb.ScheduledNotification {
+	return &pb.ScheduledNotification{
+		Id:        id,
+		DeliverAt: timestamppb.New(deliverAt),
+		Publish:   publish,
+	}
+}
@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+func broadcast(entityID string) *pb.PublishRequest {
+	return &pb.PublishRequest{
+		Target: &pb.PublishRequest_Broadcast{Broadcast: &pb.Message{EntityId: entityID}},
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	released := make(chan *pb.PublishRequest, 10)
+	s, err := New(nil, func(_ context.Context, n *pb.ScheduledNotification) error {
+		released <- n.GetPublish()
+		return nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	t.Run("should release notifications in delivery order", func(t *testing.T) {
+		now := time.Now()
+		_, err := s.Schedule(NewNotification("", now.Add(100*time.Millisecond), broadcast("second")))
+		require.NoError(t, err)
+		_, err = s.Schedule(NewNotification("", now.Add(50*time.Millisecond), broadcast("first")))
+		require.NoError(t, err)
+
+		for _, expected := range []string{"first", "second"} {
+			select {
+			case publish := <-released:
+				assert.Equal(t, expected, publish.GetBroadcast().GetEntityId())
+			case <-time.After(time.Second):
+				t.Fatal("scheduled notification was not released")
+			}
+		}
+		assert.Empty(t, s.List())
+	})
+
+	t.Run("should not release cancelled notifications", func(t *testing.T) {
+		id, err := s.Schedule(NewNotification("reminder", time.Now().Add(50*time.Millisecond), broadcast("cancelled")))
+		require.NoError(t, err)
+		assert.Equal(t, "reminder", id)
+		require.Len(t, s.List(), 1)
+		n, ok := s.Get("", id)
+		require.True(t, ok)
+		assert.Equal(t, "cancelled", n.GetPublish().GetBroadcast().GetEntityId())
+
+		require.NoError(t, s.Cancel("", id))
+		assert.ErrorIs(t, s.Cancel("", id), ErrNotFound)
+		_, ok = s.Get("", id)
+		assert.False(t, ok)
+
+		select {
+		case <-released:
+			t.Fatal("cancelled notification was released")
+		case <-time.After(150 * time.Millisecond):
+		}
+	})
+
+	t.Run("should reject duplicate ids", func(t *testing.T) {
+		_, err := s.Schedule(NewNotification("dup", time.Now().Add(time.Hour), broadcast("a")))
+		require.NoError(t, err)
+		_, err = s.Schedule(NewNotification("dup", time.Now().Add(time.Hour), broadcast("b")))
+		assert.ErrorIs(t, err, ErrDuplicateID)
+		require.NoError(t, s.Cancel("", "dup"))
+	})
+
+	t.Run("should scope ids to the tenant", func(t *testing.T) {
+		acme := NewNotification("report", time.Now().Add(time.Hour), broadcast("acme"))
+		acme.Tenant = "acme"
+		globex := NewNotification("report", time.Now().Add(time.Hour), broadcast("globex"))
+		globex.Tenant = "globex"
+		_, err := s.Schedule(acme)
+		require.NoError(t, err)
+		_, err = s.Schedule(globex)
+		require.NoError(t, err)
+
+		_, ok := s.Get("", "report")
+		assert.False(t, ok)
+		assert.ErrorIs(t, s.Cancel("", "report"), ErrNotFound)
+		require.NoError(t, s.Cancel("acme", "report"))
+		n, ok := s.Get("globex", "report")
+		require.True(t, ok)
+		assert.Equal(t, "globex", n.GetPublish().GetBroadcast().GetEntityId())
+		require.NoError(t, s.Cancel("globex", "report"))
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "scheduled.pb"))
+
+	t.Run("should load nothing without a file", func(t *testing.T) {
+		notifications, err := store.Load()
+		require.NoError(t, err)
+		assert.Empty(t, notifications)
+	})
+
+	t.Run("should restore pending notifications", func(t *testing.T) {
+		s, err := New(store, func(context.Context, *pb.ScheduledNotification) error { return nil })
+		require.NoError(t, err)
+		_, err = s.Schedule(NewNotification("later", time.Now().Add(time.Hour), broadcast("later")))
+		require.NoError(t, err)
+
+		restored, err := New(store, func(context.Context, *pb.ScheduledNotification) error { return nil })
+		require.NoError(t, err)
+		list := restored.List()
+		require.Len(t, list, 1)
+		assert.Equal(t, "later", list[0].GetId())
+		assert.Equal(t, "later", list[0].GetPublish().GetBroadcast().GetEntityId())
+		require.NoError(t, restored.Cancel("", "later"))
+	})
+
+	t.Run("should keep notifications whose release failed", func(t *testing.T) {
+		var attempts atomic.Int32
+		failed := make(chan struct{})
+		s, err := New(store, func(context.Context, *pb.ScheduledNotification) error {
+			if attempts.Add(1) == 1 {
+				close(failed)
+				return errors.New("hub closed")
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		s.retryDelay = 100 * time.Millisecond
+		_, err = s.Schedule(NewNotification("due", time.Now(), broadcast("due")))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go s.Run(ctx)
+
+		<-failed
+		restored, err := New(store, func(context.Context, *pb.ScheduledNotification) error { return nil })
+		require.NoError(t, err)
+		require.Len(t, restored.List(), 1)
+		assert.Equal(t, "due", restored.List()[0].GetId())
+
+		require.Eventually(t, func() bool {
+			_, ok := s.Get("", "due")
+			return !ok
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int32(2), attempts.Load())
+		restored, err = New(store, func(context.Context, *pb.ScheduledNotification) error { return nil })
+		require.NoError(t, err)
+		assert.Empty(t, restored.List())
+	})
+
+	t.Run("should keep notifications not released before shutdown", func(t *testing.T) {
+		s, err := New(store, func(context.Context, *pb.ScheduledNotification) error { return nil })
+		require.NoError(t, err)
+		_, err = s.Schedule(NewNotification("shutdown", time.Now(), broadcast("shutdown")))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Run(ctx)
+
+		_, ok := s.Get("", "shutdown")
+		assert.True(t, ok)
+		restored, err := New(store, func(context.Context, *pb.ScheduledNotification) error { return nil })
+		require.NoError(t, err)
+		require.Len(t, restored.List(), 1)
+	})
+}
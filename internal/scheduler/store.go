@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// Store persists pending notifications so that they survive restarts.
+type Store interface {
+	Load() ([]*pb.ScheduledNotification, error)
+	Save(notifications []*pb.ScheduledNotification) error
+}
+
+// FileStore is a Store keeping pending notifications in a local file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a store persisting to the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the pending notifications. A missing file holds no notifications.
+func (f *FileStore) Load() ([]*pb.ScheduledNotification, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := &pb.ScheduledList{}
+	if err := proto.Unmarshal(data, list); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", f.path, err)
+	}
+	return list.Notifications, nil
+}
+
+// Save replaces the file contents with the given notifications. The file is
+// written to a temporary file first and renamed, so a crash never leaves it
+// half written.
+func (f *FileStore) Save(notifications []*pb.ScheduledNotification) error {
+	data, err := proto.Marshal(&pb.ScheduledList{Notifications: notifications})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
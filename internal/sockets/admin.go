@@ -0,0 +1,241 @@
+package sockets
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+)
+
+var (
+	// ErrConnectionNotFound is returned for an unknown connection ID.
+	ErrConnectionNotFound = errors.New("sockets: connection not found")
+
+	// ErrRoomNotFound is returned for a room without members.
+	ErrRoomNotFound = errors.New("sockets: room not found")
+)
+
+// Reasons a client is removed from a room by the server.
+const (
+	removedKicked = "kicked"
+	removedClosed = "closed"
+)
+
+// ConnectionInfo is a snapshot of a connection, for inspecting the hub.
+type ConnectionInfo struct {
+	ID          string
+	Tenant      string
+	UserID      string
+	IP          string
+	ConnectedAt time.Time
+	Rooms       []string
+
+	// QueueDepth is the number of messages waiting in the send buffer.
+	QueueDepth int
+}
+
+// RoomInfo is a snapshot of a room and its member connections.
+type RoomInfo struct {
+	Name    string
+	Members []ConnectionInfo
+}
+
+// RoomSummary is the name and member count of a room.
+type RoomSummary struct {
+	Name    string
+	Members int
+}
+
+// ConnectionPage selects a page of the connections of a tenant. Connections
+// are ordered by ID and the page starts after the After ID.
+type ConnectionPage struct {
+	Tenant string
+	UserID string
+	After  string
+	Limit  int
+}
+
+// Connections returns a page of connections and the ID to continue after, or
+// an empty ID on the last page.
+func (h *Hub) Connections(ctx context.Context, page ConnectionPage) ([]ConnectionInfo, string, error) {
+	var (
+		infos []ConnectionInfo
+		next  string
+	)
+	err := h.call(ctx, func() {
+		ns := h.lookupNamespace(page.Tenant)
+		clients := slices.Collect(maps.Keys(ns.clients))
+		if page.UserID != "" {
+			clients = slices.Collect(maps.Keys(ns.users[page.UserID]))
+		}
+		slices.SortFunc(clients, func(a, b Subscriber) int {
+			return strings.Compare(a.ConnectionID(), b.ConnectionID())
+		})
+
+		for _, client := range clients {
+			if client.ConnectionID() <= page.After {
+				continue
+			}
+			if page.Limit > 0 && len(infos) == page.Limit {
+				next = infos[len(infos)-1].ID
+				break
+			}
+			infos = append(infos, h.clients[client].info())
+		}
+	})
+	return infos, next, err
+}
+
+// Rooms returns the rooms of the tenant with at least one member, ordered by name.
+func (h *Hub) Rooms(ctx context.Context, tenant string) ([]RoomSummary, error) {
+	var rooms []RoomSummary
+	err := h.call(ctx, func() {
+		ns := h.lookupNamespace(tenant)
+		for _, name := range slices.Sorted(maps.Keys(ns.rooms)) {
+			rooms = append(rooms, RoomSummary{Name: name, Members: len(ns.rooms[name])})
+		}
+	})
+	return rooms, err
+}
+
+// Room returns the members of a room of the tenant.
+func (h *Hub) Room(ctx context.Context, tenant, name string) (*RoomInfo, error) {
+	var room *RoomInfo
+	err := h.call(ctx, func() {
+		members, ok := h.lookupNamespace(tenant).rooms[name]
+		if !ok {
+			return
+		}
+		room = &RoomInfo{Name: name}
+		for client := range members {
+			room.Members = append(room.Members, h.clients[client].info())
+		}
+		slices.SortFunc(room.Members, func(a, b ConnectionInfo) int {
+			return strings.Compare(a.ID, b.ID)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	return room, nil
+}
+
+// DisconnectConnection closes a single connection.
+func (h *Hub) DisconnectConnection(ctx context.Context, connID string) error {
+	found := false
+	err := h.call(ctx, func() {
+		if client := h.connection(connID); client != nil {
+			found = true
+			h.closeSubscriber(client, closeRequest{code: closeDisconnected, text: "disconnected by server"}, metrics.CloseAdmin)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrConnectionNotFound
+	}
+	return nil
+}
+
+// DisconnectUser closes every connection of a user of the tenant and returns
+// their number. Unlike RevokeUser, the user is free to connect again.
+func (h *Hub) DisconnectUser(ctx context.Context, tenant, userID string) (int, error) {
+	var n int
+	err := h.call(ctx, func() {
+		n = h.disconnectUser(tenant, userID, closeRequest{code: closeDisconnected, text: "disconnected by server"}, metrics.CloseAdmin)
+	})
+	return n, err
+}
+
+// KickFromRoom removes a connection, or every connection of a user when
+// connID is empty, from a room of the tenant and returns the number of
+// connections removed.
+func (h *Hub) KickFromRoom(ctx context.Context, tenant, room, userID, connID string) (int, error) {
+	var (
+		n     int
+		found bool
+	)
+	err := h.call(ctx, func() {
+		members, ok := h.lookupNamespace(tenant).rooms[room]
+		if !ok {
+			return
+		}
+		found = true
+		for _, client := range slices.Collect(maps.Keys(members)) {
+			if (connID != "" && client.ConnectionID() != connID) || (connID == "" && h.clients[client].userID != userID) {
+				continue
+			}
+			h.removeFromRoom(room, client, removedKicked)
+			n++
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, ErrRoomNotFound
+	}
+	return n, nil
+}
+
+// CloseRoom removes every member of a room of the tenant and returns their number.
+func (h *Hub) CloseRoom(ctx context.Context, tenant, room string) (int, error) {
+	n := -1
+	err := h.call(ctx, func() {
+		members, ok := h.lookupNamespace(tenant).rooms[room]
+		if !ok {
+			return
+		}
+		n = len(members)
+		for _, client := range slices.Collect(maps.Keys(members)) {
+			h.removeFromRoom(room, client, removedClosed)
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, ErrRoomNotFound
+	}
+	return n, nil
+}
+
+// removeFromRoom makes a subscriber leave a room and tells it why.
+func (h *Hub) removeFromRoom(room string, client Subscriber, reason string) {
+	h.leaveRoom(room, client)
+	h.handleDirectMessage(&directMessage{
+		client: client,
+		payload: Event{
+			Event: eventRemovedFromRoom,
+			Data:  removedFromRoomData{Room: room, Reason: reason},
+		},
+	})
+}
+
+// connection returns the subscriber of a connection ID. It must be called from the hub loop.
+func (h *Hub) connection(connID string) Subscriber {
+	return h.connections[connID]
+}
+
+// info returns a snapshot of the subscriber. It must be called from the hub loop.
+func (m *member) info() ConnectionInfo {
+	info := ConnectionInfo{
+		ID:          m.sub.ConnectionID(),
+		Tenant:      m.tenant,
+		UserID:      m.userID,
+		ConnectedAt: m.registeredAt,
+		Rooms:       slices.Sorted(maps.Keys(m.rooms)),
+	}
+	if d, ok := m.sub.(describer); ok {
+		d.describe(&info)
+	}
+	return info
+}
@@ -0,0 +1,154 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestHub_Admin(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user")
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, userID))
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+
+	dial := func(t *testing.T, userID string, rooms ...string) *websocket.Conn {
+		t.Helper()
+		ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"?user="+userID, nil)
+		require.NoError(t, err)
+		res.Body.Close()
+		for _, room := range rooms {
+			require.NoError(t, ws.WriteJSON(IncomingSubscription{Action: subscribeAction, Room: room}))
+		}
+		return ws
+	}
+	readEvent := func(t *testing.T, ws *websocket.Conn) Event {
+		t.Helper()
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, incoming, err := ws.ReadMessage()
+		require.NoError(t, err)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(incoming, &event))
+		return event
+	}
+
+	alice := dial(t, "alice", "lobby", "orders")
+	defer alice.Close()
+	bob := dial(t, "bob", "lobby")
+	defer bob.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("should page through connections", func(t *testing.T) {
+		first, next, err := hub.Connections(ctx, ConnectionPage{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, first, 1)
+		require.NotEmpty(t, next)
+
+		second, next, err := hub.Connections(ctx, ConnectionPage{After: next, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, second, 1)
+		assert.Empty(t, next)
+		assert.NotEqual(t, first[0].ID, second[0].ID)
+
+		filtered, _, err := hub.Connections(ctx, ConnectionPage{UserID: "alice"})
+		require.NoError(t, err)
+		require.Len(t, filtered, 1)
+		assert.Equal(t, []string{"lobby", "orders"}, filtered[0].Rooms)
+	})
+
+	t.Run("should list rooms with member counts", func(t *testing.T) {
+		rooms, err := hub.Rooms(ctx, "")
+		require.NoError(t, err)
+		assert.Equal(t, []RoomSummary{{Name: "lobby", Members: 2}, {Name: "orders", Members: 1}}, rooms)
+
+		room, err := hub.Room(ctx, "", "orders")
+		require.NoError(t, err)
+		require.Len(t, room.Members, 1)
+		assert.Equal(t, "alice", room.Members[0].UserID)
+
+		_, err = hub.Room(ctx, "", "missing")
+		assert.ErrorIs(t, err, ErrRoomNotFound)
+	})
+
+	t.Run("should kick a user from a room", func(t *testing.T) {
+		n, err := hub.KickFromRoom(ctx, "", "lobby", "bob", "")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, eventRemovedFromRoom, readEvent(t, bob).Event)
+	})
+
+	t.Run("should close a room", func(t *testing.T) {
+		n, err := hub.CloseRoom(ctx, "", "orders")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, eventRemovedFromRoom, readEvent(t, alice).Event)
+
+		_, err = hub.CloseRoom(ctx, "", "orders")
+		assert.ErrorIs(t, err, ErrRoomNotFound)
+	})
+
+	t.Run("should disconnect a connection", func(t *testing.T) {
+		connections, _, err := hub.Connections(ctx, ConnectionPage{UserID: "bob"})
+		require.NoError(t, err)
+		require.Len(t, connections, 1)
+
+		require.NoError(t, hub.DisconnectConnection(ctx, connections[0].ID))
+		require.NoError(t, bob.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, _, err = bob.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, closeDisconnected), err)
+
+		assert.ErrorIs(t, hub.DisconnectConnection(ctx, "missing"), ErrConnectionNotFound)
+	})
+
+	t.Run("should disconnect a user", func(t *testing.T) {
+		n, err := hub.DisconnectUser(ctx, "", "alice")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		require.NoError(t, alice.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, _, err = alice.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, closeDisconnected), err)
+	})
+}
+
+func TestHub_connection(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer cancel()
+
+	lookup := func(t *testing.T, connID string) Subscriber {
+		t.Helper()
+		var sub Subscriber
+		require.NoError(t, hub.call(ctx, func() { sub = hub.connection(connID) }))
+		return sub
+	}
+
+	sub := newFakeSubscriber("conn-1", "alice", 1)
+	require.NoError(t, hub.Register(ctx, sub))
+	assert.Equal(t, Subscriber(sub), lookup(t, "conn-1"))
+	assert.Nil(t, lookup(t, "conn-2"))
+
+	require.NoError(t, hub.Unregister(ctx, sub))
+	assert.Nil(t, lookup(t, "conn-1"))
+}
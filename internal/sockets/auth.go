@@ -0,0 +1,124 @@
+package sockets
+
+import (
+	"context"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+// defaultExpiryWarning is how long before the token expiry a client is warned.
+const defaultExpiryWarning = time.Minute
+
+// AuthOptions configures the enforcement of token expiry on live connections.
+type AuthOptions struct {
+	// Validator validates the tokens sent by clients re-authenticating.
+	Validator middleware.TokenValidator
+
+	// ExpiryWarning is how long before its token expires a client receives
+	// a token_expiring event.
+	ExpiryWarning time.Duration
+
+	// TenantClaim names the claim holding the tenant, if tenancy is enabled.
+	TenantClaim string
+}
+
+// reauthenticate validates a fresh token sent by the client and extends the
+// connection deadline to its expiry. The token must be for the same user and tenant.
+func (c *Client) reauthenticate(token string) {
+	claims, err := c.hub.auth.Validator(context.Background(), token)
+	if err != nil || !c.sameIdentity(claims) {
+		metrics.Reauthentications.Add(metrics.ReauthFailed, 1)
+		enqueue(c.hub, c.hub.direct, &directMessage{client: c, payload: Event{Event: eventReauthFailed}})
+		return
+	}
+
+	metrics.Reauthentications.Add(metrics.ReauthSucceeded, 1)
+	c.extendDeadline(claims.ExpiresAt)
+
+	enqueue(c.hub, c.hub.direct, &directMessage{
+		client:  c,
+		payload: Event{Event: eventReauthenticated, Data: tokenExpiryData{ExpiresAt: claims.ExpiresAt}},
+	})
+}
+
+// extendDeadline moves the deadline of the connection to a fresh token expiry.
+func (c *Client) extendDeadline(expiresAt time.Time) {
+	// Only the latest deadline matters to the write pump.
+	select {
+	case <-c.reauth:
+	default:
+	}
+	c.reauth <- expiresAt
+}
+
+// sameIdentity reports whether the claims are for the user and tenant of the client.
+func (c *Client) sameIdentity(claims *middleware.Claims) bool {
+	if claims.Subject != c.ID {
+		return false
+	}
+	return c.hub.auth.TenantClaim == "" || claims.Tenant(c.hub.auth.TenantClaim) == c.Tenant
+}
+
+// tokenDeadline tracks when the write pump warns about the token expiry and
+// when it closes the connection. It is only used from the write pump.
+type tokenDeadline struct {
+	expiresAt time.Time
+	warning   time.Duration
+	warned    bool
+	timer     *time.Timer
+}
+
+func newTokenDeadline(expiresAt time.Time, warning time.Duration) *tokenDeadline {
+	d := &tokenDeadline{
+		warning: warning,
+		timer:   time.NewTimer(0),
+	}
+	d.timer.Stop()
+	d.reset(expiresAt)
+	return d
+}
+
+// reset moves the deadline to a new expiry. A zero expiry never expires.
+func (d *tokenDeadline) reset(expiresAt time.Time) {
+	d.expiresAt = expiresAt
+	d.warned = false
+	d.schedule()
+}
+
+// C returns the channel signalling the next warning or expiry, or nil when
+// the token does not expire.
+func (d *tokenDeadline) C() <-chan time.Time {
+	if d.expiresAt.IsZero() {
+		return nil
+	}
+	return d.timer.C
+}
+
+// fire handles the timer and reports whether the token expired. Otherwise the
+// warning is due and the timer is moved to the expiry.
+func (d *tokenDeadline) fire() bool {
+	if d.warned || !time.Now().Before(d.expiresAt) {
+		return true
+	}
+	d.warned = true
+	d.schedule()
+	return false
+}
+
+func (d *tokenDeadline) schedule() {
+	d.timer.Stop()
+	if d.expiresAt.IsZero() {
+		return
+	}
+	next := d.expiresAt
+	if !d.warned {
+		next = next.Add(-d.warning)
+	}
+	d.timer.Reset(time.Until(next))
+}
+
+func (d *tokenDeadline) stop() {
+	d.timer.Stop()
+}
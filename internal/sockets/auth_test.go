@@ -0,0 +1,147 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestClient_TokenExpiry(t *testing.T) {
+	const userID = "user-1"
+	validator := func(_ context.Context, token string) (*middleware.Claims, error) {
+		if token != "fresh" {
+			return nil, errors.New("invalid token")
+		}
+		return &middleware.Claims{Subject: userID, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	hub := NewHub(WithAuth(AuthOptions{Validator: validator, ExpiryWarning: 200 * time.Millisecond}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := &middleware.Claims{Subject: userID, ExpiresAt: time.Now().Add(400 * time.Millisecond)}
+		ctx := context.WithValue(r.Context(), middleware.ClaimsContextKey, claims)
+		ctx = context.WithValue(ctx, middleware.UserIDContextKey, userID)
+		ServeWs(hub, w, r.WithContext(ctx))
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	dial := func(t *testing.T) *websocket.Conn {
+		t.Helper()
+		ws, res, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		res.Body.Close()
+		return ws
+	}
+	readEvent := func(t *testing.T, ws *websocket.Conn) Event {
+		t.Helper()
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, incoming, err := ws.ReadMessage()
+		require.NoError(t, err)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(incoming, &event))
+		return event
+	}
+
+	t.Run("should close with 4001 once the token expired", func(t *testing.T) {
+		ws := dial(t)
+		defer ws.Close()
+
+		assert.Equal(t, eventTokenExpiring, readEvent(t, ws).Event)
+
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, _, err := ws.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, closeTokenExpired), err)
+	})
+
+	t.Run("should extend the connection on reauth", func(t *testing.T) {
+		ws := dial(t)
+		defer ws.Close()
+
+		assert.Equal(t, eventTokenExpiring, readEvent(t, ws).Event)
+
+		require.NoError(t, ws.WriteJSON(IncomingSubscription{Action: reauthAction, Token: "stale"}))
+		assert.Equal(t, eventReauthFailed, readEvent(t, ws).Event)
+
+		require.NoError(t, ws.WriteJSON(IncomingSubscription{Action: reauthAction, Token: "fresh"}))
+		assert.Equal(t, eventReauthenticated, readEvent(t, ws).Event)
+
+		// The original deadline passes without closing the connection.
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(500*time.Millisecond)))
+		_, _, err := ws.ReadMessage()
+		var netErr interface{ Timeout() bool }
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	})
+}
+
+func TestHub_RevokeUser(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, "revoked"))
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+
+	sessions := make([]*websocket.Conn, 2)
+	for i := range sessions {
+		ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		res.Body.Close()
+		sessions[i] = ws
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	n, err := hub.RevokeUser(ctx, "", "revoked")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	for _, ws := range sessions {
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, _, err := ws.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, closeTokenRevoked), err)
+	}
+}
+
+func TestClient_reauthenticateAfterClose(t *testing.T) {
+	validator := func(_ context.Context, token string) (*middleware.Claims, error) {
+		if token != "fresh" {
+			return nil, errors.New("invalid token")
+		}
+		return &middleware.Claims{ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+	hub := stoppedHub(WithAuth(AuthOptions{Validator: validator}))
+	client := newClient(hub, defaultTransport, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	assert.NotPanics(t, func() {
+		client.reauthenticate("fresh")
+		client.reauthenticate("stale")
+	})
+}
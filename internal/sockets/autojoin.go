@@ -0,0 +1,186 @@
+package sockets
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+// errRoomPinned rejects leaving a room the client was auto-joined to.
+var errRoomPinned = errors.New("room_not_leavable")
+
+// errRoomReserved rejects a client subscription which could reach the
+// auto-joined rooms of other clients, see Hub.reserved.
+var errRoomReserved = errors.New("room_reserved")
+
+// subjectClaim is the placeholder of the token subject.
+const subjectClaim = "sub"
+
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_-]+)\}`)
+
+// RoomTemplate is a room name with {claim} placeholders, e.g. "tenant:{tid}",
+// expanded from the claims of a connecting client. A claim holding a list
+// expands to one room per entry.
+type RoomTemplate struct {
+	template string
+	claims   []string
+
+	// pattern matches the rooms the template expands to, when it has placeholders.
+	pattern *regexp.Regexp
+}
+
+// ParseRoomTemplate parses a room template.
+func ParseRoomTemplate(template string) (RoomTemplate, error) {
+	var claims []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		claims = append(claims, match[1])
+	}
+
+	// Placeholders are replaced by a plain level to validate the rest of the name.
+	literal := placeholderPattern.ReplaceAllString(template, "x")
+	if strings.ContainsAny(literal, "{}") {
+		return RoomTemplate{}, fmt.Errorf("%w: unbalanced placeholder in %q", ErrInvalidRoomName, template)
+	}
+	if err := validateRoomName(literal); err != nil {
+		return RoomTemplate{}, fmt.Errorf("%w: %q", err, template)
+	}
+
+	t := RoomTemplate{template: template, claims: claims}
+	if len(claims) > 0 {
+		t.pattern = expansionPattern(template)
+	}
+	return t, nil
+}
+
+// expansionPattern returns a pattern matching the rooms a template expands to.
+// A placeholder matches any non-empty value, separators included.
+func expansionPattern(template string) *regexp.Regexp {
+	literals := placeholderPattern.Split(template, -1)
+	for i, literal := range literals {
+		literals[i] = regexp.QuoteMeta(literal)
+	}
+	return regexp.MustCompile("^" + strings.Join(literals, ".+") + "$")
+}
+
+// reservedPrefix returns the literal part of the template before its first
+// placeholder. Templates without placeholders expand to the same room for
+// every client and reserve nothing.
+func (t RoomTemplate) reservedPrefix() (string, bool) {
+	if len(t.claims) == 0 {
+		return "", false
+	}
+	prefix, _, _ := strings.Cut(t.template, "{")
+	return prefix, true
+}
+
+// ParseRoomTemplates parses a list of room templates.
+func ParseRoomTemplates(templates []string) ([]RoomTemplate, error) {
+	parsed := make([]RoomTemplate, 0, len(templates))
+	var errs []error
+	for _, template := range templates {
+		t, err := ParseRoomTemplate(template)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		parsed = append(parsed, t)
+	}
+	return parsed, errors.Join(errs...)
+}
+
+// Expand returns the rooms of the template for the given claims. Nothing is
+// returned when a claim is missing.
+func (t RoomTemplate) Expand(claims *middleware.Claims) []string {
+	rooms := []string{t.template}
+	for _, claim := range t.claims {
+		values := claimValues(claims, claim)
+		expanded := make([]string, 0, len(rooms)*len(values))
+		for _, room := range rooms {
+			for _, value := range values {
+				expanded = append(expanded, strings.ReplaceAll(room, "{"+claim+"}", value))
+			}
+		}
+		rooms = expanded
+	}
+	return rooms
+}
+
+// claimValues returns the values of a claim as room name levels. Values that
+// could turn the room into a wildcard pattern are left out.
+func claimValues(claims *middleware.Claims, claim string) []string {
+	var raw any
+	if claim == subjectClaim && claims.Subject != "" {
+		raw = claims.Subject
+	} else {
+		raw = claims.Values[claim]
+	}
+
+	var values []string
+	switch v := raw.(type) {
+	case nil:
+	case []string:
+		values = v
+	case []any:
+		for _, entry := range v {
+			values = append(values, fmt.Sprint(entry))
+		}
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+
+	// Filtered into a new slice, as values may be the slice of the claims.
+	valid := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || strings.ContainsAny(value, singleLevelWildcard+multiLevelWildcard) {
+			continue
+		}
+		valid = append(valid, value)
+	}
+	return valid
+}
+
+// autoJoinRooms returns the rooms a client with the given claims is pinned to.
+func (h *Hub) autoJoinRooms(claims *middleware.Claims) map[string]struct{} {
+	rooms := make(map[string]struct{})
+	for _, template := range h.autoJoin {
+		for _, room := range template.Expand(claims) {
+			if err := validateRoomName(room); err != nil {
+				slog.Warn("sockets: skipped auto-join room", "room", room, "err", err)
+				continue
+			}
+			rooms[room] = struct{}{}
+		}
+	}
+	return rooms
+}
+
+// reserved reports whether a client pinned to the given rooms could receive
+// the auto-joined rooms of other clients by subscribing to room. Only
+// templates with placeholders reserve rooms, e.g. "user:{sub}": an exact name
+// is reserved when the template expands to it, e.g. "user:42", unless the
+// client was auto-joined to it, and a wildcard pattern, e.g. "#", unless its
+// literal levels rule out the prefix of the template. Claim values may hold
+// separators, so the levels past a placeholder are unknown.
+func (h *Hub) reserved(room string, pinned map[string]struct{}) bool {
+	if _, ok := pinned[room]; ok {
+		return false
+	}
+	i := strings.IndexAny(room, singleLevelWildcard+multiLevelWildcard)
+	for _, template := range h.autoJoin {
+		prefix, ok := template.reservedPrefix()
+		switch {
+		case !ok:
+		case i < 0:
+			if template.pattern.MatchString(room) {
+				return true
+			}
+		case strings.HasPrefix(prefix, room[:i]) || strings.HasPrefix(room[:i], prefix):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package sockets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestRoomTemplate_Expand(t *testing.T) {
+	claims := &middleware.Claims{
+		Subject: "42",
+		Values: map[string]any{
+			"tid":   "acme",
+			"rooms": []any{"a", "b", "#"},
+			"org":   7.0,
+		},
+	}
+
+	tests := []struct {
+		template string
+		expected []string
+	}{
+		{"user:{sub}", []string{"user:42"}},
+		{"tenant:{tid}.alerts", []string{"tenant:acme.alerts"}},
+		{"{rooms}", []string{"a", "b"}},
+		{"org:{org}", []string{"org:7"}},
+		{"{tid}:{rooms}", []string{"acme:a", "acme:b"}},
+		{"group:{missing}", []string{}},
+		{"announcements", []string{"announcements"}},
+	}
+	for _, tt := range tests {
+		template, err := ParseRoomTemplate(tt.template)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, tt.expected, template.Expand(claims), tt.template)
+	}
+
+	for _, invalid := range []string{"user:{sub", "store.{tid}..x", "#.{sub}"} {
+		_, err := ParseRoomTemplate(invalid)
+		assert.ErrorIs(t, err, ErrInvalidRoomName, invalid)
+	}
+}
+
+func TestClaimValues(t *testing.T) {
+	groups := []string{"#", "a", "", "b"}
+	claims := &middleware.Claims{Values: map[string]any{"groups": groups}}
+
+	assert.Equal(t, []string{"a", "b"}, claimValues(claims, "groups"))
+	assert.Equal(t, []string{"#", "a", "", "b"}, groups, "the claims should be left untouched")
+}
+
+func TestServeWs_AutoJoin(t *testing.T) {
+	templates, err := ParseRoomTemplates([]string{"user:{sub}", "tenant:{tid}"})
+	require.NoError(t, err)
+
+	hub := NewHub(WithAutoJoin(templates))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub := r.URL.Query().Get("sub")
+		if sub == "" {
+			sub = "42"
+		}
+		claims := &middleware.Claims{Subject: sub, Values: map[string]any{"tid": "acme"}}
+		ctx := context.WithValue(r.Context(), middleware.ClaimsContextKey, claims)
+		ctx = context.WithValue(ctx, middleware.UserIDContextKey, claims.Subject)
+		ServeWs(hub, w, r.WithContext(ctx))
+	}))
+	defer s.Close()
+
+	ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	res.Body.Close()
+
+	// Queued events may be written in a single frame.
+	var pending []Event
+	readEvent := func(t *testing.T) Event {
+		t.Helper()
+		if len(pending) == 0 {
+			require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+			_, incoming, err := ws.ReadMessage()
+			require.NoError(t, err)
+			decoder := json.NewDecoder(bytes.NewReader(incoming))
+			for decoder.More() {
+				event := Event{}
+				require.NoError(t, decoder.Decode(&event))
+				pending = append(pending, event)
+			}
+		}
+		event := pending[0]
+		pending = pending[1:]
+		return event
+	}
+
+	t.Run("should join the rooms of the claims", func(t *testing.T) {
+		assert.Equal(t, eventAddedToRoom, readEvent(t).Event)
+		assert.Equal(t, eventAddedToRoom, readEvent(t).Event)
+
+		connections, _, err := hub.Connections(ctx, ConnectionPage{UserID: "42"})
+		require.NoError(t, err)
+		require.Len(t, connections, 1)
+		assert.Equal(t, []string{"tenant:acme", "user:42"}, connections[0].Rooms)
+	})
+
+	t.Run("should not leave auto-joined rooms", func(t *testing.T) {
+		require.NoError(t, ws.WriteJSON(IncomingSubscription{Action: unsubscribeAction, Room: "tenant:acme"}))
+
+		event := readEvent(t)
+		assert.Equal(t, eventSubscriptionRejected, event.Event)
+		assert.Equal(t, errRoomPinned.Error(), event.Data.(map[string]any)["reason"])
+
+		room, err := hub.Room(ctx, "", "tenant:acme")
+		require.NoError(t, err)
+		assert.Len(t, room.Members, 1)
+	})
+
+	t.Run("should reject wildcards matching the auto-joined rooms of others", func(t *testing.T) {
+		require.NoError(t, ws.WriteJSON(IncomingSubscription{Action: subscribeAction, Room: "#"}))
+
+		event := readEvent(t)
+		assert.Equal(t, eventSubscriptionRejected, event.Event)
+		assert.Equal(t, errRoomReserved.Error(), event.Data.(map[string]any)["reason"])
+
+		connections, _, err := hub.Connections(ctx, ConnectionPage{UserID: "42"})
+		require.NoError(t, err)
+		require.Len(t, connections, 1)
+		assert.Equal(t, []string{"tenant:acme", "user:42"}, connections[0].Rooms)
+	})
+
+	t.Run("should reject the auto-joined rooms of others", func(t *testing.T) {
+		other, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"?sub=7", nil)
+		require.NoError(t, err)
+		defer other.Close()
+		res.Body.Close()
+
+		require.NoError(t, other.WriteJSON(IncomingSubscription{Action: subscribeAction, Room: "user:42"}))
+
+		// The auto-join events come first.
+		require.NoError(t, other.SetReadDeadline(time.Now().Add(2*time.Second)))
+		var rejected *Event
+		for rejected == nil {
+			_, incoming, err := other.ReadMessage()
+			require.NoError(t, err)
+			decoder := json.NewDecoder(bytes.NewReader(incoming))
+			for decoder.More() {
+				event := Event{}
+				require.NoError(t, decoder.Decode(&event))
+				if event.Event == eventSubscriptionRejected {
+					rejected = &event
+				}
+			}
+		}
+		assert.Equal(t, errRoomReserved.Error(), rejected.Data.(map[string]any)["reason"])
+
+		room, err := hub.Room(ctx, "", "user:42")
+		require.NoError(t, err)
+		assert.Len(t, room.Members, 1)
+	})
+}
+
+func TestHub_reserved(t *testing.T) {
+	templates, err := ParseRoomTemplates([]string{"user:{sub}", "store.{sid}.private", "announcements"})
+	require.NoError(t, err)
+	hub := NewHub(WithAutoJoin(templates))
+	pinned := map[string]struct{}{"user:7": {}, "store.3.private": {}, "announcements": {}}
+
+	tests := []struct {
+		room     string
+		expected bool
+	}{
+		{"#", true},
+		{"*", true},
+		{"store.#", true},
+		{"store.*.private", true},
+		{"user:42", true},
+		{"user:7.x", true},
+		{"store.4.private", true},
+		{"store.4.x.private", true},
+		{"user:7", false},
+		{"store.3.private", false},
+		{"user:", false},
+		{"store.4", false},
+		{"orders.#", false},
+		{"store", false},
+		{"announcements", false},
+		{"announcements.#", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, hub.reserved(tt.room, pinned), tt.room)
+	}
+	assert.True(t, hub.reserved("user:7", nil), "rooms pinned to others are reserved")
+	assert.False(t, NewHub().reserved("#", nil), "nothing is reserved without templates")
+}
@@ -3,37 +3,113 @@ package sockets
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"slices"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/ratelimit"
 )
 
+// Inbound rate limits checked for every frame read.
 const (
-	// Time allowed to write a message to the peer.
-	writeWait = 10 * time.Second
-
-	// Time allowed to read the next pong message from the peer.
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait.
-	pingPeriod = 45 * time.Second
-
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512
+	framesLimit        = "frames"
+	subscriptionsLimit = "subscriptions"
 )
 
-// Client is the websocket client.
+// errRateLimited ends the read loop of a client exceeding its rate limits.
+var errRateLimited = errors.New("rate limit exceeded")
+
+// Client is a hub client, connected through a websocket or an event stream.
 type Client struct {
 	hub *Hub
 	ID  string
 
-	// The websocket connection.
-	conn *websocket.Conn
+	// Tenant scopes the user ID and rooms of the client, empty without tenancy.
+	Tenant string
+
+	// ConnID identifies the connection, as opposed to the user.
+	ConnID string
+
+	// IP is the remote address of the peer, resolved through trusted proxies.
+	IP string
+
+	// ConnectedAt is when the connection was upgraded.
+	ConnectedAt time.Time
+
+	// The websocket connection, and its timeouts and buffer sizes.
+	conn      *websocket.Conn
+	transport Transport
+
+	// session is set instead of conn for clients connected through plain
+	// HTTP requests, see ServeSSE and ServePoll.
+	session *session
 
 	// Buffered channel of outbound messages.
-	send chan []byte
+	send chan outbound
+
+	// kick asks the write pump to close the connection.
+	kick chan closeRequest
+
+	// format is the wire format negotiated by the peer, JSON by default.
+	format Format
+
+	// compress is set when the peer negotiated permessage-deflate, and
+	// wireBytes counts the bytes written to its connection.
+	compress  bool
+	wireBytes atomic.Int64
+
+	// closed is closed once the read pump returned, which stops the write pump.
+	closed chan struct{}
+
+	// Rooms the client joins on connect and cannot leave.
+	pinnedRooms map[string]struct{}
+
+	// expiresAt is the expiry of the token the client connected with, and
+	// reauth carries the expiry of fresh tokens to the write pump.
+	expiresAt time.Time
+	reauth    chan time.Time
+
+	// Inbound rate limiters of the connection, the policy they were made
+	// from, and the number of frames discarded for going over them.
+	policy        *livePolicy
+	frames        *ratelimit.Bucket
+	subscriptions *ratelimit.Bucket
+	violations    int
+}
+
+// Application close codes, in the 4000-4999 range reserved for applications.
+const (
+	closeSessionEvicted = 4000
+	closeTokenExpired   = 4001
+	closeDisconnected   = 4002
+	closeTokenRevoked   = 4003
+)
+
+// closeSlowConsumer closes clients that cannot keep up with their messages.
+const closeSlowConsumer = websocket.CloseTryAgainLater
+
+// drainedRequest ends the event streams and long polls of a server shutting down.
+var drainedRequest = closeRequest{code: websocket.CloseGoingAway, text: "server shutting down"}
+
+// closeRequest is a close frame the write pump sends before closing the connection.
+type closeRequest struct {
+	code int
+	text string
+}
+
+// outbound is a marshalled message queued for a client.
+type outbound struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func (o outbound) expired(now time.Time) bool {
+	return !o.expiresAt.IsZero() && now.After(o.expiresAt)
 }
 
 // readPump pumps messages from the websocket connection to the hub.
@@ -43,45 +119,73 @@ type Client struct {
 // reads from this goroutine.
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.release(c)
+		close(c.closed)
 		c.conn.Close()
 	}()
-	c.conn.SetReadLimit(maxMessageSize)
-	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
-	c.conn.SetPongHandler(func(string) error { return c.conn.SetReadDeadline(time.Now().Add(pongWait)) })
+	c.conn.SetReadLimit(c.transport.MaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.transport.PongWait))
+	c.conn.SetPongHandler(func(string) error { return c.conn.SetReadDeadline(time.Now().Add(c.transport.PongWait)) })
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("read message error: %v\n", err)
+				slog.Debug("sockets: read message error", "err", err)
 			}
 			break
 		}
 
-		// if message is FE ping to keep connection alive, discard it.
-		if string(message) == "0" {
-			continue
+		if err := c.handleFrame(message); errors.Is(err, errRateLimited) {
+			c.closeWith(websocket.ClosePolicyViolation, err.Error(), metrics.CloseRateLimited)
+			break
+		} else if err != nil {
+			slog.Debug("sockets: invalid frame", "err", err)
 		}
+	}
+}
 
-		incomingMsg := IncomingSubscription{}
-		err = json.Unmarshal(message, &incomingMsg)
-		if err != nil {
-			log.Printf("unmarshal read message error: %v\n", err)
-			continue
-		}
+// handleFrame handles a frame sent by the client, changing its rooms or
+// re-authenticating it. Frames over a rate limit are discarded, and
+// errRateLimited is returned once the client must be closed. Other errors
+// report an invalid frame.
+func (c *Client) handleFrame(message []byte) error {
+	policy := c.refreshPolicy()
+	allowed, err := c.limit(c.frames, policy.userFrames, framesLimit)
+	if err != nil || !allowed {
+		return err
+	}
 
-		if err := validateIncomingMessage(incomingMsg); err != nil {
-			log.Printf("validate incoming message error: %v\n", err)
-			continue
-		}
+	// if message is FE ping to keep connection alive, discard it.
+	if string(message) == "0" {
+		return nil
+	}
 
-		if incomingMsg.Action == unsubscribeAction {
-			c.hub.unregisterRoom <- newSubscription(incomingMsg.Room, c)
-			continue
-		}
+	incomingMsg := IncomingSubscription{}
+	if err := json.Unmarshal(message, &incomingMsg); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
 
-		c.hub.registerRoom <- newSubscription(incomingMsg.Room, c)
+	if err := validateIncomingMessage(incomingMsg); err != nil {
+		return fmt.Errorf("validate: %w", err)
 	}
+
+	if incomingMsg.Action == reauthAction {
+		c.reauthenticate(incomingMsg.Token)
+		return nil
+	}
+
+	allowed, err = c.limit(c.subscriptions, policy.userSubscriptions, subscriptionsLimit)
+	if err != nil || !allowed {
+		return err
+	}
+
+	if incomingMsg.Action == unsubscribeAction {
+		enqueue(c.hub, c.hub.unregisterRoom, newSubscription(incomingMsg.Room, c))
+		return nil
+	}
+
+	enqueue(c.hub, c.hub.registerRoom, newSubscription(incomingMsg.Room, c))
+	return nil
 }
 
 // writePump pumps messages from the hub to the websocket connection.
@@ -90,38 +194,63 @@ func (c *Client) readPump() {
 // application ensures that there is at most one writer to a connection by
 // executing all writes from this goroutine.
 func (c *Client) writePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.transport.PingPeriod)
+	deadline := newTokenDeadline(c.expiresAt, c.hub.auth.ExpiryWarning)
 	defer func() {
 		ticker.Stop()
+		deadline.stop()
 		c.conn.Close()
 	}()
 	for {
 		select {
-		case message, ok := <-c.send:
-			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel.
-				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+		case message := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.transport.WriteWait))
 
-			writr, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
+			// Add queued chat messages to the current websocket message.
+			// Messages may have expired while waiting in the send buffer.
+			now := time.Now()
+			frame := make([][]byte, 0, 1+len(c.send))
+			size := 0
+			for n := len(c.send); ; n-- {
+				if message.expired(now) {
+					metrics.DroppedMessages.Add(metrics.DropExpired, 1)
+					metrics.AddTenant(c.Tenant, metrics.TenantDroppedMessages, 1)
+				} else {
+					frame = append(frame, message.data)
+					size += len(message.data)
+				}
+				if n == 0 {
+					break
+				}
+				message = <-c.send
+			}
+			if len(frame) == 0 {
+				continue
+			}
+			if err := c.writeFrame(frame, size); err != nil {
 				return
 			}
-			_, _ = writr.Write(message)
-
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				_, _ = writr.Write(<-c.send)
+		case expiresAt := <-c.reauth:
+			deadline.reset(expiresAt)
+		case <-deadline.C():
+			if deadline.fire() {
+				metrics.ClosedConnections.Add(metrics.CloseTokenExpired, 1)
+				msg := websocket.FormatCloseMessage(closeTokenExpired, "token expired")
+				_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.transport.WriteWait))
+				return
 			}
-
-			if err := writr.Close(); err != nil {
+			event := Event{Event: eventTokenExpiring, Data: tokenExpiryData{ExpiresAt: deadline.expiresAt}}
+			if err := c.writeEvent(event); err != nil {
 				return
 			}
+		case req := <-c.kick:
+			msg := websocket.FormatCloseMessage(req.code, req.text)
+			_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.transport.WriteWait))
+			return
+		case <-c.closed:
+			return
 		case <-ticker.C:
-			if err := c.conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(writeWait)); err != nil {
+			if err := c.conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(c.transport.WriteWait)); err != nil {
 				c.conn.Close()
 				return
 			}
@@ -129,11 +258,158 @@ func (c *Client) writePump() {
 	}
 }
 
+// writeFrame writes messages to the peer, compressed when the peer
+// negotiated it and they are large enough. JSON messages are concatenated
+// into a single websocket message, whereas binary ones are each sent as their
+// own, for the peer to tell them apart.
+func (c *Client) writeFrame(messages [][]byte, size int) error {
+	if c.format == FormatJSON {
+		return c.writeMessage(size, messages...)
+	}
+	for _, data := range messages {
+		if err := c.writeMessage(len(data), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMessage writes parts of size bytes as a single websocket message.
+func (c *Client) writeMessage(size int, parts ...[]byte) error {
+	compress := c.compress && c.hub.compression.compress(size)
+	c.conn.EnableWriteCompression(compress)
+	before, start := c.wireBytes.Load(), time.Now()
+
+	writr, err := c.conn.NextWriter(c.format.messageType())
+	if err != nil {
+		return err
+	}
+	for _, data := range parts {
+		_, _ = writr.Write(data)
+	}
+	if err := writr.Close(); err != nil {
+		return err
+	}
+
+	if c.compress {
+		countFrame(compress, int64(size), c.wireBytes.Load()-before, time.Since(start))
+	}
+	return nil
+}
+
+// writeEvent writes a server event. It must only be called from the write pump.
+func (c *Client) writeEvent(event Event) error {
+	data, err := encode(event, c.format)
+	if err != nil {
+		return err
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.transport.WriteWait))
+	return c.conn.WriteMessage(c.format.messageType(), data)
+}
+
+// limit checks an inbound frame against the connection and user limiters. A
+// frame over a limit is answered with a warning event and must be discarded.
+// errRateLimited is returned once the client went over MaxViolations times.
+func (c *Client) limit(bucket *ratelimit.Bucket, users *ratelimit.Keyed, name string) (bool, error) {
+	if bucket.Allow() && (c.ID == "" || users.Allow(c.userKey())) {
+		return true, nil
+	}
+
+	metrics.RateLimitedFrames.Add(name, 1)
+	metrics.AddTenant(c.Tenant, metrics.TenantRateLimitedFrames, 1)
+	c.violations++
+	maxViolations := c.policy.RateLimits.MaxViolations
+	if c.violations > maxViolations {
+		return false, errRateLimited
+	}
+
+	enqueue(c.hub, c.hub.direct, &directMessage{
+		client: c,
+		payload: Event{
+			Event: eventRateLimited,
+			Data: rateLimitedData{
+				Limit:         name,
+				Violations:    c.violations,
+				MaxViolations: maxViolations,
+			},
+		},
+	})
+	return false, nil
+}
+
+// ConnectionID implements Subscriber.
+func (c *Client) ConnectionID() string {
+	return c.ConnID
+}
+
+// User implements Subscriber.
+func (c *Client) User() (string, string) {
+	return c.Tenant, c.ID
+}
+
+// Format implements Subscriber.
+func (c *Client) Format() Format {
+	return c.format
+}
+
+// Deliver implements Subscriber. Messages wait in the send buffer, and a
+// client whose buffer is full cannot keep up.
+func (c *Client) Deliver(data []byte, expiresAt time.Time) bool {
+	select {
+	case c.send <- outbound{data: data, expiresAt: expiresAt}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close implements Subscriber. It asks the write pump to close the connection
+// with the given code, and is safe to call from any goroutine.
+func (c *Client) Close(code int, text string) {
+	select {
+	case c.kick <- closeRequest{code: code, text: text}:
+	default:
+		// A close is already pending.
+	}
+}
+
+// describe adds what the client knows about its peer to the admin view.
+func (c *Client) describe(info *ConnectionInfo) {
+	info.IP = c.IP
+	info.ConnectedAt = c.ConnectedAt
+	info.QueueDepth = len(c.send)
+}
+
+// disconnect closes the connection with the given code and counts the reason.
+func (c *Client) disconnect(code int, text, reason string) {
+	metrics.ClosedConnections.Add(reason, 1)
+	c.Close(code, text)
+}
+
+// closeWith sends a close frame with the given code to the peer. The caller
+// is expected to stop reading, which unregisters the client.
+func (c *Client) closeWith(code int, text, reason string) {
+	metrics.ClosedConnections.Add(reason, 1)
+	msg := websocket.FormatCloseMessage(code, text)
+	_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.transport.WriteWait))
+}
+
 func validateIncomingMessage(msg IncomingSubscription) error {
+	if msg.Action == reauthAction {
+		if msg.Token == "" {
+			return errors.New("invalid reauth message body")
+		}
+		return nil
+	}
+
 	if msg.Action == "" || msg.Room == "" {
 		return errors.New("invalid subscription message body")
 	}
 
+	if err := validateRoomName(msg.Room); err != nil {
+		return err
+	}
+
 	validActions := []string{subscribeAction, unsubscribeAction}
 	if slices.Contains(validActions, msg.Action) {
 		return nil
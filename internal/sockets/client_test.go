@@ -0,0 +1,87 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/ratelimit"
+)
+
+func TestClient_RateLimit(t *testing.T) {
+	hub := NewHub(WithRateLimits(RateLimits{
+		Frames:        ratelimit.Limit{Rate: 0.001, Burst: 2},
+		MaxViolations: 1,
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+
+	ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	res.Body.Close()
+
+	for range 3 {
+		require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("0")))
+	}
+
+	t.Run("should warn when going over the limit", func(t *testing.T) {
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, incoming, err := ws.ReadMessage()
+		require.NoError(t, err)
+
+		event := Event{}
+		require.NoError(t, json.Unmarshal(incoming, &event))
+		assert.Equal(t, eventRateLimited, event.Event)
+	})
+
+	t.Run("should close repeat offenders with policy violation", func(t *testing.T) {
+		require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("0")))
+
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, _, err := ws.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), err)
+	})
+}
+
+// stoppedHub returns a hub whose loop ran and stopped.
+func stoppedHub(opts ...Option) *Hub {
+	hub := NewHub(opts...)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	hub.Run(ctx)
+	return hub
+}
+
+func TestClient_handleFrameAfterClose(t *testing.T) {
+	hub := stoppedHub(WithRateLimits(RateLimits{
+		Frames:        ratelimit.Limit{Rate: 0.001, Burst: 2},
+		MaxViolations: 5,
+	}))
+
+	client := newClient(hub, defaultTransport, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.NotPanics(t, func() {
+		require.NoError(t, client.handleFrame([]byte(`{"action":"enter","room":"a"}`)))
+		require.NoError(t, client.handleFrame([]byte(`{"action":"leave","room":"a"}`)))
+		// Over the frames limit, the warning is not sent.
+		require.NoError(t, client.handleFrame([]byte("0")))
+	})
+}
@@ -0,0 +1,97 @@
+package sockets
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+)
+
+// Compression enables permessage-deflate on the websockets of peers that
+// negotiate it. Frames under MinSize bytes are sent uncompressed, as the
+// deflate overhead outweighs the savings on small payloads.
+type Compression struct {
+	Enabled bool
+
+	// Level is a compress/flate level, from -2 (Huffman only) to 9 (best
+	// compression). Zero uses the websocket default of 1 (best speed).
+	Level int
+
+	// MinSize is the payload size in bytes from which frames are compressed.
+	MinSize int
+}
+
+// WithCompression sets the permessage-deflate settings of the websockets
+// opened through ServeWs.
+func WithCompression(compression Compression) Option {
+	return func(h *Hub) {
+		h.compression = compression
+	}
+}
+
+// compress reports whether a frame of size bytes is worth compressing.
+func (c Compression) compress(size int) bool {
+	return size >= c.MinSize
+}
+
+// offersDeflate reports whether the websocket handshake offers
+// permessage-deflate, which the upgrade then negotiates.
+func offersDeflate(r *http.Request) bool {
+	for _, value := range r.Header.Values("Sec-Websocket-Extensions") {
+		for _, ext := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.TrimSpace(name) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countingWriter counts the bytes written to the connection hijacked by the
+// websocket upgrade, so that the size of compressed frames on the wire can be
+// told apart from their payload.
+type countingWriter struct {
+	http.ResponseWriter
+	written *atomic.Int64
+}
+
+func (w countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &countingConn{Conn: conn, written: w.written}, brw, nil
+}
+
+func (w countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type countingConn struct {
+	net.Conn
+	written *atomic.Int64
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// countFrame records the payload and wire size of a frame written by the
+// write pump, and how long writing a compressed one took.
+func countFrame(compressed bool, payload, wire int64, took time.Duration) {
+	if !compressed {
+		metrics.Compression.Add(metrics.FramesUncompressed, 1)
+		return
+	}
+	metrics.Compression.Add(metrics.FramesCompressed, 1)
+	metrics.Compression.Add(metrics.CompressedPayloadBytes, payload)
+	metrics.Compression.Add(metrics.CompressedWireBytes, wire)
+	metrics.CompressionDuration.Observe(took)
+}
@@ -0,0 +1,181 @@
+package sockets
+
+import (
+	"compress/flate"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func compressionCounter(name string) int64 {
+	if v, ok := metrics.Compression.Get(name).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestServeWs_Compression(t *testing.T) {
+	hub := NewHub(WithCompression(Compression{Enabled: true, Level: flate.BestCompression, MinSize: 256}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, r.URL.Query().Get("user")))
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	dial := func(t *testing.T, user string, compress bool) *websocket.Conn {
+		t.Helper()
+		dialer := websocket.Dialer{EnableCompression: compress}
+		ws, res, err := dialer.Dial(wsURL+"?user="+user, nil)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.Eventually(t, func() bool {
+			var sub Subscriber
+			_ = hub.call(ctx, func() { sub = hub.GetUser("", user) })
+			return sub != nil
+		}, time.Second, 10*time.Millisecond)
+		return ws
+	}
+
+	send := func(t *testing.T, ws *websocket.Conn, user, body string) {
+		t.Helper()
+		hub.Private <- &MessageWithUser{Message: Message{Type: "order", EntityID: "1", MessageBody: body}, UserID: user}
+		_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+		var got Message
+		require.NoError(t, ws.ReadJSON(&got))
+		assert.Equal(t, body, got.MessageBody)
+	}
+
+	t.Run("should compress large frames", func(t *testing.T) {
+		ws := dial(t, "large", true)
+		defer ws.Close()
+
+		frames := compressionCounter(metrics.FramesCompressed)
+		payload := compressionCounter(metrics.CompressedPayloadBytes)
+		wire := compressionCounter(metrics.CompressedWireBytes)
+		timed := metrics.CompressionDuration.Get("count")
+		send(t, ws, "large", strings.Repeat(`{"status":"shipped"}`, 50))
+
+		// The frame is counted once written, the peer may read it before.
+		require.Eventually(t, func() bool {
+			return metrics.CompressionDuration.Get("count") == timed+1
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, frames+1, compressionCounter(metrics.FramesCompressed))
+		assert.Less(t, compressionCounter(metrics.CompressedWireBytes)-wire, compressionCounter(metrics.CompressedPayloadBytes)-payload)
+	})
+
+	t.Run("should not compress small frames", func(t *testing.T) {
+		ws := dial(t, "small", true)
+		defer ws.Close()
+
+		compressed := compressionCounter(metrics.FramesCompressed)
+		uncompressed := compressionCounter(metrics.FramesUncompressed)
+		send(t, ws, "small", "shipped")
+
+		assert.Equal(t, compressed, compressionCounter(metrics.FramesCompressed))
+		assert.Equal(t, uncompressed+1, compressionCounter(metrics.FramesUncompressed))
+	})
+
+	t.Run("should not compress for peers without permessage-deflate", func(t *testing.T) {
+		ws := dial(t, "plain", false)
+		defer ws.Close()
+
+		compressed := compressionCounter(metrics.FramesCompressed)
+		uncompressed := compressionCounter(metrics.FramesUncompressed)
+		send(t, ws, "plain", strings.Repeat(`{"status":"shipped"}`, 50))
+
+		assert.Equal(t, compressed, compressionCounter(metrics.FramesCompressed))
+		assert.Equal(t, uncompressed, compressionCounter(metrics.FramesUncompressed))
+	})
+}
+
+// BenchmarkWriteFrame measures the cost of writing a notification to a
+// websocket per compression level, and reports the compression ratio.
+func BenchmarkWriteFrame(b *testing.B) {
+	data, err := json.Marshal(Message{
+		Type:     "order",
+		EntityID: "3f0c2a9e-8d4b-4c55-9a51-2f6d7c1e0b84",
+		MessageBody: map[string]any{
+			"status":    "shipped",
+			"carrier":   "UPS",
+			"items":     []string{"sku-1001", "sku-1002", "sku-1003", "sku-1004"},
+			"updatedAt": "2026-10-19T08:30:00Z",
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, level := range []int{0, flate.BestSpeed, flate.DefaultCompression, flate.BestCompression} {
+		name := "off"
+		if level != 0 {
+			name = fmt.Sprintf("level=%d", level)
+		}
+		b.Run(name, func(b *testing.B) {
+			enabled := level != 0
+			hub := NewHub(WithCompression(Compression{Enabled: enabled, Level: level}))
+			client := &Client{hub: hub, compress: enabled}
+
+			upgraded := make(chan *websocket.Conn, 1)
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				upgrader := websocket.Upgrader{EnableCompression: enabled}
+				conn, err := upgrader.Upgrade(countingWriter{ResponseWriter: w, written: &client.wireBytes}, r, nil)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				if enabled {
+					_ = conn.SetCompressionLevel(level)
+				}
+				upgraded <- conn
+			}))
+			defer s.Close()
+
+			dialer := websocket.Dialer{EnableCompression: enabled}
+			ws, res, err := dialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			res.Body.Close()
+			defer ws.Close()
+			go func() {
+				for {
+					if _, _, err := ws.NextReader(); err != nil {
+						return
+					}
+				}
+			}()
+			client.conn = <-upgraded
+			defer client.conn.Close()
+
+			before := client.wireBytes.Load()
+			for b.Loop() {
+				if err := client.writeFrame([][]byte{data}, len(data)); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(client.wireBytes.Load()-before)/float64(b.N*len(data)), "wire/payload")
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package sockets
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+)
+
+// ConnectionLimits caps the number of accepted websocket connections. A zero
+// limit is unlimited.
+type ConnectionLimits struct {
+	Total     int
+	PerTenant int
+	PerUser   int
+	PerIP     int
+
+	// EvictOldest closes the oldest session of a user reaching PerUser
+	// instead of rejecting the new connection.
+	EvictOldest bool
+
+	// RetryAfter is advertised to rejected clients through the Retry-After header.
+	RetryAfter time.Duration
+
+	// TrustedProxies are the networks whose forwarding headers are honoured
+	// when resolving the IP address of a client.
+	TrustedProxies []netip.Prefix
+}
+
+// limitError is the rejection of a connection over a limit.
+type limitError struct {
+	status int
+	reason string
+}
+
+// connLimiter counts the accepted connections. It is used from the upgrade
+// handlers, outside of the hub loop, so that rejected clients are never upgraded.
+type connLimiter struct {
+	mu     sync.Mutex
+	limits ConnectionLimits
+	total  int
+
+	// sessions of each user, oldest first, keyed by Client.userKey.
+	users   map[string][]*Client
+	ips     map[string]int
+	tenants map[string]int
+
+	// acquired holds the clients counted, so that releasing is idempotent.
+	acquired map[*Client]struct{}
+	// evicting holds the sessions acquire evicted which did not end yet, so
+	// that they can be restored when the client replacing them fails.
+	evicting map[*Client]struct{}
+}
+
+func newConnLimiter(limits ConnectionLimits) *connLimiter {
+	return &connLimiter{
+		limits:   limits,
+		users:    make(map[string][]*Client),
+		ips:      make(map[string]int),
+		tenants:  make(map[string]int),
+		acquired: make(map[*Client]struct{}),
+		evicting: make(map[*Client]struct{}),
+	}
+}
+
+// acquire counts the client. It returns the session to evict to make room
+// for it, if any, or an error when the client is over a limit.
+func (l *connLimiter) acquire(client *Client) (*Client, *limitError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limits.Total > 0 && l.total >= l.limits.Total {
+		return nil, &limitError{status: http.StatusServiceUnavailable, reason: "total"}
+	}
+	if l.limits.PerTenant > 0 && l.tenants[client.Tenant] >= l.limits.PerTenant {
+		return nil, &limitError{status: http.StatusTooManyRequests, reason: "tenant"}
+	}
+	if l.limits.PerIP > 0 && l.ips[client.IP] >= l.limits.PerIP {
+		return nil, &limitError{status: http.StatusTooManyRequests, reason: "ip"}
+	}
+
+	var evicted *Client
+	key := client.userKey()
+	if client.ID != "" && l.limits.PerUser > 0 && len(l.users[key]) >= l.limits.PerUser {
+		if !l.limits.EvictOldest {
+			return nil, &limitError{status: http.StatusTooManyRequests, reason: "user"}
+		}
+		evicted = l.users[key][0]
+		l.remove(evicted)
+		l.evicting[evicted] = struct{}{}
+	}
+	l.add(client)
+
+	return evicted, nil
+}
+
+// restore counts again a session acquire evicted for a client which failed
+// to connect, unless the session ended meanwhile. It stays the oldest one.
+func (l *connLimiter) restore(evicted *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.evicting[evicted]; !ok {
+		return
+	}
+	delete(l.evicting, evicted)
+	l.add(evicted)
+	if evicted.ID != "" {
+		key := evicted.userKey()
+		sessions := l.users[key]
+		l.users[key] = append([]*Client{evicted}, sessions[:len(sessions)-1]...)
+	}
+}
+
+// add counts the client as the newest session of its user. It must be
+// called with l.mu held.
+func (l *connLimiter) add(client *Client) {
+	l.total++
+	l.tenants[client.Tenant]++
+	l.ips[client.IP]++
+	if client.ID != "" {
+		key := client.userKey()
+		l.users[key] = append(l.users[key], client)
+	}
+	l.acquired[client] = struct{}{}
+}
+
+// setLimits replaces the limits. Clients already counted are kept.
+func (l *connLimiter) setLimits(limits ConnectionLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limits = limits
+}
+
+// trustedProxies returns the networks whose forwarding headers are honoured.
+func (l *connLimiter) trustedProxies() []netip.Prefix {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limits.TrustedProxies
+}
+
+// release stops counting the client.
+func (l *connLimiter) release(client *Client) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.remove(client)
+}
+
+// remove stops counting the client. It must be called with l.mu held.
+func (l *connLimiter) remove(client *Client) {
+	delete(l.evicting, client)
+	if _, ok := l.acquired[client]; !ok {
+		return
+	}
+	delete(l.acquired, client)
+
+	l.total--
+	if l.tenants[client.Tenant]--; l.tenants[client.Tenant] <= 0 {
+		delete(l.tenants, client.Tenant)
+	}
+	if l.ips[client.IP]--; l.ips[client.IP] <= 0 {
+		delete(l.ips, client.IP)
+	}
+	if client.ID != "" {
+		key := client.userKey()
+		sessions := slices.DeleteFunc(l.users[key], func(c *Client) bool { return c == client })
+		if len(sessions) == 0 {
+			delete(l.users, key)
+		} else {
+			l.users[key] = sessions
+		}
+	}
+}
+
+// reject answers the upgrade request of a client over a connection limit.
+func (l *connLimiter) reject(w http.ResponseWriter, client *Client, err *limitError) {
+	metrics.RejectedConnections.Add(err.reason, 1)
+	metrics.AddTenant(client.Tenant, metrics.TenantRejectedConnections, 1)
+	l.mu.Lock()
+	retryAfter := l.limits.RetryAfter
+	l.mu.Unlock()
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+	}
+	http.Error(w, http.StatusText(err.status), err.status)
+}
@@ -0,0 +1,165 @@
+package sockets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestConnLimiter(t *testing.T) {
+	t.Run("should reject over the total limit with service unavailable", func(t *testing.T) {
+		l := newConnLimiter(ConnectionLimits{Total: 1, RetryAfter: 30 * time.Second})
+		_, err := l.acquire(&Client{IP: "a"})
+		require.Nil(t, err)
+
+		_, err = l.acquire(&Client{IP: "b"})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, err.status)
+
+		rec := httptest.NewRecorder()
+		l.reject(rec, &Client{IP: "b"}, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("should reject over the ip limit until released", func(t *testing.T) {
+		l := newConnLimiter(ConnectionLimits{PerIP: 1})
+		first := &Client{IP: "a"}
+		_, err := l.acquire(first)
+		require.Nil(t, err)
+
+		_, err = l.acquire(&Client{IP: "a"})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, err.status)
+
+		l.release(first)
+		l.release(first)
+		_, err = l.acquire(&Client{IP: "a"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, l.total)
+	})
+
+	t.Run("should reject over the user limit", func(t *testing.T) {
+		l := newConnLimiter(ConnectionLimits{PerUser: 1})
+		_, err := l.acquire(&Client{ID: "user", IP: "a"})
+		require.Nil(t, err)
+
+		_, err = l.acquire(&Client{ID: "user", IP: "b"})
+		require.NotNil(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, err.status)
+
+		_, err = l.acquire(&Client{ID: "other", IP: "b"})
+		assert.Nil(t, err)
+
+		// The same user ID of another tenant is another user.
+		_, err = l.acquire(&Client{ID: "user", Tenant: "acme", IP: "c"})
+		assert.Nil(t, err)
+	})
+
+	t.Run("should reject over the tenant limit", func(t *testing.T) {
+		l := newConnLimiter(ConnectionLimits{PerTenant: 1})
+		_, err := l.acquire(&Client{Tenant: "acme", IP: "a"})
+		require.Nil(t, err)
+
+		_, err = l.acquire(&Client{Tenant: "acme", IP: "b"})
+		require.NotNil(t, err)
+		assert.Equal(t, "tenant", err.reason)
+
+		_, err = l.acquire(&Client{Tenant: "globex", IP: "b"})
+		assert.Nil(t, err)
+	})
+
+	t.Run("should evict the oldest session of the user", func(t *testing.T) {
+		l := newConnLimiter(ConnectionLimits{PerUser: 2, EvictOldest: true})
+		oldest := &Client{ID: "user", IP: "a"}
+		for _, c := range []*Client{oldest, {ID: "user", IP: "a"}} {
+			_, err := l.acquire(c)
+			require.Nil(t, err)
+		}
+
+		evicted, err := l.acquire(&Client{ID: "user", IP: "a"})
+		require.Nil(t, err)
+		assert.Same(t, oldest, evicted)
+		assert.Len(t, l.users["user"], 2)
+		assert.Equal(t, 2, l.total)
+	})
+
+	t.Run("should restore the evicted session", func(t *testing.T) {
+		l := newConnLimiter(ConnectionLimits{PerUser: 2, EvictOldest: true})
+		oldest, newer := &Client{ID: "user", IP: "a"}, &Client{ID: "user", IP: "a"}
+		for _, c := range []*Client{oldest, newer} {
+			_, err := l.acquire(c)
+			require.Nil(t, err)
+		}
+
+		failed := &Client{ID: "user", IP: "a"}
+		evicted, err := l.acquire(failed)
+		require.Nil(t, err)
+		l.release(failed)
+		l.restore(evicted)
+		assert.Equal(t, []*Client{oldest, newer}, l.users["user"])
+		assert.Equal(t, 2, l.total)
+
+		// A session which ended while evicted is not restored.
+		evicted, err = l.acquire(failed)
+		require.Nil(t, err)
+		l.release(evicted)
+		l.release(failed)
+		l.restore(evicted)
+		assert.Equal(t, []*Client{newer}, l.users["user"])
+		assert.Equal(t, 1, l.total)
+	})
+}
+
+func TestServeWs_failedUpgradeAtUserLimit(t *testing.T) {
+	hub := NewHub(WithConnectionLimits(ConnectionLimits{PerUser: 1, EvictOldest: true}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, "user")))
+	}))
+	defer s.Close()
+	sessions := func() int {
+		hub.conns.mu.Lock()
+		defer hub.conns.mu.Unlock()
+		return len(hub.conns.users["user"])
+	}
+
+	ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	res.Body.Close()
+
+	// A plain request is not upgraded.
+	res, err = http.Get(s.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	assert.Equal(t, 1, sessions())
+	require.NoError(t, ws.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+	_, _, err = ws.ReadMessage()
+	assert.False(t, websocket.IsCloseError(err, closeSessionEvicted), err)
+
+	// The session is still counted, so the next upgrade evicts it.
+	ws2, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	require.NoError(t, err)
+	defer ws2.Close()
+	res.Body.Close()
+	assert.Equal(t, 1, sessions())
+}
@@ -0,0 +1,69 @@
+package sockets
+
+import "time"
+
+// Server events sent to a single connection.
+const (
+	eventRateLimited          = "rate_limited"
+	eventSubscriptionRejected = "subscription_rejected"
+	eventTokenExpiring        = "token_expiring"
+	eventReauthenticated      = "reauthenticated"
+	eventReauthFailed         = "reauth_failed"
+	eventAddedToRoom          = "added_to_room"
+	eventRemovedFromRoom      = "removed_from_room"
+	eventConnected            = "connected"
+	eventClosed               = "closed"
+)
+
+// Event is a notice generated by the server for a single connection, as
+// opposed to a notification published by a backend.
+type Event struct {
+	Event string `json:"event"`
+	Data  any    `json:"data,omitempty"`
+}
+
+// rateLimitedData describes a rate limit violation to the client.
+type rateLimitedData struct {
+	Limit         string `json:"limit"`
+	Violations    int    `json:"violations"`
+	MaxViolations int    `json:"maxViolations"`
+}
+
+// subscriptionRejectedData describes a room the client could not enter.
+type subscriptionRejectedData struct {
+	Room   string `json:"room"`
+	Reason string `json:"reason"`
+}
+
+// addedToRoomData tells the client it was put in a room by the server.
+type addedToRoomData struct {
+	Room string `json:"room"`
+}
+
+// removedFromRoomData tells the client it was taken out of a room.
+type removedFromRoomData struct {
+	Room   string `json:"room"`
+	Reason string `json:"reason"`
+}
+
+// connectedData tells an event stream client the ID to post its frames to.
+type connectedData struct {
+	ConnectionID string `json:"connectionId"`
+}
+
+// closedData tells an event stream client why the server ended the stream.
+type closedData struct {
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// tokenExpiryData tells the client when its token expires.
+type tokenExpiryData struct {
+	ExpiresAt time.Time `json:"expiresAt,omitzero"`
+}
+
+// directMessage is a payload for a single connection.
+type directMessage struct {
+	client  Subscriber
+	payload any
+}
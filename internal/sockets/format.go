@@ -0,0 +1,176 @@
+package sockets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+// Format is the wire format of the messages written to a subscriber.
+type Format uint8
+
+// Wire formats a websocket client can negotiate through its subprotocols.
+const (
+	FormatJSON Format = iota
+	FormatMsgpack
+	FormatProtobuf
+
+	numFormats
+)
+
+var formatNames = [numFormats]string{
+	FormatJSON:     "json",
+	FormatMsgpack:  "msgpack",
+	FormatProtobuf: "protobuf",
+}
+
+func (f Format) String() string {
+	if f >= numFormats {
+		return fmt.Sprintf("Format(%d)", f)
+	}
+	return formatNames[f]
+}
+
+// messageType is the websocket message type frames of the format are sent as.
+func (f Format) messageType() int {
+	if f == FormatJSON {
+		return websocket.TextMessage
+	}
+	return websocket.BinaryMessage
+}
+
+// negotiateFormat returns the first wire format among the subprotocols
+// offered by a websocket client, and whether one was offered.
+func negotiateFormat(protocols []string) (Format, bool) {
+	for _, protocol := range protocols {
+		for f, name := range formatNames {
+			if protocol == name {
+				return Format(f), true
+			}
+		}
+	}
+	return FormatJSON, false
+}
+
+// payload is a message sent to subscribers, encoded at most once per wire
+// format however many subscribers it fans out to. It is only accessed from
+// the hub loop.
+type payload struct {
+	value     any
+	expiresAt time.Time
+
+	encoded [numFormats][]byte
+	err     [numFormats]error
+}
+
+func newPayload(value any, expiresAt time.Time) *payload {
+	return &payload{value: value, expiresAt: expiresAt}
+}
+
+// encode returns the payload in the format, encoding it on first use.
+func (p *payload) encode(f Format) ([]byte, error) {
+	if p.encoded[f] == nil && p.err[f] == nil {
+		p.encoded[f], p.err[f] = encode(p.value, f)
+	}
+	return p.encoded[f], p.err[f]
+}
+
+// encode marshals a notification or a server event in the format.
+func encode(value any, f Format) ([]byte, error) {
+	switch f {
+	case FormatMsgpack:
+		var buf bytes.Buffer
+		enc := msgpack.NewEncoder(&buf)
+		// Field names are the same as in JSON.
+		enc.SetCustomStructTag("json")
+		enc.UseCompactInts(true)
+		if err := enc.Encode(value); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatProtobuf:
+		frame, err := toSocketFrame(value)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(frame)
+	default:
+		return json.Marshal(value)
+	}
+}
+
+// toSocketFrame converts a notification or a server event to its protobuf
+// frame. Bodies and event data that are not protobuf messages are carried as
+// google.protobuf.Value and Struct, through their JSON form.
+func toSocketFrame(value any) (*pb.SocketFrame, error) {
+	switch v := value.(type) {
+	case Message:
+		body, err := toAny(v.MessageBody)
+		if err != nil {
+			return nil, fmt.Errorf("message body: %w", err)
+		}
+		notification := &pb.Message{
+			Type:     v.Type.ToProtoEnum(),
+			EntityId: v.EntityID,
+			Message:  body,
+		}
+		if !v.ExpiresAt.IsZero() {
+			notification.ExpiresAt = timestamppb.New(v.ExpiresAt)
+		}
+		return &pb.SocketFrame{Frame: &pb.SocketFrame_Notification{Notification: notification}}, nil
+	case Event:
+		event := &pb.SocketEvent{Event: v.Event}
+		if v.Data != nil {
+			var data map[string]any
+			if err := jsonRoundTrip(v.Data, &data); err != nil {
+				return nil, fmt.Errorf("event data: %w", err)
+			}
+			var err error
+			if event.Data, err = structpb.NewStruct(data); err != nil {
+				return nil, fmt.Errorf("event data: %w", err)
+			}
+		}
+		return &pb.SocketFrame{Frame: &pb.SocketFrame_Event{Event: event}}, nil
+	default:
+		return nil, fmt.Errorf("no protobuf frame for %T", value)
+	}
+}
+
+// toAny wraps a message body in an Any, as it is sent over gRPC.
+func toAny(body any) (*anypb.Any, error) {
+	switch v := body.(type) {
+	case nil:
+		return nil, nil
+	case *anypb.Any:
+		return v, nil
+	case proto.Message:
+		return anypb.New(v)
+	}
+	var data any
+	if err := jsonRoundTrip(body, &data); err != nil {
+		return nil, err
+	}
+	value, err := structpb.NewValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return anypb.New(value)
+}
+
+func jsonRoundTrip(in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
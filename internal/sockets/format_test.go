@@ -0,0 +1,178 @@
+package sockets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+	pb "github.com/yiannis54/go-socket-server/notificationspb"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	format, ok := negotiateFormat([]string{"bearer.abc", "protobuf", "msgpack"})
+	assert.True(t, ok)
+	assert.Equal(t, FormatProtobuf, format)
+
+	format, ok = negotiateFormat([]string{"bearer.abc"})
+	assert.False(t, ok)
+	assert.Equal(t, FormatJSON, format)
+}
+
+func TestPayload_encode(t *testing.T) {
+	expiresAt := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+	message := Message{Type: TypeInfo, EntityID: "order-1", MessageBody: map[string]any{"status": "shipped"}, ExpiresAt: expiresAt}
+	p := newPayload(message, expiresAt)
+
+	t.Run("should encode once per format", func(t *testing.T) {
+		first, err := p.encode(FormatJSON)
+		require.NoError(t, err)
+		second, err := p.encode(FormatJSON)
+		require.NoError(t, err)
+		assert.Same(t, &first[0], &second[0])
+		assert.Nil(t, p.encoded[FormatMsgpack])
+	})
+
+	t.Run("should encode msgpack with the JSON field names", func(t *testing.T) {
+		data, err := p.encode(FormatMsgpack)
+		require.NoError(t, err)
+		var got map[string]any
+		require.NoError(t, msgpack.Unmarshal(data, &got))
+		assert.Equal(t, "info", got["type"])
+		assert.Equal(t, "order-1", got["entityId"])
+		assert.Equal(t, map[string]any{"status": "shipped"}, got["message"])
+		assert.Equal(t, expiresAt, got["expiresAt"].(time.Time).UTC())
+
+		data, err = newPayload(Message{Type: TypeInfo}, time.Time{}).encode(FormatMsgpack)
+		require.NoError(t, err)
+		got = nil
+		require.NoError(t, msgpack.Unmarshal(data, &got))
+		assert.NotContains(t, got, "expiresAt")
+	})
+
+	t.Run("should leave out a missing expiry", func(t *testing.T) {
+		message := Message{Type: TypeInfo, EntityID: "order-1", MessageBody: "shipped"}
+		p := newPayload(message, time.Time{})
+
+		data, err := p.encode(FormatJSON)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "expiresAt")
+		var fromJSON Message
+		require.NoError(t, json.Unmarshal(data, &fromJSON))
+		assert.Equal(t, message, fromJSON)
+
+		data, err = p.encode(FormatMsgpack)
+		require.NoError(t, err)
+		var fields map[string]any
+		require.NoError(t, msgpack.Unmarshal(data, &fields))
+		assert.NotContains(t, fields, "expiresAt")
+		var fromMsgpack Message
+		dec := msgpack.NewDecoder(bytes.NewReader(data))
+		dec.SetCustomStructTag("json")
+		require.NoError(t, dec.Decode(&fromMsgpack))
+		assert.Equal(t, message, fromMsgpack)
+	})
+
+	t.Run("should round-trip the expiry through msgpack", func(t *testing.T) {
+		for _, expiresAt := range []time.Time{{}, expiresAt} {
+			message := Message{Type: TypeInfo, EntityID: "order-1", MessageBody: "shipped", ExpiresAt: expiresAt}
+			data, err := newPayload(message, expiresAt).encode(FormatMsgpack)
+			require.NoError(t, err)
+
+			var got Message
+			dec := msgpack.NewDecoder(bytes.NewReader(data))
+			dec.SetCustomStructTag("json")
+			require.NoError(t, dec.Decode(&got))
+			assert.True(t, expiresAt.Equal(got.ExpiresAt), "expiresAt %v, got %v", expiresAt, got.ExpiresAt)
+			assert.Equal(t, expiresAt.IsZero(), got.ExpiresAt.IsZero())
+			got.ExpiresAt = expiresAt
+			assert.Equal(t, message, got)
+		}
+	})
+
+	t.Run("should encode protobuf frames", func(t *testing.T) {
+		data, err := p.encode(FormatProtobuf)
+		require.NoError(t, err)
+		var frame pb.SocketFrame
+		require.NoError(t, proto.Unmarshal(data, &frame))
+		notification := frame.GetNotification()
+		require.NotNil(t, notification)
+		assert.Equal(t, pb.MessageType_TYPE_INFO, notification.GetType())
+		assert.Equal(t, "order-1", notification.GetEntityId())
+		assert.Equal(t, expiresAt, notification.GetExpiresAt().AsTime())
+
+		var body structpb.Value
+		require.NoError(t, notification.GetMessage().UnmarshalTo(&body))
+		assert.Equal(t, "shipped", body.GetStructValue().GetFields()["status"].GetStringValue())
+
+		data, err = encode(Event{Event: eventAddedToRoom, Data: addedToRoomData{Room: "orders"}}, FormatProtobuf)
+		require.NoError(t, err)
+		require.NoError(t, proto.Unmarshal(data, &frame))
+		assert.Equal(t, eventAddedToRoom, frame.GetEvent().GetEvent())
+		assert.Equal(t, "orders", frame.GetEvent().GetData().GetFields()["room"].GetStringValue())
+	})
+}
+
+func TestServeWs_Format(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDContextKey, "user")
+		ctx = context.WithValue(ctx, middleware.SubprotocolContextKey, "bearer.abc")
+		ServeWs(hub, w, r.WithContext(ctx))
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	t.Run("should send binary frames in the negotiated format", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{"bearer.abc", "protobuf"}}
+		ws, res, err := dialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		res.Body.Close()
+		assert.Equal(t, "protobuf", ws.Subprotocol())
+
+		require.Eventually(t, func() bool {
+			var sub Subscriber
+			_ = hub.call(ctx, func() { sub = hub.GetUser("", "user") })
+			return sub != nil
+		}, time.Second, 10*time.Millisecond)
+		hub.Private <- &MessageWithUser{Message: Message{Type: TypeInfo, EntityID: "order-1"}, UserID: "user"}
+
+		_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+		messageType, data, err := ws.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.BinaryMessage, messageType)
+		var frame pb.SocketFrame
+		require.NoError(t, proto.Unmarshal(data, &frame))
+		assert.Equal(t, "order-1", frame.GetNotification().GetEntityId())
+	})
+
+	t.Run("should echo the token subprotocol without format", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{"bearer.abc"}}
+		ws, res, err := dialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		res.Body.Close()
+		assert.Equal(t, "bearer.abc", ws.Subprotocol())
+	})
+}
@@ -2,20 +2,30 @@ package sockets
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/middleware"
 )
 
 // Hub is a struct that holds all the clients and the messages that are sent to them.
 type Hub struct {
-	// Registered clients.
-	clients map[*Client]struct{}
+	// Registered subscribers of every tenant.
+	clients map[Subscriber]*member
+
+	// Registered subscribers by connection ID, see connection.
+	connections map[string]Subscriber
 
-	// map of ws client per user id.
-	users map[string]*Client
+	// Session clients closed while no request served them, by connection
+	// ID, kept for their peer to resume and receive the closed event.
+	ended map[string]*Client
 
-	// map of rooms for events notifications.
-	rooms map[string]map[*Client]struct{}
+	// users and rooms of each tenant.
+	namespaces map[string]*namespace
 
 	// Inbound messages from broadcasting to clients.
 	Broadcast chan *MessageWithRoom
@@ -23,30 +33,84 @@ type Hub struct {
 	// Inbound messages from private messaging to clients.
 	Private chan *MessageWithUser
 
-	// Register requests from the clients.
-	register chan *Client
+	// Inbound batches of messages to several users and rooms.
+	Batch chan *MessageBatch
 
-	// Unregister requests from clients.
-	unregister chan *Client
+	// Register requests from the clients.
+	register chan *member
 
 	// channels for incoming register/unregister room subscriptions.
 	registerRoom   chan *Subscription
 	unregisterRoom chan *Subscription
+
+	// Payloads for a single connection, e.g. server events.
+	direct chan *directMessage
+
+	// Functions run on the hub loop, see call.
+	calls chan func()
+
+	// done is closed once the hub stopped.
+	done chan struct{}
+
+	// draining is closed by Drain, ending the session requests in flight.
+	draining  chan struct{}
+	drainOnce sync.Once
+
+	// policy holds the limits and origins in force, replaced by Reload.
+	policy atomic.Pointer[livePolicy]
+
+	// Accepted connections, counted outside of the hub loop.
+	conns *connLimiter
+
+	auth AuthOptions
+
+	// Templates of the rooms clients join on connect.
+	autoJoin []RoomTemplate
+
+	compression Compression
+
+	transport Transport
 }
 
+// ErrHubStopped is returned for messages and calls which reach the hub once it stopped.
+var ErrHubStopped = errors.New("sockets: hub stopped")
+
+// Reasons a room subscription is rejected.
+var (
+	errClientGone           = errors.New("client_gone")
+	errTooManySubscriptions = errors.New("too_many_subscriptions")
+	errTooManyRooms         = errors.New("too_many_rooms")
+)
+
 // NewHub returns a new socket Hub.
-func NewHub() *Hub {
-	return &Hub{
+func NewHub(opts ...Option) *Hub {
+	h := &Hub{
 		Broadcast:      make(chan *MessageWithRoom),
 		Private:        make(chan *MessageWithUser),
-		register:       make(chan *Client),
-		unregister:     make(chan *Client),
+		Batch:          make(chan *MessageBatch),
+		register:       make(chan *member),
 		registerRoom:   make(chan *Subscription),
 		unregisterRoom: make(chan *Subscription),
-		clients:        make(map[*Client]struct{}),
-		users:          make(map[string]*Client),
-		rooms:          make(map[string]map[*Client]struct{}),
+		clients:        make(map[Subscriber]*member),
+		connections:    make(map[string]Subscriber),
+		ended:          make(map[string]*Client),
+		namespaces:     make(map[string]*namespace),
+		direct:         make(chan *directMessage),
+		calls:          make(chan func()),
+		done:           make(chan struct{}),
+		draining:       make(chan struct{}),
+		conns:          newConnLimiter(ConnectionLimits{}),
+		transport:      defaultTransport,
+		auth: AuthOptions{
+			Validator:     middleware.ValidateToken,
+			ExpiryWarning: defaultExpiryWarning,
+		},
+	}
+	h.Reload(Policy{})
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // Run starts a hub that listens for incoming messages.
@@ -56,29 +120,28 @@ func NewHub() *Hub {
 func (h *Hub) Run(ctx context.Context) {
 	for {
 		select {
-		case client := <-h.register:
-			h.clients[client] = struct{}{}
-			h.users[client.ID] = client
-		case client := <-h.unregister:
-			if _, ok := h.clients[client]; ok {
-				h.unRegisterClient(client)
-			}
+		case m := <-h.register:
+			h.registerClient(m)
 		case subscription := <-h.registerRoom:
-			if h.rooms[subscription.Room] == nil {
-				h.rooms[subscription.Room] = make(map[*Client]struct{})
+			if err := h.enterRoom(subscription); err != nil {
+				h.rejectSubscription(subscription, err)
 			}
-			h.rooms[subscription.Room][subscription.client] = struct{}{}
 		case subscription := <-h.unregisterRoom:
-			if h.rooms[subscription.Room] != nil {
-				delete(h.rooms[subscription.Room], subscription.client)
-				if len(h.rooms[subscription.Room]) == 0 {
-					delete(h.rooms, subscription.Room)
-				}
+			if m, ok := h.clients[subscription.client]; ok && m.isPinned(subscription.Room) {
+				h.rejectSubscription(subscription, errRoomPinned)
+			} else {
+				h.leaveRoom(subscription.Room, subscription.client)
 			}
 		case messageWithRoom := <-h.Broadcast:
 			h.handleBroadcastMessage(messageWithRoom)
 		case messageWithUser := <-h.Private:
 			h.handlePrivateMessage(messageWithUser)
+		case batch := <-h.Batch:
+			h.handleBatchMessage(batch)
+		case direct := <-h.direct:
+			h.handleDirectMessage(direct)
+		case fn := <-h.calls:
+			fn()
 		case <-ctx.Done():
 			h.Close()
 			return
@@ -86,100 +149,434 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
-// GetUser returns the hub client data from user id.
-func (h *Hub) GetUser(userID string) *Client {
-	return h.users[userID]
+// GetUser returns the subscriber of a user of the tenant.
+// When the user has several sessions, the most recent one is returned.
+func (h *Hub) GetUser(tenant, userID string) Subscriber {
+	var latest *member
+	for sub := range h.lookupNamespace(tenant).users[userID] {
+		if m := h.clients[sub]; latest == nil || m.registeredAt.After(latest.registeredAt) {
+			latest = m
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return latest.sub
 }
 
-func (h *Hub) unRegisterClient(client *Client) {
-	for roomName := range h.rooms {
-		delete(h.rooms[roomName], client)
-		if len(h.rooms[roomName]) == 0 {
-			delete(h.rooms, roomName)
+func (h *Hub) registerClient(m *member) {
+	ns := h.namespace(m.tenant)
+	m.ns = ns
+	h.clients[m.sub] = m
+	h.connections[m.sub.ConnectionID()] = m.sub
+	ns.clients[m.sub] = struct{}{}
+	metrics.AddTenant(m.tenant, metrics.TenantConnections, 1)
+	if m.userID != "" {
+		if ns.users[m.userID] == nil {
+			ns.users[m.userID] = make(map[Subscriber]struct{})
 		}
+		ns.users[m.userID][m.sub] = struct{}{}
 	}
-	delete(h.users, client.ID)
-	delete(h.clients, client)
-	close(client.send)
+	h.joinAssignedRooms(m)
 }
 
-func (h *Hub) handlePrivateMessage(messageWithUser *MessageWithUser) {
-	client, ok := h.users[messageWithUser.UserID]
+// joinRoom subscribes a registered subscriber to a room of its tenant, within
+// the subscription limits. The rooms limit applies to each tenant.
+func (h *Hub) joinRoom(room string, sub Subscriber) error {
+	// The subscriber may have been unregistered while the subscription was queued.
+	m, ok := h.clients[sub]
+	if !ok {
+		return errClientGone
+	}
+	ns := m.ns
+	if _, ok := m.rooms[room]; ok {
+		return nil
+	}
+	limits := h.policy.Load().SubscriptionLimits
+	if limits.PerClient > 0 && len(m.rooms) >= limits.PerClient {
+		return errTooManySubscriptions
+	}
+
+	if ns.rooms[room] == nil {
+		if limits.Rooms > 0 && len(ns.rooms) >= limits.Rooms {
+			return errTooManyRooms
+		}
+		ns.rooms[room] = make(map[Subscriber]struct{})
+		ns.roomIndex.insert(room)
+	}
+	ns.rooms[room][sub] = struct{}{}
+	m.rooms[room] = struct{}{}
+
+	return nil
+}
+
+// enterRoom subscribes a client to the room it asked for, unless the room is
+// reserved to other clients.
+func (h *Hub) enterRoom(subscription *Subscription) error {
+	m, ok := h.clients[subscription.client]
+	if !ok {
+		return errClientGone
+	}
+	if h.reserved(subscription.Room, m.pinned) {
+		return errRoomReserved
+	}
+	return h.joinRoom(subscription.Room, subscription.client)
+}
+
+func (h *Hub) leaveRoom(room string, sub Subscriber) {
+	m, ok := h.clients[sub]
 	if !ok {
-		log.Println("sockets: no client to send private message")
 		return
 	}
-	message, err := json.Marshal(messageWithUser.Message)
-	if err != nil {
-		log.Printf("sockets: could not marshal private message: %v", err)
+	delete(m.rooms, room)
+	ns := m.ns
+	if ns.rooms[room] == nil {
+		return
+	}
+	delete(ns.rooms[room], sub)
+	if len(ns.rooms[room]) == 0 {
+		delete(ns.rooms, room)
+		ns.roomIndex.remove(room)
+	}
+}
+
+// roomMembers returns the subscribers of every room of the namespace matching
+// the given room name or pattern. A subscriber of several matching rooms is
+// returned once.
+func (ns *namespace) roomMembers(room string) map[Subscriber]struct{} {
+	members := make(map[Subscriber]struct{})
+	for _, name := range ns.roomIndex.match(room) {
+		for client := range ns.rooms[name] {
+			members[client] = struct{}{}
+		}
+	}
+	return members
+}
+
+// rejectSubscription tells the client why it could not enter a room.
+func (h *Hub) rejectSubscription(subscription *Subscription, err error) {
+	if errors.Is(err, errClientGone) {
 		return
 	}
+	tenant, _ := subscription.client.User()
+	metrics.RejectedSubscriptions.Add(err.Error(), 1)
+	metrics.AddTenant(tenant, metrics.TenantRejectedSubscriptions, 1)
+	h.handleDirectMessage(&directMessage{
+		client: subscription.client,
+		payload: Event{
+			Event: eventSubscriptionRejected,
+			Data: subscriptionRejectedData{
+				Room:   subscription.Room,
+				Reason: err.Error(),
+			},
+		},
+	})
+}
+
+func (h *Hub) unRegisterClient(sub Subscriber) {
+	m := h.clients[sub]
+	for roomName := range m.rooms {
+		h.leaveRoom(roomName, sub)
+	}
+	ns := m.ns
+	if sessions, ok := ns.users[m.userID]; ok {
+		delete(sessions, sub)
+		if len(sessions) == 0 {
+			delete(ns.users, m.userID)
+		}
+	}
+	delete(ns.clients, sub)
+	h.releaseNamespace(ns)
+	delete(h.clients, sub)
+	delete(h.connections, sub.ConnectionID())
+	metrics.AddTenant(m.tenant, metrics.TenantConnections, -1)
+}
+
+// release unregisters a client whose connection or session ended and frees
+// its connection slot. It does not block once the hub stopped.
+func (h *Hub) release(client *Client) {
 	select {
-	case client.send <- message:
-	default:
-		h.unRegisterClient(client)
+	case h.calls <- func() {
+		if _, ok := h.clients[client]; ok {
+			h.unRegisterClient(client)
+		}
+		delete(h.ended, client.ConnID)
+	}:
+	case <-h.done:
+	}
+	h.conns.release(client)
+}
+
+// enqueue sends v to the hub loop on ch, one of the channels of the hub
+// clients, unless the hub stopped. It reports whether v was sent.
+func enqueue[T any](h *Hub, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-h.done:
+		return false
+	}
+}
+
+// publish sends v to the hub loop on ch, one of its inbound message channels,
+// unless the hub stopped or the context is done.
+func publish[T any](ctx context.Context, h *Hub, ch chan<- T, v T) error {
+	select {
+	case ch <- v:
+		return nil
+	case <-h.done:
+		return ErrHubStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SendBroadcast hands a message to the subscribers of its room, or of its
+// tenant when no room is set.
+func (h *Hub) SendBroadcast(ctx context.Context, message *MessageWithRoom) error {
+	return publish(ctx, h, h.Broadcast, message)
+}
+
+// SendPrivate hands a message to the sessions of its user.
+func (h *Hub) SendPrivate(ctx context.Context, message *MessageWithUser) error {
+	return publish(ctx, h, h.Private, message)
+}
+
+// SendBatch hands a batch to the hub, whose delivery counts are then read
+// with its Report method.
+func (h *Hub) SendBatch(ctx context.Context, batch *MessageBatch) error {
+	return publish(ctx, h, h.Batch, batch)
+}
+
+// send delivers a message to the subscriber, in its wire format, without
+// blocking. A subscriber that cannot keep up is unregistered and closed, and
+// the message dropped.
+func (h *Hub) send(sub Subscriber, message *payload) bool {
+	data, err := message.encode(sub.Format())
+	if err != nil {
+		slog.Error("sockets: could not encode message", "format", sub.Format(), "err", err)
+		return false
+	}
+	if sub.Deliver(data, message.expiresAt) {
+		return true
+	}
+	tenant, _ := sub.User()
+	metrics.DroppedMessages.Add(metrics.DropSlowConsumer, 1)
+	metrics.AddTenant(tenant, metrics.TenantDroppedMessages, 1)
+	h.unRegisterClient(sub)
+	h.closeSubscriber(sub, closeRequest{code: closeSlowConsumer, text: "slow consumer"}, metrics.CloseSlowConsumer)
+	return false
+}
+
+// closeSubscriber asks a subscriber to end and counts the reason. Session
+// clients no request serves are unregistered at once rather than once their
+// window passed, see endSession.
+func (h *Hub) closeSubscriber(sub Subscriber, req closeRequest, reason string) {
+	metrics.ClosedConnections.Add(reason, 1)
+	if client, ok := sub.(*Client); ok && client.session != nil && client.endSession(req) {
+		if _, ok := h.clients[sub]; ok {
+			h.unRegisterClient(sub)
+		}
+		h.ended[client.ConnID] = client
+		h.conns.release(client)
+		return
+	}
+	sub.Close(req.code, req.text)
+}
+
+// dropExpired reports whether the message expired and counts the drop.
+func dropExpired(tenant string, message Message) bool {
+	if !message.Expired(time.Now()) {
+		return false
+	}
+	metrics.DroppedMessages.Add(metrics.DropExpired, 1)
+	metrics.AddTenant(tenant, metrics.TenantDroppedMessages, 1)
+	return true
+}
+
+func (h *Hub) handlePrivateMessage(messageWithUser *MessageWithUser) {
+	if dropExpired(messageWithUser.Tenant, messageWithUser.Message) {
+		return
+	}
+	sessions, ok := h.lookupNamespace(messageWithUser.Tenant).users[messageWithUser.UserID]
+	if !ok {
+		slog.Debug("sockets: no client to send private message", "user", messageWithUser.UserID)
+		return
+	}
+	message := newPayload(messageWithUser.Message, messageWithUser.ExpiresAt)
+	for client := range sessions {
+		h.send(client, message)
 	}
 }
 
 //nolint:cyclop // TODO: reduce cyclomatic complexity.
 func (h *Hub) handleBroadcastMessage(messageWithRoom *MessageWithRoom) {
-	message, err := json.Marshal(messageWithRoom.Message)
-	if err != nil {
-		log.Printf("sockets: could not marshal broadcast message: %v", err)
+	if dropExpired(messageWithRoom.Tenant, messageWithRoom.Message) {
 		return
 	}
+	message := newPayload(messageWithRoom.Message, messageWithRoom.ExpiresAt)
+	ns := h.lookupNamespace(messageWithRoom.Tenant)
 
-	// if room not passed, send to all subscribers.
+	// if room not passed, send to all subscribers of the tenant.
 	if messageWithRoom.RoomName == nil {
-		for client := range h.clients {
-			select {
-			case client.send <- message:
-			default:
-				h.unRegisterClient(client)
-			}
+		for client := range ns.clients {
+			h.send(client, message)
 		}
 		return
 	}
 
-	room, ok := h.rooms[*messageWithRoom.RoomName]
-	if !ok {
-		log.Printf("sockets: room not found or noone in room: %v", messageWithRoom.RoomName)
+	if err := validateRoomName(*messageWithRoom.RoomName); err != nil {
+		slog.Warn("sockets: could not broadcast to room", "room", *messageWithRoom.RoomName, "err", err)
+		return
+	}
+
+	room := ns.roomMembers(*messageWithRoom.RoomName)
+	if len(room) == 0 {
+		slog.Debug("sockets: room not found or noone in room", "room", *messageWithRoom.RoomName)
+		return
+	}
+
+	for client := range room {
+		h.send(client, message)
+	}
+}
+
+//nolint:cyclop // TODO: reduce cyclomatic complexity.
+func (h *Hub) handleBatchMessage(batch *MessageBatch) {
+	report := NewDeliveryReport()
+	defer func() {
+		if batch.report != nil {
+			batch.report <- report
+		}
+	}()
+
+	if dropExpired(batch.Tenant, batch.Message) {
 		return
 	}
+	message := newPayload(batch.Message, batch.ExpiresAt)
+	ns := h.lookupNamespace(batch.Tenant)
+
+	// delivered tracks the outcome per client so that a client matched by
+	// several targets receives the message once.
+	delivered := make(map[Subscriber]bool)
+	deliver := func(client Subscriber) bool {
+		if ok, seen := delivered[client]; seen {
+			return ok
+		}
+		delivered[client] = h.send(client, message)
+		return delivered[client]
+	}
+
+	if batch.Broadcast {
+		for client := range ns.clients {
+			if deliver(client) {
+				report.Broadcast++
+			}
+		}
+	}
 
-	if len(room) > 0 {
-		for client := range room {
-			select {
-			case client.send <- message:
-			default:
-				h.unRegisterClient(client)
+	for _, userID := range batch.UserIDs {
+		count := 0
+		for client := range ns.users[userID] {
+			if deliver(client) {
+				count++
 			}
 		}
+		report.Users[userID] = count
+	}
+
+	for _, room := range batch.Rooms {
+		count := 0
+		if err := validateRoomName(room); err != nil {
+			slog.Warn("sockets: could not batch to room", "room", room, "err", err)
+		} else {
+			for client := range ns.roomMembers(room) {
+				if deliver(client) {
+					count++
+				}
+			}
+		}
+		report.Rooms[room] = count
 	}
 }
 
-// Close removes all map elements and closes hub channels.
+func (h *Hub) handleDirectMessage(direct *directMessage) {
+	// The client may have been unregistered while the payload was queued.
+	if _, ok := h.clients[direct.client]; !ok {
+		return
+	}
+	h.send(direct.client, newPayload(direct.payload, time.Time{}))
+}
+
+// RevokeUser closes every connection of a user of the tenant whose token was
+// revoked and returns the number of connections closed.
+func (h *Hub) RevokeUser(ctx context.Context, tenant, userID string) (int, error) {
+	var n int
+	err := h.call(ctx, func() {
+		n = h.disconnectUser(tenant, userID, closeRequest{code: closeTokenRevoked, text: "token revoked"}, metrics.CloseTokenRevoked)
+	})
+	return n, err
+}
+
+// disconnectUser asks every session of a user to close and returns their number.
+func (h *Hub) disconnectUser(tenant, userID string, req closeRequest, reason string) int {
+	sessions := h.lookupNamespace(tenant).users[userID]
+	n := len(sessions)
+	for sub := range sessions {
+		h.closeSubscriber(sub, req, reason)
+	}
+	return n
+}
+
+// call runs fn on the hub loop and waits for it to return, so that fn can
+// safely read and change the hub state.
+func (h *Hub) call(ctx context.Context, fn func()) error {
+	done := make(chan struct{})
+	select {
+	case h.calls <- func() { fn(); close(done) }:
+	case <-h.done:
+		return ErrHubStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-done
+	return nil
+}
+
+// Drain ends the event streams and long polls in flight, telling their peers
+// the server is shutting down, as an http.Server shutting down waits for them.
+// Register it with http.Server.RegisterOnShutdown. Websocket connections are
+// hijacked, the server does not wait for them.
+func (h *Hub) Drain() {
+	h.drainOnce.Do(func() { close(h.draining) })
+}
+
+// Close removes all map elements. Sends to the hub fail from then on.
 func (h *Hub) Close() {
-	for room, clients := range h.rooms {
-		for client := range clients {
-			delete(clients, client)
+	close(h.done)
+
+	for tenant, ns := range h.namespaces {
+		for room, clients := range ns.rooms {
+			for client := range clients {
+				delete(clients, client)
+			}
+
+			delete(ns.rooms, room)
 		}
+		ns.roomIndex = newRoomTrie()
 
-		delete(h.rooms, room)
-	}
+		for user := range ns.users {
+			delete(ns.users, user)
+		}
 
-	for user := range h.users {
-		delete(h.users, user)
+		delete(h.namespaces, tenant)
 	}
 
 	for client := range h.clients {
 		delete(h.clients, client)
 	}
+	clear(h.connections)
 
-	close(h.unregisterRoom)
-	close(h.registerRoom)
-	close(h.unregister)
-	close(h.register)
-	close(h.Private)
-	close(h.Broadcast)
+	// The channels are left open: senders select on done, and a send racing
+	// their closing would panic.
 }
@@ -3,6 +3,7 @@ package sockets
 import (
 	"context"
 	"encoding/json"
+	"expvar"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +15,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/yiannis54/go-socket-server/internal/metrics"
 	"github.com/yiannis54/go-socket-server/internal/middleware"
 )
 
@@ -78,43 +80,43 @@ func (suite *SocketsTestSuite) TearDownTest() {
 
 func (suite *SocketsTestSuite) TestHub_RegisterUnregisterRoom() {
 	suite.Assert().Len(suite.hub.clients, 1)
-	suite.Assert().Len(suite.hub.rooms, 0)
+	suite.Assert().Len(suite.hub.lookupNamespace("").rooms, 0)
 
 	suite.Run("should fail to enter room with bad json", func() {
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(`{"action": "`)))
 		time.Sleep(100 * time.Millisecond)
-		suite.Assert().Len(suite.hub.rooms, 0)
+		suite.Assert().Len(suite.hub.lookupNamespace("").rooms, 0)
 	})
 
 	suite.Run("should fail to enter room with no incoming room info", func() {
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q}`, subscribeAction))))
 		time.Sleep(100 * time.Millisecond)
-		suite.Assert().Len(suite.hub.rooms, 0)
+		suite.Assert().Len(suite.hub.lookupNamespace("").rooms, 0)
 	})
 
 	suite.Run("should fail to enter room with invalid action", func() {
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(`{"action": "fly", "room": "2023-06-08"}`)))
 		time.Sleep(100 * time.Millisecond)
-		suite.Assert().Len(suite.hub.rooms, 0)
+		suite.Assert().Len(suite.hub.lookupNamespace("").rooms, 0)
 	})
 
 	suite.Run("should enter and leave room correctly", func() {
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "2023-06-08"}`, subscribeAction))))
 		time.Sleep(100 * time.Millisecond)
-		suite.Assert().Len(suite.hub.rooms, 1)
+		suite.Assert().Len(suite.hub.lookupNamespace("").rooms, 1)
 
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "2023-06-08"}`, unsubscribeAction))))
 		time.Sleep(100 * time.Millisecond)
-		suite.Assert().Len(suite.hub.rooms, 0)
+		suite.Assert().Len(suite.hub.lookupNamespace("").rooms, 0)
 	})
 }
 
 func (suite *SocketsTestSuite) TestHub_unRegisterClient() {
 	time.Sleep(200 * time.Millisecond)
-	suite.Require().NotNil(suite.hub.GetUser(suite.userID))
-	suite.hub.unRegisterClient(suite.hub.users[suite.userID])
+	suite.Require().NotNil(suite.hub.GetUser("", suite.userID))
+	suite.hub.unRegisterClient(suite.hub.GetUser("", suite.userID))
 	time.Sleep(200 * time.Millisecond)
-	suite.Assert().Nil(suite.hub.GetUser(suite.userID))
+	suite.Assert().Nil(suite.hub.GetUser("", suite.userID))
 }
 
 func (suite *SocketsTestSuite) TestHub_handlePrivateMessage() {
@@ -173,7 +175,7 @@ func (suite *SocketsTestSuite) TestHub_handleBroadcastMessage_Room() {
 	suite.Run("should receive message if in room", func() {
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "2023-01-01"}`, subscribeAction))))
 		time.Sleep(100 * time.Millisecond)
-		suite.Require().Len(suite.hub.rooms, 1)
+		suite.Require().Len(suite.hub.lookupNamespace("").rooms, 1)
 
 		suite.hub.handleBroadcastMessage(msg)
 
@@ -190,7 +192,7 @@ func (suite *SocketsTestSuite) TestHub_handleBroadcastMessage_Room() {
 	suite.Run("should not receive message if not in room", func() {
 		suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "2023-01-01"}`, unsubscribeAction))))
 		time.Sleep(100 * time.Millisecond)
-		suite.Require().Len(suite.hub.rooms, 0)
+		suite.Require().Len(suite.hub.lookupNamespace("").rooms, 0)
 
 		suite.hub.handleBroadcastMessage(msg)
 
@@ -199,3 +201,119 @@ func (suite *SocketsTestSuite) TestHub_handleBroadcastMessage_Room() {
 		suite.Require().Error(err)
 	})
 }
+
+func (suite *SocketsTestSuite) TestHub_handleBroadcastMessage_WildcardRoom() {
+	roomName := "store.42.orders.7"
+	msg := NewRoomMessage(TypeInfo, "order-7", roomName, "Order shipped!")
+
+	suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "store.42.#"}`, subscribeAction))))
+	suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "store.*.orders.7"}`, subscribeAction))))
+	time.Sleep(100 * time.Millisecond)
+	rooms, err := suite.hub.Rooms(context.Background(), "")
+	suite.Require().NoError(err)
+	suite.Require().Len(rooms, 2)
+
+	suite.Run("should receive message once when matching several subscriptions", func() {
+		suite.hub.Broadcast <- msg
+
+		suite.Require().NoError(suite.ws.SetReadDeadline(time.Now().Add(time.Second * 2)))
+		_, incoming, err := suite.ws.ReadMessage()
+		suite.Require().NoError(err)
+
+		incomingMsg := Message{}
+		suite.Require().NoError(json.Unmarshal(incoming, &incomingMsg))
+		suite.Assert().Equal(msg.Message, incomingMsg)
+
+		suite.Require().NoError(suite.ws.SetReadDeadline(time.Now().Add(time.Second)))
+		_, _, err = suite.ws.ReadMessage()
+		suite.Require().Error(err)
+	})
+}
+
+func (suite *SocketsTestSuite) TestHub_handleBatchMessage() {
+	suite.Require().NoError(suite.ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"action": %q, "room": "batch-room"}`, subscribeAction))))
+	time.Sleep(100 * time.Millisecond)
+
+	msg := Message{
+		Type:        TypeInfo,
+		MessageBody: "Hello, batch!",
+	}
+	batch := NewMessageBatch(msg, []string{suite.userID, "unknown-user"}, []string{"batch-room", "empty-room"})
+
+	suite.hub.Batch <- batch
+
+	report, err := batch.Report(context.Background())
+	suite.Require().NoError(err)
+	suite.Assert().Equal(map[string]int{suite.userID: 1, "unknown-user": 0}, report.Users)
+	suite.Assert().Equal(map[string]int{"batch-room": 1, "empty-room": 0}, report.Rooms)
+
+	suite.Require().NoError(suite.ws.SetReadDeadline(time.Now().Add(time.Second * 2)))
+	_, incoming, err := suite.ws.ReadMessage()
+	suite.Require().NoError(err)
+
+	incomingMsg := Message{}
+	suite.Require().NoError(json.Unmarshal(incoming, &incomingMsg))
+	suite.Assert().Equal(msg, incomingMsg)
+
+	suite.Require().NoError(suite.ws.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err = suite.ws.ReadMessage()
+	suite.Require().Error(err)
+}
+
+func expiredCounter() int64 {
+	if v, ok := metrics.DroppedMessages.Get(metrics.DropExpired).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func (suite *SocketsTestSuite) TestHub_ExpiredMessage() {
+	ctx := context.Background()
+	expired := Message{
+		Type:        TypeInfo,
+		MessageBody: "Driver is 1 min away",
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+	fresh := Message{
+		Type:        TypeInfo,
+		MessageBody: "Driver arrived",
+	}
+
+	// Messages are delivered in order, so the fresh message sent after the
+	// expired one must be the first to arrive. A read timeout would break the
+	// connection for the next subtests.
+	assertDropped := func(before int64) {
+		suite.Require().NoError(suite.hub.SendPrivate(ctx, &MessageWithUser{UserID: suite.userID, Message: fresh}))
+
+		suite.Require().NoError(suite.ws.SetReadDeadline(time.Now().Add(time.Second * 2)))
+		_, incoming, err := suite.ws.ReadMessage()
+		suite.Require().NoError(err)
+
+		incomingMsg := Message{}
+		suite.Require().NoError(json.Unmarshal(incoming, &incomingMsg))
+		suite.Assert().Equal(fresh, incomingMsg)
+		suite.Assert().Equal(before+1, expiredCounter())
+	}
+
+	suite.Run("should drop expired private message in hub", func() {
+		before := expiredCounter()
+		suite.Require().NoError(suite.hub.SendPrivate(ctx, &MessageWithUser{UserID: suite.userID, Message: expired}))
+		assertDropped(before)
+	})
+
+	suite.Run("should drop expired broadcast message in hub", func() {
+		before := expiredCounter()
+		suite.Require().NoError(suite.hub.SendBroadcast(ctx, &MessageWithRoom{Message: expired}))
+		assertDropped(before)
+	})
+
+	suite.Run("should drop message expired in send buffer", func() {
+		var sub Subscriber
+		suite.Require().NoError(suite.hub.call(ctx, func() { sub = suite.hub.GetUser("", suite.userID) }))
+		suite.Require().NotNil(sub)
+
+		before := expiredCounter()
+		suite.Require().True(sub.Deliver([]byte(`{"message":"stale"}`), expired.ExpiresAt))
+		assertDropped(before)
+	})
+}
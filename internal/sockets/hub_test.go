@@ -10,20 +10,61 @@ func TestNewHub(t *testing.T) {
 	hub := NewHub()
 	assert.NotNil(t, hub.Broadcast)
 	assert.NotNil(t, hub.Private)
+	assert.NotNil(t, hub.Batch)
 	assert.NotNil(t, hub.register)
-	assert.NotNil(t, hub.unregister)
 	assert.NotNil(t, hub.registerRoom)
 	assert.NotNil(t, hub.unregisterRoom)
 	assert.NotNil(t, hub.clients)
-	assert.NotNil(t, hub.users)
-	assert.NotNil(t, hub.rooms)
+	assert.NotNil(t, hub.namespaces)
 
 	client := &Client{
 		hub: hub,
 		ID:  "abc-xyz",
 	}
-	hub.clients[client] = struct{}{}
-	hub.users["abc-xyz"] = client
-	hub.rooms["public"] = hub.clients
+	ns := hub.namespace("")
+	hub.clients[client] = newMember(client, nil)
+	ns.clients[client] = struct{}{}
+	ns.users["abc-xyz"] = map[Subscriber]struct{}{client: {}}
+	ns.rooms["public"] = ns.clients
+	hub.Close()
+	assert.Empty(t, hub.namespaces)
+}
+
+func TestHub_joinRoom(t *testing.T) {
+	hub := NewHub(WithSubscriptionLimits(SubscriptionLimits{PerClient: 2, Rooms: 3}))
+	first := &Client{hub: hub, ID: "first", send: make(chan outbound, defaultTransport.SendBuffer)}
+	second := &Client{hub: hub, ID: "second", send: make(chan outbound, defaultTransport.SendBuffer)}
+
+	t.Run("should not subscribe unregistered clients", func(t *testing.T) {
+		assert.ErrorIs(t, hub.joinRoom("a", first), errClientGone)
+		assert.Empty(t, hub.lookupNamespace("").rooms)
+	})
+
+	hub.registerClient(newMember(first, nil))
+	hub.registerClient(newMember(second, nil))
+	firstRooms := hub.clients[first].rooms
+
+	t.Run("should cap subscriptions per client", func(t *testing.T) {
+		assert.NoError(t, hub.joinRoom("a", first))
+		assert.NoError(t, hub.joinRoom("b", first))
+		assert.NoError(t, hub.joinRoom("b", first))
+		assert.ErrorIs(t, hub.joinRoom("c", first), errTooManySubscriptions)
+		assert.Len(t, firstRooms, 2)
+	})
+
+	t.Run("should cap the number of rooms", func(t *testing.T) {
+		assert.NoError(t, hub.joinRoom("c", second))
+		assert.ErrorIs(t, hub.joinRoom("d", second), errTooManyRooms)
+		assert.NoError(t, hub.joinRoom("a", second))
+	})
+
+	t.Run("should leave only the rooms of the client on unregister", func(t *testing.T) {
+		hub.unRegisterClient(first)
+		assert.Len(t, hub.lookupNamespace("").rooms, 2)
+		assert.Contains(t, hub.lookupNamespace("").rooms, "a")
+		assert.Contains(t, hub.lookupNamespace("").rooms, "c")
+		assert.Empty(t, firstRooms)
+	})
+
 	hub.Close()
 }
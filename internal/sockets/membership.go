@@ -0,0 +1,125 @@
+package sockets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Reason a client is removed from a room assigned by the server.
+const removedUnsubscribed = "unsubscribed"
+
+// SubscribeUser puts every session of a user of the tenant in the given rooms.
+// The rooms stick to the user, so that sessions connecting later join them as
+// well, until UnsubscribeUser removes them. It returns the number of live sessions.
+func (h *Hub) SubscribeUser(ctx context.Context, tenant, userID string, rooms []string) (int, error) {
+	if err := validateRoomNames(rooms); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := h.call(ctx, func() {
+		ns := h.namespace(tenant)
+		if ns.userRooms[userID] == nil {
+			ns.userRooms[userID] = make(map[string]struct{})
+		}
+		for _, room := range rooms {
+			ns.userRooms[userID][room] = struct{}{}
+		}
+
+		for client := range ns.users[userID] {
+			for _, room := range rooms {
+				if !h.assignRoom(room, client) {
+					break
+				}
+			}
+		}
+		n = len(ns.users[userID])
+	})
+	return n, err
+}
+
+// UnsubscribeUser takes every session of a user of the tenant out of the given
+// rooms and stops new sessions from joining them. It returns the number of
+// live sessions.
+func (h *Hub) UnsubscribeUser(ctx context.Context, tenant, userID string, rooms []string) (int, error) {
+	if err := validateRoomNames(rooms); err != nil {
+		return 0, err
+	}
+
+	var n int
+	err := h.call(ctx, func() {
+		ns := h.lookupNamespace(tenant)
+		for _, room := range rooms {
+			delete(ns.userRooms[userID], room)
+		}
+		if len(ns.userRooms[userID]) == 0 {
+			delete(ns.userRooms, userID)
+		}
+
+		for sub := range ns.users[userID] {
+			m := h.clients[sub]
+			for _, room := range rooms {
+				if _, ok := m.rooms[room]; ok && !m.isPinned(room) {
+					h.removeFromRoom(room, sub, removedUnsubscribed)
+				}
+			}
+		}
+		n = len(ns.users[userID])
+		h.releaseNamespace(ns)
+	})
+	return n, err
+}
+
+// joinAssignedRooms puts a newly registered subscriber in the rooms it was
+// auto-joined to and in the rooms assigned to its user.
+func (h *Hub) joinAssignedRooms(m *member) {
+	rooms := slices.Sorted(maps.Keys(m.pinned))
+	rooms = append(rooms, slices.Sorted(maps.Keys(m.ns.userRooms[m.userID]))...)
+	for _, room := range rooms {
+		if !h.assignRoom(room, m.sub) {
+			return
+		}
+	}
+}
+
+// assignRoom puts a subscriber in a room on behalf of the server and tells it
+// so. It reports whether the subscriber is still registered, as one that
+// cannot keep up with the events is unregistered.
+func (h *Hub) assignRoom(room string, client Subscriber) bool {
+	m, ok := h.clients[client]
+	if !ok {
+		return false
+	}
+	if _, ok := m.rooms[room]; ok {
+		return true
+	}
+	if err := h.joinRoom(room, client); err != nil {
+		h.rejectSubscription(newSubscription(room, client), err)
+	} else {
+		h.handleDirectMessage(&directMessage{
+			client: client,
+			payload: Event{
+				Event: eventAddedToRoom,
+				Data:  addedToRoomData{Room: room},
+			},
+		})
+	}
+	_, ok = h.clients[client]
+	return ok
+}
+
+func validateRoomNames(rooms []string) error {
+	if len(rooms) == 0 {
+		return fmt.Errorf("%w: no room given", ErrInvalidRoomName)
+	}
+	var errs []error
+	for _, room := range rooms {
+		if err := validateRoomName(room); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %q", err, room))
+		}
+	}
+	return errors.Join(errs...)
+}
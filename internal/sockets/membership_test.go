@@ -0,0 +1,131 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestHub_SubscribeUser(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, "member"))
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+
+	dial := func(t *testing.T) *websocket.Conn {
+		t.Helper()
+		ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+		require.NoError(t, err)
+		res.Body.Close()
+		return ws
+	}
+	readEvent := func(t *testing.T, ws *websocket.Conn) Event {
+		t.Helper()
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, incoming, err := ws.ReadMessage()
+		require.NoError(t, err)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(incoming, &event))
+		return event
+	}
+
+	first := dial(t)
+	defer first.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("should reject invalid rooms", func(t *testing.T) {
+		_, err := hub.SubscribeUser(ctx, "", "member", []string{"project..1"})
+		assert.ErrorIs(t, err, ErrInvalidRoomName)
+	})
+
+	t.Run("should put live sessions in the rooms", func(t *testing.T) {
+		n, err := hub.SubscribeUser(ctx, "", "member", []string{"project-123"})
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, eventAddedToRoom, readEvent(t, first).Event)
+	})
+
+	second := dial(t)
+	defer second.Close()
+
+	t.Run("should put later sessions in the rooms", func(t *testing.T) {
+		assert.Equal(t, eventAddedToRoom, readEvent(t, second).Event)
+
+		room, err := hub.Room(ctx, "", "project-123")
+		require.NoError(t, err)
+		assert.Len(t, room.Members, 2)
+	})
+
+	t.Run("should take sessions out of the rooms", func(t *testing.T) {
+		n, err := hub.UnsubscribeUser(ctx, "", "member", []string{"project-123"})
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Equal(t, eventRemovedFromRoom, readEvent(t, first).Event)
+		assert.Equal(t, eventRemovedFromRoom, readEvent(t, second).Event)
+
+		_, err = hub.Room(ctx, "", "project-123")
+		assert.ErrorIs(t, err, ErrRoomNotFound)
+	})
+
+	t.Run("should not put later sessions in removed rooms", func(t *testing.T) {
+		third := dial(t)
+		defer third.Close()
+		time.Sleep(100 * time.Millisecond)
+
+		rooms, err := hub.Rooms(ctx, "")
+		require.NoError(t, err)
+		assert.Empty(t, rooms)
+	})
+}
+
+func TestHub_SubscribeUserSlowConsumer(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer cancel()
+
+	registered := func(t *testing.T, sub Subscriber) bool {
+		t.Helper()
+		var ok bool
+		require.NoError(t, hub.call(ctx, func() { _, ok = hub.clients[sub] }))
+		return ok
+	}
+
+	t.Run("should stop assigning rooms to a subscriber unregistered as slow", func(t *testing.T) {
+		slow := newFakeSubscriber("slow", "member", 0)
+		require.NoError(t, hub.Register(ctx, slow))
+
+		n, err := hub.SubscribeUser(ctx, "", "member", []string{"a", "b"})
+		require.NoError(t, err)
+		assert.Zero(t, n, "the session should be gone")
+		assert.Equal(t, closeSlowConsumer, <-slow.closed)
+		assert.False(t, registered(t, slow))
+	})
+
+	t.Run("should stop joining the assigned rooms of a slow subscriber", func(t *testing.T) {
+		slow := newFakeSubscriber("later", "member", 0)
+		require.NoError(t, hub.Register(ctx, slow))
+		assert.Equal(t, closeSlowConsumer, <-slow.closed)
+		assert.False(t, registered(t, slow))
+	})
+}
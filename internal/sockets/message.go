@@ -1,9 +1,15 @@
 package sockets
 
-// Room subscription actions.
+import (
+	"context"
+	"time"
+)
+
+// Incoming message actions.
 const (
 	subscribeAction   string = "enter"
 	unsubscribeAction string = "leave"
+	reauthAction      string = "reauth"
 )
 
 // Message holds the information of the notification message sent.
@@ -11,33 +17,114 @@ type Message struct {
 	Type        MessageType `json:"type"`
 	EntityID    string      `json:"entityId"`
 	MessageBody any         `json:"message"`
+
+	// ExpiresAt is the optional time after which the message is dropped
+	// instead of being delivered.
+	ExpiresAt time.Time `json:"expiresAt,omitzero" msgpack:"expiresAt,omitempty"`
+}
+
+// Expired reports whether the message has an expiry time before now.
+func (m Message) Expired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && now.After(m.ExpiresAt)
 }
 
 // MessageWithRoom adds a room in the message information sent.
 type MessageWithRoom struct {
 	Message
 	RoomName *string `json:"room"`
+
+	// Tenant scopes the room, and the broadcast when no room is set.
+	Tenant string `json:"-"`
 }
 
 // MessageWithUser adds a user id in the message information sent.
 type MessageWithUser struct {
 	Message
 	UserID string `json:"userId"`
+
+	// Tenant scopes the user ID.
+	Tenant string `json:"-"`
+}
+
+// MessageBatch sends the same message to several users and rooms at once.
+// The hub handles a batch as one unit: the message is marshalled once and
+// every connection receives it at most once, even if matched by several targets.
+type MessageBatch struct {
+	Message
+	UserIDs []string
+	Rooms   []string
+
+	// Broadcast sends the message to every connected client of the tenant
+	// in addition to the listed targets.
+	Broadcast bool
+
+	// Tenant scopes the users, rooms and broadcast of the batch.
+	Tenant string
+
+	report chan *DeliveryReport
+}
+
+// DeliveryReport holds the number of connections a batch was delivered to per target.
+type DeliveryReport struct {
+	Users     map[string]int
+	Rooms     map[string]int
+	Broadcast int
+}
+
+// NewMessageBatch constructs and returns a batch of the message to the given users and rooms.
+func NewMessageBatch(message Message, userIDs, rooms []string) *MessageBatch {
+	return &MessageBatch{
+		Message: message,
+		UserIDs: userIDs,
+		Rooms:   rooms,
+		report:  make(chan *DeliveryReport, 1),
+	}
+}
+
+// Report waits until the hub has handled the batch and returns its delivery counts.
+func (b *MessageBatch) Report(ctx context.Context) (*DeliveryReport, error) {
+	select {
+	case report := <-b.report:
+		return report, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NewDeliveryReport returns an empty delivery report.
+func NewDeliveryReport() *DeliveryReport {
+	return &DeliveryReport{
+		Users: make(map[string]int),
+		Rooms: make(map[string]int),
+	}
+}
+
+// Merge adds the delivery counts of another report to r.
+func (r *DeliveryReport) Merge(other *DeliveryReport) {
+	r.Broadcast += other.Broadcast
+	for user, count := range other.Users {
+		r.Users[user] += count
+	}
+	for room, count := range other.Rooms {
+		r.Rooms[room] += count
+	}
 }
 
-// IncomingSubscription is used as incoming message for changing rooms.
+// IncomingSubscription is used as incoming message for changing rooms and
+// for re-authenticating with a fresh token.
 type IncomingSubscription struct {
 	Action string `json:"action"`
 	Room   string `json:"room"`
+	Token  string `json:"token,omitempty"`
 }
 
 // Subscription is the object sent to hub for handling the room registrations.
 type Subscription struct {
 	Room   string
-	client *Client
+	client Subscriber
 }
 
-func newSubscription(room string, c *Client) *Subscription {
+func newSubscription(room string, c Subscriber) *Subscription {
 	return &Subscription{
 		Room:   room,
 		client: c,
@@ -2,6 +2,7 @@ package sockets
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +40,11 @@ func TestNewMessageWithRoom(t *testing.T) {
 		assert.Equal(t, expected, *NewRoomMessage(TypeInfo, entityID, room, nil))
 	})
 }
+
+func TestMessage_Expired(t *testing.T) {
+	now := time.Now()
+
+	assert.False(t, Message{}.Expired(now))
+	assert.False(t, Message{ExpiresAt: now.Add(time.Minute)}.Expired(now))
+	assert.True(t, Message{ExpiresAt: now.Add(-time.Minute)}.Expired(now))
+}
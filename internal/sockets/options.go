@@ -0,0 +1,85 @@
+package sockets
+
+import "github.com/yiannis54/go-socket-server/internal/ratelimit"
+
+// Option configures a Hub.
+type Option func(*Hub)
+
+// RateLimits restricts the inbound frames read from clients. Each limit applies
+// per connection and, for the User limits, across all connections of a user.
+type RateLimits struct {
+	Frames            ratelimit.Limit
+	UserFrames        ratelimit.Limit
+	Subscriptions     ratelimit.Limit
+	UserSubscriptions ratelimit.Limit
+
+	// MaxViolations is the number of frames over a limit after which the
+	// connection is closed. Zero closes on the first violation.
+	MaxViolations int
+}
+
+// WithRateLimits sets the inbound rate limits of the hub clients.
+func WithRateLimits(limits RateLimits) Option {
+	return func(h *Hub) {
+		p := h.Policy()
+		p.RateLimits = limits
+		h.Reload(p)
+	}
+}
+
+// WithConnectionLimits caps the number of connections accepted by ServeWs.
+func WithConnectionLimits(limits ConnectionLimits) Option {
+	return func(h *Hub) {
+		p := h.Policy()
+		p.ConnectionLimits = limits
+		h.Reload(p)
+	}
+}
+
+// SubscriptionLimits caps room subscriptions. A zero limit is unlimited.
+type SubscriptionLimits struct {
+	// PerClient is the maximum number of rooms a connection can be in.
+	PerClient int
+	// Rooms is the maximum number of rooms with at least one member.
+	Rooms int
+}
+
+// WithSubscriptionLimits caps the room subscriptions handled by the hub.
+func WithSubscriptionLimits(limits SubscriptionLimits) Option {
+	return func(h *Hub) {
+		p := h.Policy()
+		p.SubscriptionLimits = limits
+		h.Reload(p)
+	}
+}
+
+// WithOriginPolicy restricts the origins allowed to connect through ServeWs.
+func WithOriginPolicy(policy OriginPolicy) Option {
+	return func(h *Hub) {
+		p := h.Policy()
+		p.OriginPolicy = policy
+		h.Reload(p)
+	}
+}
+
+// WithAuth sets how tokens of live connections are re-validated and how long
+// before their expiry clients are warned.
+func WithAuth(auth AuthOptions) Option {
+	return func(h *Hub) {
+		if auth.Validator != nil {
+			h.auth.Validator = auth.Validator
+		}
+		if auth.ExpiryWarning > 0 {
+			h.auth.ExpiryWarning = auth.ExpiryWarning
+		}
+		h.auth.TenantClaim = auth.TenantClaim
+	}
+}
+
+// WithAutoJoin puts clients in the rooms expanded from their token claims
+// when they connect. Clients cannot leave these rooms.
+func WithAutoJoin(templates []RoomTemplate) Option {
+	return func(h *Hub) {
+		h.autoJoin = templates
+	}
+}
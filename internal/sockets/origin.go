@@ -0,0 +1,63 @@
+package sockets
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OriginPolicy restricts the origins allowed to open websocket connections,
+// protecting cookie authenticated users from cross-site websocket hijacking.
+type OriginPolicy struct {
+	// Allowed lists the allowed origins, e.g. "https://app.example.com". A
+	// leading "*." in the host allows any subdomain ("https://*.example.com")
+	// and an entry without scheme matches any scheme. "*" allows every origin,
+	// as does an empty list.
+	Allowed []string
+
+	// AllowEmpty accepts requests without an Origin header, as sent by native apps.
+	AllowEmpty bool
+}
+
+// allowed reports whether the origin of a request is allowed.
+func (p OriginPolicy) allowed(origin string) bool {
+	if origin == "" {
+		return p.AllowEmpty || len(p.Allowed) == 0
+	}
+	if len(p.Allowed) == 0 {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+
+	for _, entry := range p.Allowed {
+		if entry == "*" {
+			return true
+		}
+
+		entry = strings.ToLower(entry)
+		if entryScheme, entryHost, ok := strings.Cut(entry, "://"); ok {
+			if entryScheme != scheme {
+				continue
+			}
+			entry = entryHost
+		}
+
+		if matchHost(entry, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHost matches a host against an allowed host, which may start with
+// "*." to allow any subdomain, but not the domain itself.
+func matchHost(allowed, host string) bool {
+	if suffix, ok := strings.CutPrefix(allowed, "*"); ok && strings.HasPrefix(suffix, ".") {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return allowed == host
+}
@@ -0,0 +1,41 @@
+package sockets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOriginPolicy(t *testing.T) {
+	t.Run("should allow every origin without allow-list", func(t *testing.T) {
+		p := OriginPolicy{}
+		assert.True(t, p.allowed("https://evil.example"))
+		assert.True(t, p.allowed(""))
+	})
+
+	p := OriginPolicy{Allowed: []string{"https://app.example.com", "https://*.example.org", "*.example.net:8443"}}
+
+	tests := map[string]bool{
+		"https://app.example.com":      true,
+		"https://APP.example.com":      true,
+		"http://app.example.com":       false,
+		"https://other.example.com":    false,
+		"https://a.example.org":        true,
+		"https://a.b.example.org":      true,
+		"https://example.org":          false,
+		"https://evilexample.org":      false,
+		"http://a.example.net:8443":    true,
+		"https://a.example.net":        false,
+		"null":                         false,
+		"":                             false,
+		"https://app.example.com.evil": false,
+	}
+	for origin, expected := range tests {
+		assert.Equal(t, expected, p.allowed(origin), origin)
+	}
+
+	t.Run("should allow empty origin when enabled", func(t *testing.T) {
+		p.AllowEmpty = true
+		assert.True(t, p.allowed(""))
+	})
+}
@@ -0,0 +1,68 @@
+package sockets
+
+import "github.com/yiannis54/go-socket-server/internal/ratelimit"
+
+// Policy holds the hub settings which can be replaced while it runs.
+type Policy struct {
+	RateLimits         RateLimits
+	ConnectionLimits   ConnectionLimits
+	SubscriptionLimits SubscriptionLimits
+	OriginPolicy       OriginPolicy
+}
+
+// livePolicy is the policy in force, with the limiters shared by all
+// connections of a user.
+type livePolicy struct {
+	Policy
+	userFrames        *ratelimit.Keyed
+	userSubscriptions *ratelimit.Keyed
+}
+
+// Policy returns the policy in force.
+func (h *Hub) Policy() Policy {
+	return h.policy.Load().Policy
+}
+
+// Reload replaces the policy of the hub, without disconnecting its clients.
+// Rate limits apply from the next frame of each client, subscription limits
+// from the next subscription, and connection limits and origins from the next
+// upgrade. Clients over a lowered limit are kept.
+//
+// Limiters whose limit did not change keep their state, so that a reload
+// does not hand out a fresh burst.
+func (h *Hub) Reload(p Policy) {
+	live := &livePolicy{Policy: p}
+	old := h.policy.Load()
+	if old != nil && old.RateLimits.UserFrames == p.RateLimits.UserFrames {
+		live.userFrames = old.userFrames
+	} else {
+		live.userFrames = ratelimit.NewKeyed(p.RateLimits.UserFrames)
+	}
+	if old != nil && old.RateLimits.UserSubscriptions == p.RateLimits.UserSubscriptions {
+		live.userSubscriptions = old.userSubscriptions
+	} else {
+		live.userSubscriptions = ratelimit.NewKeyed(p.RateLimits.UserSubscriptions)
+	}
+
+	h.conns.setLimits(p.ConnectionLimits)
+	h.policy.Store(live)
+}
+
+// refreshPolicy returns the policy in force, renewing the rate limiters of
+// the connection whose limit a reload changed. It must only be called by the
+// reader of the client frames, i.e. the read pump of a websocket or a session
+// request holding the frames lock, or before frames are read.
+func (c *Client) refreshPolicy() *livePolicy {
+	p := c.hub.policy.Load()
+	if p == c.policy {
+		return p
+	}
+	if c.policy == nil || p.RateLimits.Frames != c.policy.RateLimits.Frames {
+		c.frames = ratelimit.NewBucket(p.RateLimits.Frames)
+	}
+	if c.policy == nil || p.RateLimits.Subscriptions != c.policy.RateLimits.Subscriptions {
+		c.subscriptions = ratelimit.NewBucket(p.RateLimits.Subscriptions)
+	}
+	c.policy = p
+	return p
+}
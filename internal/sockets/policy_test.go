@@ -0,0 +1,72 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/ratelimit"
+)
+
+func TestHub_Reload(t *testing.T) {
+	hub := NewHub(WithRateLimits(RateLimits{UserFrames: ratelimit.Limit{Rate: 1, Burst: 5}}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	ws, res, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer ws.Close()
+	res.Body.Close()
+
+	t.Run("should keep the limiters of unchanged limits", func(t *testing.T) {
+		userFrames := hub.policy.Load().userFrames
+		p := hub.Policy()
+		p.RateLimits.Frames = ratelimit.Limit{Rate: 0.001, Burst: 1}
+		p.RateLimits.MaxViolations = 1
+		hub.Reload(p)
+		assert.Same(t, userFrames, hub.policy.Load().userFrames)
+	})
+
+	t.Run("should apply rate limits to connected clients", func(t *testing.T) {
+		for range 2 {
+			require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte("0")))
+		}
+
+		require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+		_, incoming, err := ws.ReadMessage()
+		require.NoError(t, err)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(incoming, &event))
+		assert.Equal(t, eventRateLimited, event.Event)
+	})
+
+	t.Run("should apply origins to the next upgrades", func(t *testing.T) {
+		p := hub.Policy()
+		p.OriginPolicy = OriginPolicy{Allowed: []string{"https://app.example.com"}}
+		hub.Reload(p)
+
+		_, res, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{"https://evil.example.com"}})
+		require.Error(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+}
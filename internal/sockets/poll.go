@@ -0,0 +1,132 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+)
+
+// PollResponse is the answer to a long poll.
+type PollResponse struct {
+	// Session identifies the client to poll again and to post frames to.
+	Session string `json:"session"`
+
+	// Cursor is the position of the last message returned, to send back with
+	// the next poll.
+	Cursor uint64 `json:"cursor"`
+
+	// Messages are the notifications and server events, as a websocket
+	// client receives them.
+	Messages []json.RawMessage `json:"messages"`
+}
+
+// ServePoll answers a long poll of a hub client, for clients that can neither
+// open a websocket nor an event stream. The request is held until messages
+// arrive or the PollTimeout of the hub transport passed. A poll without a
+// known session query parameter registers a new client in the rooms given as
+// room query parameters, which changes them through ServeSessionAction.
+//
+// Messages are kept until a later poll acknowledges them by sending back the
+// cursor it received, so that a lost response does not lose messages.
+func ServePoll(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if !allowOrigin(hub, w, r) {
+		return
+	}
+
+	query := r.URL.Query()
+	var cursor uint64
+	if value := query.Get("cursor"); value != "" {
+		var err error
+		if cursor, err = strconv.ParseUint(value, 10, 64); err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var client *Client
+	if id := query.Get("session"); id != "" {
+		client = hub.sessionClient(r, id)
+	}
+	if client != nil {
+		client.resume(r)
+	} else {
+		var ok bool
+		if client, ok = connectSession(hub, w, r, hub.transport.PollSessionWindow); !ok {
+			return
+		}
+		cursor = 0
+	}
+
+	ctx, done, ok := client.attach(r.Context())
+	if !ok {
+		http.Error(w, "session closed", http.StatusGone)
+		return
+	}
+	events, final := client.poll(ctx, cursor)
+
+	res := PollResponse{Session: client.ConnID, Cursor: cursor, Messages: []json.RawMessage{}}
+	for _, event := range events {
+		res.Messages = append(res.Messages, event.data)
+		res.Cursor = event.seq
+	}
+	client.detach(done, final)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.Debug("sockets: could not write poll response", "err", err)
+	}
+}
+
+// poll returns the messages not acknowledged by cursor, waiting for the next
+// ones when there are none. It also returns whether the client is done for
+// good, with the same semantics as the websocket write pump.
+func (c *Client) poll(ctx context.Context, cursor uint64) ([]sessionEvent, bool) {
+	s := c.session
+	c.countLost(cursor)
+	s.ack(cursor)
+	if s.ended {
+		return s.events, true
+	}
+	if len(s.events) > 0 {
+		return s.events, false
+	}
+
+	timeout := time.NewTimer(c.transport.PollTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case message := <-c.send:
+			if events := c.recordQueued(message); len(events) > 0 {
+				return events, false
+			}
+		case expiresAt := <-c.reauth:
+			s.deadline.reset(expiresAt)
+		case <-s.deadline.C():
+			if s.deadline.fire() {
+				metrics.ClosedConnections.Add(metrics.CloseTokenExpired, 1)
+				return []sessionEvent{s.record(closedEvent(closeRequest{code: closeTokenExpired, text: "token expired"}))}, true
+			}
+			data, err := json.Marshal(Event{Event: eventTokenExpiring, Data: tokenExpiryData{ExpiresAt: s.deadline.expiresAt}})
+			if err != nil {
+				continue
+			}
+			return []sessionEvent{s.record(data)}, false
+		case req := <-c.kick:
+			return []sessionEvent{s.record(closedEvent(req))}, true
+		case <-c.hub.draining:
+			return []sessionEvent{s.record(closedEvent(drainedRequest))}, true
+		case <-c.hub.done:
+			return nil, true
+		case <-timeout.C:
+			return nil, false
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestServePoll(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDContextKey, r.URL.Query().Get("user"))
+		r = r.WithContext(ctx)
+		if r.Method == http.MethodPost {
+			ServeSessionAction(hub, strings.TrimPrefix(r.URL.Path, "/poll/"), w, r)
+			return
+		}
+		ServePoll(hub, w, r)
+	}))
+	defer s.Close()
+
+	poll := func(t *testing.T, query string) PollResponse {
+		t.Helper()
+		res, err := http.Get(s.URL + "/poll?" + query)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body := PollResponse{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return body
+	}
+	next := func(t *testing.T, user string, prev PollResponse) PollResponse {
+		t.Helper()
+		return poll(t, "user="+user+"&session="+prev.Session+"&cursor="+strconv.FormatUint(prev.Cursor, 10))
+	}
+	entityIDs := func(t *testing.T, res PollResponse) []string {
+		t.Helper()
+		ids := []string{}
+		for _, raw := range res.Messages {
+			message := Message{}
+			require.NoError(t, json.Unmarshal(raw, &message))
+			ids = append(ids, message.EntityID)
+		}
+		return ids
+	}
+
+	t.Run("should start a session", func(t *testing.T) {
+		res := poll(t, "user=alice&room=orders")
+		require.NotEmpty(t, res.Session)
+		require.Len(t, res.Messages, 1)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(res.Messages[0], &event))
+		assert.Equal(t, eventConnected, event.Event)
+		assert.Equal(t, uint64(1), res.Cursor)
+	})
+
+	t.Run("should hold the poll until a message arrives", func(t *testing.T) {
+		res := poll(t, "user=bob&room=orders")
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			hub.Private <- &MessageWithUser{Message: Message{EntityID: "private"}, UserID: "bob"}
+		}()
+		res = next(t, "bob", res)
+		assert.Equal(t, []string{"private"}, entityIDs(t, res))
+	})
+
+	t.Run("should buffer messages between polls", func(t *testing.T) {
+		res := poll(t, "user=carol&room=invoices")
+		time.Sleep(50 * time.Millisecond)
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "first"}, RoomName: ptr("invoices")}
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "second"}, RoomName: ptr("invoices")}
+		time.Sleep(50 * time.Millisecond)
+
+		res = next(t, "carol", res)
+		assert.Equal(t, []string{"first", "second"}, entityIDs(t, res))
+	})
+
+	t.Run("should return messages again until acknowledged", func(t *testing.T) {
+		first := poll(t, "user=dave&room=refunds")
+		time.Sleep(50 * time.Millisecond)
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "refund"}, RoomName: ptr("refunds")}
+
+		lost := next(t, "dave", first)
+		assert.Equal(t, []string{"refund"}, entityIDs(t, lost))
+		// The response was lost, the device polls with its previous cursor.
+		again := next(t, "dave", first)
+		assert.Equal(t, lost, again)
+	})
+
+	t.Run("should change rooms through posted frames", func(t *testing.T) {
+		res := poll(t, "user=erin")
+		post, err := http.Post(s.URL+"/poll/"+res.Session+"?user=erin", "application/json", strings.NewReader(`{"action":"enter","room":"alerts"}`))
+		require.NoError(t, err)
+		post.Body.Close()
+		require.Equal(t, http.StatusAccepted, post.StatusCode)
+		time.Sleep(50 * time.Millisecond)
+
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "alert"}, RoomName: ptr("alerts")}
+		res = next(t, "erin", res)
+		assert.Equal(t, []string{"alert"}, entityIDs(t, res))
+	})
+
+	t.Run("should end the session when disconnected", func(t *testing.T) {
+		res := poll(t, "user=frank")
+		require.NoError(t, hub.DisconnectConnection(context.Background(), res.Session))
+
+		closed := next(t, "frank", res)
+		require.Len(t, closed.Messages, 1)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(closed.Messages[0], &event))
+		assert.Equal(t, eventClosed, event.Event)
+
+		// The next poll starts over.
+		time.Sleep(50 * time.Millisecond)
+		assert.NotEqual(t, res.Session, next(t, "frank", closed).Session)
+	})
+
+	t.Run("should count the messages forgotten before they were acknowledged", func(t *testing.T) {
+		overflowed := func() int64 {
+			if v, ok := metrics.DroppedMessages.Get(metrics.DropReplayOverflow).(*expvar.Int); ok {
+				return v.Value()
+			}
+			return 0
+		}
+		first := poll(t, "user=heidi&room=audits")
+		time.Sleep(50 * time.Millisecond)
+		for i := range sessionBufferSize + 2 {
+			hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: strconv.Itoa(i)}, RoomName: ptr("audits")}
+		}
+		time.Sleep(50 * time.Millisecond)
+		before := overflowed()
+
+		res := next(t, "heidi", first)
+		require.Len(t, res.Messages, sessionBufferSize+2)
+		assert.Equal(t, before, overflowed())
+
+		// The response was lost, the two oldest messages are no longer kept.
+		again := next(t, "heidi", first)
+		require.Len(t, again.Messages, sessionBufferSize)
+		assert.Equal(t, "2", entityIDs(t, again)[0])
+		assert.Equal(t, before+2, overflowed())
+	})
+
+	t.Run("should unregister a session disconnected between polls", func(t *testing.T) {
+		res := poll(t, "user=grace&room=payments")
+		time.Sleep(50 * time.Millisecond)
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "unacknowledged"}, RoomName: ptr("payments")}
+		time.Sleep(50 * time.Millisecond)
+
+		require.NoError(t, hub.DisconnectConnection(context.Background(), res.Session))
+		_, err := hub.Room(context.Background(), "", "payments")
+		require.ErrorIs(t, err, ErrRoomNotFound)
+
+		closed := next(t, "grace", res)
+		require.Len(t, closed.Messages, 1)
+		event := Event{}
+		require.NoError(t, json.Unmarshal(closed.Messages[0], &event))
+		assert.Equal(t, eventClosed, event.Event)
+	})
+}
+
+func TestServePoll_transport(t *testing.T) {
+	hub := NewHub(WithTransport(Transport{PollTimeout: 100 * time.Millisecond, PollSessionWindow: 300 * time.Millisecond}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer cancel()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, "alice"))
+		ServePoll(hub, w, r)
+	}))
+	defer s.Close()
+
+	poll := func(t *testing.T, query string) PollResponse {
+		t.Helper()
+		res, err := http.Get(s.URL + "/poll?" + query)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		body := PollResponse{}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+		return body
+	}
+
+	first := poll(t, "")
+	query := "session=" + first.Session + "&cursor=" + strconv.FormatUint(first.Cursor, 10)
+
+	t.Run("should answer an idle poll after the poll timeout", func(t *testing.T) {
+		start := time.Now()
+		res := poll(t, query)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, first.Session, res.Session)
+		assert.Empty(t, res.Messages)
+	})
+
+	t.Run("should end a session not polled within the session window", func(t *testing.T) {
+		time.Sleep(600 * time.Millisecond)
+		assert.NotEqual(t, first.Session, poll(t, query).Session)
+	})
+}
@@ -0,0 +1,150 @@
+package sockets
+
+import (
+	"errors"
+	"strings"
+)
+
+// Hierarchical room name tokens. Room names are split into levels by
+// roomSeparator, e.g. "store.42.orders". A single level wildcard matches
+// exactly one level ("store.*.orders") and a multi level wildcard, which is
+// only valid as the last level, matches zero or more levels ("store.42.#").
+const (
+	roomSeparator       = "."
+	singleLevelWildcard = "*"
+	multiLevelWildcard  = "#"
+)
+
+// ErrInvalidRoomName is returned for a malformed room name or pattern.
+var ErrInvalidRoomName = errors.New("invalid room name")
+
+// validateRoomName checks that a room name or pattern is well formed.
+func validateRoomName(room string) error {
+	if room == "" {
+		return ErrInvalidRoomName
+	}
+
+	levels := strings.Split(room, roomSeparator)
+	for i, level := range levels {
+		switch {
+		case level == "":
+			return ErrInvalidRoomName
+		case level == multiLevelWildcard && i != len(levels)-1:
+			return ErrInvalidRoomName
+		case level != singleLevelWildcard && level != multiLevelWildcard &&
+			strings.ContainsAny(level, singleLevelWildcard+multiLevelWildcard):
+			return ErrInvalidRoomName
+		}
+	}
+
+	return nil
+}
+
+// roomTrie indexes room names by level so that a published room name or
+// pattern can be matched against all subscribed names without scanning them.
+type roomTrie struct {
+	root *roomTrieNode
+}
+
+type roomTrieNode struct {
+	children map[string]*roomTrieNode
+
+	// room is the full subscribed name ending at this node, if any.
+	room string
+}
+
+func newRoomTrie() *roomTrie {
+	return &roomTrie{root: newRoomTrieNode()}
+}
+
+func newRoomTrieNode() *roomTrieNode {
+	return &roomTrieNode{children: make(map[string]*roomTrieNode)}
+}
+
+// insert adds a room name to the trie.
+func (t *roomTrie) insert(room string) {
+	node := t.root
+	for _, level := range strings.Split(room, roomSeparator) {
+		child, ok := node.children[level]
+		if !ok {
+			child = newRoomTrieNode()
+			node.children[level] = child
+		}
+		node = child
+	}
+	node.room = room
+}
+
+// remove deletes a room name from the trie, pruning empty branches.
+func (t *roomTrie) remove(room string) {
+	t.root.remove(strings.Split(room, roomSeparator))
+}
+
+func (n *roomTrieNode) remove(levels []string) bool {
+	if len(levels) == 0 {
+		n.room = ""
+	} else if child, ok := n.children[levels[0]]; ok && child.remove(levels[1:]) {
+		delete(n.children, levels[0])
+	}
+
+	return n.room == "" && len(n.children) == 0
+}
+
+// match returns every subscribed room name that shares at least one concrete
+// room with the given name. Wildcards are honoured on both sides, so a
+// subscription to "store.#" receives "store.42.orders" and a publish to
+// "store.*" reaches a subscription to "store.42".
+func (t *roomTrie) match(room string) []string {
+	matched := make(map[string]struct{})
+	t.root.match(strings.Split(room, roomSeparator), matched)
+
+	rooms := make([]string, 0, len(matched))
+	for name := range matched {
+		rooms = append(rooms, name)
+	}
+	return rooms
+}
+
+func (n *roomTrieNode) match(levels []string, matched map[string]struct{}) {
+	// A multi level wildcard subscription matches the remaining levels,
+	// including none at all.
+	if child, ok := n.children[multiLevelWildcard]; ok {
+		child.collect(matched)
+	}
+
+	if len(levels) == 0 {
+		if n.room != "" {
+			matched[n.room] = struct{}{}
+		}
+		return
+	}
+
+	level, rest := levels[0], levels[1:]
+	switch level {
+	case multiLevelWildcard:
+		n.collect(matched)
+	case singleLevelWildcard:
+		for name, child := range n.children {
+			if name != multiLevelWildcard {
+				child.match(rest, matched)
+			}
+		}
+	default:
+		if child, ok := n.children[level]; ok {
+			child.match(rest, matched)
+		}
+		if child, ok := n.children[singleLevelWildcard]; ok {
+			child.match(rest, matched)
+		}
+	}
+}
+
+// collect adds every room name in the subtree rooted at n.
+func (n *roomTrieNode) collect(matched map[string]struct{}) {
+	if n.room != "" {
+		matched[n.room] = struct{}{}
+	}
+	for _, child := range n.children {
+		child.collect(matched)
+	}
+}
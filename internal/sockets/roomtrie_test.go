@@ -0,0 +1,70 @@
+package sockets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRoomName(t *testing.T) {
+	valid := []string{"orders", "store.42.orders", "store.*.orders", "store.42.#", "#", "*"}
+	for _, room := range valid {
+		assert.NoError(t, validateRoomName(room), room)
+	}
+
+	invalid := []string{"", ".", "store..orders", "store.", "store.#.orders", "store.4*", "store.#42"}
+	for _, room := range invalid {
+		assert.Error(t, validateRoomName(room), room)
+	}
+}
+
+func TestRoomTrie_Match(t *testing.T) {
+	trie := newRoomTrie()
+	for _, room := range []string{
+		"store.42.orders",
+		"store.42.orders.7",
+		"store.43.orders",
+		"store.*.orders",
+		"store.42.#",
+		"store",
+		"#",
+	} {
+		trie.insert(room)
+	}
+
+	t.Run("should match exact and wildcard subscriptions", func(t *testing.T) {
+		assert.ElementsMatch(t,
+			[]string{"store.42.orders", "store.*.orders", "store.42.#", "#"},
+			trie.match("store.42.orders"))
+	})
+
+	t.Run("should match multi level wildcard with no remaining levels", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"store.42.#", "#"}, trie.match("store.42"))
+	})
+
+	t.Run("should match subscriptions from a single level wildcard publish", func(t *testing.T) {
+		assert.ElementsMatch(t,
+			[]string{"store.42.orders", "store.43.orders", "store.*.orders", "store.42.#", "#"},
+			trie.match("store.*.orders"))
+	})
+
+	t.Run("should match subscriptions from a multi level wildcard publish", func(t *testing.T) {
+		assert.ElementsMatch(t,
+			[]string{"store.42.orders", "store.42.orders.7", "store.*.orders", "store.42.#", "#"},
+			trie.match("store.42.#"))
+	})
+
+	t.Run("should not match after removal", func(t *testing.T) {
+		trie.remove("#")
+		trie.remove("store.42.#")
+		assert.ElementsMatch(t, []string{"store.42.orders", "store.*.orders"}, trie.match("store.42.orders"))
+		assert.Empty(t, trie.match("store.42"))
+	})
+
+	t.Run("should prune empty branches", func(t *testing.T) {
+		trie := newRoomTrie()
+		trie.insert("a.b.c")
+		trie.remove("a.b.c")
+		assert.Empty(t, trie.root.children)
+	})
+}
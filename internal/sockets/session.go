@@ -0,0 +1,319 @@
+package sockets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+// Number of events a session keeps for the peer to receive again.
+const sessionBufferSize = 128
+
+// session is the state of a client connected over plain HTTP requests, such
+// as an event stream or long polls, rather than a websocket. The client
+// outlives the requests serving it: it stays registered for window after a
+// request ends, so that the next request resumes it with its rooms and
+// without missing messages.
+type session struct {
+	mu sync.Mutex
+
+	// stop ends the request attached to the client, which closes done once
+	// it returned. Both are nil while no request is attached.
+	stop context.CancelFunc
+	done chan struct{}
+
+	// window is how long the client stays registered without a request,
+	// and linger unregisters it once the window passed.
+	window time.Duration
+	linger *time.Timer
+
+	// closed is set once the client is unregistered for good.
+	closed bool
+
+	// ended is set once the hub closed the client while no request served
+	// it. Only the closed event is kept for the next request.
+	ended bool
+
+	// frames serializes the frames posted by the client, as the read pump
+	// of a websocket does.
+	frames sync.Mutex
+
+	// The following are only used by the attached request, or with mu held
+	// while none is.
+	seq      uint64
+	events   []sessionEvent
+	deadline *tokenDeadline
+}
+
+// sessionEvent is a payload sent to the peer, kept until the peer may no
+// longer ask for it again.
+type sessionEvent struct {
+	seq  uint64
+	data []byte
+}
+
+// record numbers a payload sent to the peer and keeps it. The oldest event is
+// forgotten once the buffer is full, see countLost.
+func (s *session) record(data []byte) sessionEvent {
+	s.seq++
+	event := sessionEvent{seq: s.seq, data: data}
+	if len(s.events) == sessionBufferSize {
+		s.events = s.events[1:]
+	}
+	s.events = append(s.events, event)
+	return event
+}
+
+// since returns the events kept after seq.
+func (s *session) since(seq uint64) []sessionEvent {
+	for i, event := range s.events {
+		if event.seq > seq {
+			return s.events[i:]
+		}
+	}
+	return nil
+}
+
+// ack forgets the events up to seq, which the peer received.
+func (s *session) ack(seq uint64) {
+	s.events = s.since(seq)
+}
+
+// countLost counts the events after seq, which the peer asks for again, that
+// were forgotten as the buffer filled up.
+func (c *Client) countLost(seq uint64) {
+	s := c.session
+	if s.ended || len(s.events) == 0 || s.events[0].seq <= seq+1 {
+		return
+	}
+	n := int64(s.events[0].seq - seq - 1)
+	metrics.DroppedMessages.Add(metrics.DropReplayOverflow, n)
+	metrics.AddTenant(c.Tenant, metrics.TenantDroppedMessages, n)
+}
+
+// endSession closes the session of a client no request serves, keeping only
+// the closed event for the peer to receive when it resumes. It returns false
+// when a request serves the client, which is then asked to close instead.
+func (c *Client) endSession(req closeRequest) bool {
+	s := c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil || s.closed || s.ended {
+		return false
+	}
+	s.ended = true
+	s.events = []sessionEvent{s.record(closedEvent(req))}
+	return true
+}
+
+// connectSession registers a new session client for the request and puts it
+// in the rooms of the query. It answers the request and returns false when
+// the client is rejected.
+func connectSession(hub *Hub, w http.ResponseWriter, r *http.Request, window time.Duration) (*Client, bool) {
+	rooms := r.URL.Query()["room"]
+	for _, room := range rooms {
+		if err := validateRoomName(room); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	client := newClient(hub, hub.transport, r)
+	client.session = &session{
+		window:   window,
+		deadline: newTokenDeadline(client.expiresAt, hub.auth.ExpiryWarning),
+	}
+
+	evicted, limitErr := hub.conns.acquire(client)
+	if limitErr != nil {
+		hub.conns.reject(w, client, limitErr)
+		return nil, false
+	}
+	if evicted != nil {
+		evicted.disconnect(closeSessionEvicted, "session limit reached", metrics.CloseEvicted)
+	}
+
+	client.ConnectedAt = time.Now()
+	// The connection ID is needed to change rooms, so it comes first.
+	registered := enqueue(hub, hub.register, newMember(client, client.pinnedRooms)) &&
+		enqueue(hub, hub.direct, &directMessage{client: client, payload: Event{Event: eventConnected, Data: connectedData{ConnectionID: client.ConnID}}})
+	for _, room := range rooms {
+		registered = registered && enqueue(hub, hub.registerRoom, newSubscription(room, client))
+	}
+	if !registered {
+		hub.conns.release(client)
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return client, true
+}
+
+// ServeSessionAction handles a frame posted for the session client connID,
+// the same frames a websocket client sends: entering or leaving a room, or
+// re-authenticating. Their outcome is sent as events to the client.
+func ServeSessionAction(hub *Hub, connID string, w http.ResponseWriter, r *http.Request) {
+	if !allowOrigin(hub, w, r) {
+		return
+	}
+
+	client := hub.sessionClient(r, connID)
+	if client == nil || client.sessionEnded() {
+		http.Error(w, "connection not found", http.StatusNotFound)
+		return
+	}
+
+	message, err := io.ReadAll(http.MaxBytesReader(w, r.Body, client.transport.MaxMessageSize))
+	if err != nil {
+		http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	client.session.frames.Lock()
+	err = client.handleFrame(message)
+	client.session.frames.Unlock()
+	if errors.Is(err, errRateLimited) {
+		client.disconnect(websocket.ClosePolicyViolation, err.Error(), metrics.CloseRateLimited)
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// sessionClient returns the registered session client connID, when it
+// belongs to the user and tenant the request was authenticated as.
+func (h *Hub) sessionClient(r *http.Request, connID string) *Client {
+	var sub Subscriber
+	if err := h.call(r.Context(), func() {
+		sub = h.connection(connID)
+		if client, ok := h.ended[connID]; ok {
+			sub = client
+		}
+	}); err != nil {
+		return nil
+	}
+	client, ok := sub.(*Client)
+	if !ok || client.session == nil {
+		return nil
+	}
+	userID, _ := middleware.UserIDFromRequest(r.Context())
+	tenant, _ := middleware.TenantFromRequest(r.Context())
+	if client.ID != userID || client.Tenant != tenant {
+		return nil
+	}
+	return client
+}
+
+// sessionEnded reports whether the hub closed the session, see endSession.
+func (c *Client) sessionEnded() bool {
+	s := c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
+
+// resume extends the deadline of a resumed client with the token of the
+// request resuming it.
+func (c *Client) resume(r *http.Request) {
+	if claims, ok := middleware.ClaimsFromRequest(r.Context()); ok {
+		c.extendDeadline(claims.ExpiresAt)
+	}
+}
+
+// attach makes the request the one serving the client, ending the request
+// attached before it. It returns false once the client is closed.
+func (c *Client) attach(ctx context.Context) (context.Context, chan struct{}, bool) {
+	s := c.session
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.stop != nil {
+		stop, done := s.stop, s.done
+		s.mu.Unlock()
+		stop()
+		<-done
+		s.mu.Lock()
+	}
+	if s.closed {
+		return nil, nil, false
+	}
+	if s.linger != nil {
+		s.linger.Stop()
+	}
+	ctx, s.stop = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	return ctx, s.done, true
+}
+
+// detach releases the client from the request that served it. A client that
+// is not done lingers for the next request to resume it.
+func (c *Client) detach(done chan struct{}, final bool) {
+	s := c.session
+	s.mu.Lock()
+	s.stop()
+	s.stop, s.done = nil, nil
+	if final {
+		s.closed = true
+		s.deadline.stop()
+	} else {
+		s.linger = time.AfterFunc(s.window, c.expireSession)
+	}
+	close(done)
+	s.mu.Unlock()
+
+	if final {
+		c.hub.release(c)
+	}
+}
+
+// expireSession unregisters a client that was not resumed in time.
+func (c *Client) expireSession() {
+	s := c.session
+	s.mu.Lock()
+	if s.stop != nil || s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.deadline.stop()
+	s.mu.Unlock()
+
+	c.hub.release(c)
+}
+
+// recordQueued records the message taken from the send buffer and the
+// messages queued behind it, dropping the expired ones.
+func (c *Client) recordQueued(message outbound) []sessionEvent {
+	var events []sessionEvent
+	now := time.Now()
+	for n := len(c.send); ; n-- {
+		// Messages may have expired while waiting in the send buffer.
+		if message.expired(now) {
+			metrics.DroppedMessages.Add(metrics.DropExpired, 1)
+			metrics.AddTenant(c.Tenant, metrics.TenantDroppedMessages, 1)
+		} else {
+			events = append(events, c.session.record(message.data))
+		}
+		if n == 0 {
+			return events
+		}
+		message = <-c.send
+	}
+}
+
+// closedEvent tells the peer why the server ends the session, as the close
+// frame of a websocket does. The peer must not resume it.
+func closedEvent(req closeRequest) []byte {
+	data, _ := json.Marshal(Event{Event: eventClosed, Data: closedData{Code: req.code, Reason: req.text}})
+	return data
+}
@@ -1,47 +1,130 @@
 package sockets
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 
+	"github.com/yiannis54/go-socket-server/internal/metrics"
 	"github.com/yiannis54/go-socket-server/internal/middleware"
 )
 
-const (
-	channelBytes      = 256
-	defaultBufferSize = 1024
-)
-
 // ServeWs handles websocket requests from the peer.
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	serveWs(hub, hub.transport, w, r)
+}
+
+func serveWs(hub *Hub, transport Transport, w http.ResponseWriter, r *http.Request) {
 	upgrader := websocket.Upgrader{
-		ReadBufferSize:  defaultBufferSize,
-		WriteBufferSize: defaultBufferSize,
+		ReadBufferSize:  transport.ReadBufferSize,
+		WriteBufferSize: transport.WriteBufferSize,
+		// Compression is negotiated with peers offering permessage-deflate.
+		EnableCompression: hub.compression.Enabled,
+		// The origin is checked against the hub policy before upgrading.
 		CheckOrigin: func(r *http.Request) bool {
 			return true
 		},
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	if !allowOrigin(hub, w, r) {
+		return
+	}
+
+	client := newClient(hub, transport, r)
+
+	// Connection limits are checked before upgrading, so that rejected
+	// clients get a plain HTTP response they can retry on.
+	evicted, limitErr := hub.conns.acquire(client)
+	if limitErr != nil {
+		hub.conns.reject(w, client, limitErr)
+		return
+	}
+
+	// The handshake must echo one of the subprotocols offered for the client
+	// to accept it: the wire format, else the one a token was sent in.
+	var responseHeader http.Header
+	if format, ok := negotiateFormat(websocket.Subprotocols(r)); ok {
+		client.format = format
+		responseHeader = http.Header{"Sec-Websocket-Protocol": []string{format.String()}}
+	} else if protocol, ok := middleware.SubprotocolFromRequest(r.Context()); ok {
+		responseHeader = http.Header{"Sec-Websocket-Protocol": []string{protocol}}
+	}
+
+	client.compress = hub.compression.Enabled && offersDeflate(r)
+	if client.compress {
+		w = countingWriter{ResponseWriter: w, written: &client.wireBytes}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, responseHeader)
 	if err != nil {
-		log.Println(err)
+		hub.conns.release(client)
+		if evicted != nil {
+			hub.conns.restore(evicted)
+		}
+		slog.Debug("sockets: upgrade failed", "err", err)
 		return
 	}
-	// should not defer here conn.Close(), moved to goroutines
-	client := &Client{
-		hub:  hub,
-		conn: conn,
-		send: make(chan []byte, channelBytes),
+	if client.compress && hub.compression.Level != 0 {
+		if err := conn.SetCompressionLevel(hub.compression.Level); err != nil {
+			slog.Warn("sockets: could not set the compression level", "err", err)
+		}
+	}
+	if evicted != nil {
+		evicted.disconnect(closeSessionEvicted, "session limit reached", metrics.CloseEvicted)
 	}
 
-	client.hub.register <- client
-	if id, ok := middleware.UserIDFromRequest(r.Context()); ok {
-		client.ID = id
+	// should not defer here conn.Close(), moved to goroutines
+	client.conn = conn
+	client.ConnectedAt = time.Now()
+	if !enqueue(hub, hub.register, newMember(client, client.pinnedRooms)) {
+		conn.Close()
+		hub.conns.release(client)
+		return
 	}
 
 	// Allow collection of memory referenced by the caller by doing all work in new goroutines.
 	go client.writePump()
 	go client.readPump()
 }
+
+// allowOrigin checks the origin of the request against the hub policy, and
+// answers rejected requests with 403 Forbidden.
+func allowOrigin(hub *Hub, w http.ResponseWriter, r *http.Request) bool {
+	if origin := r.Header.Get("Origin"); !hub.Policy().OriginPolicy.allowed(origin) {
+		slog.Info("sockets: rejected connection", "origin", origin)
+		metrics.RejectedConnections.Add(metrics.RejectOrigin, 1)
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// newClient returns a client of the hub for the authenticated request.
+func newClient(hub *Hub, transport Transport, r *http.Request) *Client {
+	client := &Client{
+		hub:       hub,
+		transport: transport,
+		ConnID:    uuid.NewString(),
+		IP:        middleware.ClientIP(r, hub.conns.trustedProxies()),
+		send:      make(chan outbound, transport.SendBuffer),
+		kick:      make(chan closeRequest, 1),
+		closed:    make(chan struct{}),
+		// reauth holds the latest expiry only, see reauthenticate.
+		reauth: make(chan time.Time, 1),
+	}
+	client.refreshPolicy()
+	if id, ok := middleware.UserIDFromRequest(r.Context()); ok {
+		client.ID = id
+	}
+	if tenant, ok := middleware.TenantFromRequest(r.Context()); ok {
+		client.Tenant = tenant
+	}
+	if claims, ok := middleware.ClaimsFromRequest(r.Context()); ok {
+		client.expiresAt = claims.ExpiresAt
+		client.pinnedRooms = hub.autoJoinRooms(claims)
+	}
+	return client
+}
@@ -0,0 +1,50 @@
+package sockets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestServeWs(t *testing.T) {
+	hub := NewHub(WithOriginPolicy(OriginPolicy{Allowed: []string{"https://app.example.com"}}))
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), middleware.SubprotocolContextKey, "bearer.abc"))
+		ServeWs(hub, w, r)
+	}))
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+
+	t.Run("should reject disallowed origins", func(t *testing.T) {
+		_, res, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": []string{"https://evil.example.com"}})
+		require.Error(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	})
+
+	t.Run("should echo the token subprotocol", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{"bearer.abc"}}
+		ws, res, err := dialer.Dial(wsURL, http.Header{"Origin": []string{"https://app.example.com"}})
+		require.NoError(t, err)
+		defer ws.Close()
+		res.Body.Close()
+		assert.Equal(t, "bearer.abc", ws.Subprotocol())
+	})
+}
@@ -0,0 +1,175 @@
+package sockets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+)
+
+// ServeSSE streams the messages of a hub client as server-sent events, for
+// clients that cannot open a websocket. The client starts in the rooms given
+// as room query parameters and changes them through ServeSessionAction. A
+// request carrying the Last-Event-ID of a client that dropped resumes it.
+func ServeSSE(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	if !allowOrigin(hub, w, r) {
+		return
+	}
+
+	client, last := hub.resumableClient(r)
+	if client != nil {
+		client.resume(r)
+	} else {
+		var ok bool
+		if client, ok = connectSession(hub, w, r, hub.transport.SSEResumeWindow); !ok {
+			return
+		}
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// Proxies must not buffer the stream.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		slog.Warn("sockets: event stream not supported", "err", err)
+	}
+	client.serveStream(r.Context(), w, rc, last)
+}
+
+// resumableClient returns the session client named by the Last-Event-ID of
+// the request, and the sequence of the last event the peer received.
+func (h *Hub) resumableClient(r *http.Request) (*Client, uint64) {
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		// EventSource polyfills cannot always set the header.
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	connID, seq, ok := strings.Cut(lastEventID, ":")
+	if !ok {
+		return nil, 0
+	}
+	last, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return nil, 0
+	}
+	client := h.sessionClient(r, connID)
+	if client == nil {
+		return nil, 0
+	}
+	return client, last
+}
+
+// serveStream attaches the request to the client and writes its messages to
+// the stream until the request ends. Events written after last are replayed
+// first, as the peer may not have received them.
+func (c *Client) serveStream(ctx context.Context, w http.ResponseWriter, rc *http.ResponseController, last uint64) {
+	ctx, done, ok := c.attach(ctx)
+	if !ok {
+		// The client expired in between, the peer reconnects as a new one.
+		return
+	}
+
+	c.countLost(last)
+	var buf bytes.Buffer
+	for _, event := range c.session.since(last) {
+		writeSSEEvent(&buf, c.ConnID, event)
+	}
+	if buf.Len() > 0 {
+		if err := c.writeStream(w, rc, buf.Bytes()); err != nil {
+			c.detach(done, false)
+			return
+		}
+	}
+	if c.session.ended {
+		c.detach(done, true)
+		return
+	}
+	c.detach(done, c.ssePump(ctx, w, rc))
+}
+
+// ssePump writes the messages queued for the client to the event stream,
+// with the same semantics as the websocket write pump. It returns whether the
+// client is done for good, rather than its stream dropped.
+func (c *Client) ssePump(ctx context.Context, w http.ResponseWriter, rc *http.ResponseController) bool {
+	s := c.session
+	ticker := time.NewTicker(c.transport.PingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case message := <-c.send:
+			var buf bytes.Buffer
+			for _, event := range c.recordQueued(message) {
+				writeSSEEvent(&buf, c.ConnID, event)
+			}
+			if buf.Len() == 0 {
+				continue
+			}
+			if err := c.writeStream(w, rc, buf.Bytes()); err != nil {
+				return false
+			}
+		case expiresAt := <-c.reauth:
+			s.deadline.reset(expiresAt)
+		case <-s.deadline.C():
+			if s.deadline.fire() {
+				metrics.ClosedConnections.Add(metrics.CloseTokenExpired, 1)
+				c.writeStreamClose(w, rc, closeRequest{code: closeTokenExpired, text: "token expired"})
+				return true
+			}
+			data, err := json.Marshal(Event{Event: eventTokenExpiring, Data: tokenExpiryData{ExpiresAt: s.deadline.expiresAt}})
+			if err != nil {
+				continue
+			}
+			var buf bytes.Buffer
+			writeSSEEvent(&buf, c.ConnID, s.record(data))
+			if err := c.writeStream(w, rc, buf.Bytes()); err != nil {
+				return false
+			}
+		case req := <-c.kick:
+			c.writeStreamClose(w, rc, req)
+			return true
+		case <-ticker.C:
+			// A comment keeps proxies from timing out the idle stream.
+			if err := c.writeStream(w, rc, []byte(":ping\n\n")); err != nil {
+				return false
+			}
+		case <-c.hub.draining:
+			c.writeStreamClose(w, rc, drainedRequest)
+			return true
+		case <-c.hub.done:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// writeSSEEvent formats an event of the stream. Event IDs name the client,
+// for a reconnect to resume it.
+func writeSSEEvent(buf *bytes.Buffer, connID string, event sessionEvent) {
+	fmt.Fprintf(buf, "id: %s:%d\ndata: %s\n\n", connID, event.seq, event.data)
+}
+
+// writeStreamClose tells the peer why the server ends the stream.
+func (c *Client) writeStreamClose(w http.ResponseWriter, rc *http.ResponseController, req closeRequest) {
+	_ = c.writeStream(w, rc, []byte("data: "+string(closedEvent(req))+"\n\n"))
+}
+
+// writeStream writes to the event stream and flushes it to the peer.
+func (c *Client) writeStream(w http.ResponseWriter, rc *http.ResponseController, data []byte) error {
+	_ = rc.SetWriteDeadline(time.Now().Add(c.transport.WriteWait))
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
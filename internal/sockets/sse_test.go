@@ -0,0 +1,268 @@
+package sockets
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+// sseEventStream reads the events of a server-sent events response.
+type sseEventStream struct {
+	t      *testing.T
+	res    *http.Response
+	reader *bufio.Reader
+	cancel context.CancelFunc
+}
+
+// next returns the ID and data of the next event, skipping comments.
+func (s *sseEventStream) next() (string, string) {
+	s.t.Helper()
+	var id, data string
+	for {
+		line, err := s.reader.ReadString('\n')
+		require.NoError(s.t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && data != "":
+			return id, data
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func (s *sseEventStream) nextEvent() (string, Event) {
+	s.t.Helper()
+	id, data := s.next()
+	event := Event{}
+	require.NoError(s.t, json.Unmarshal([]byte(data), &event))
+	return id, event
+}
+
+func (s *sseEventStream) nextMessage() (string, Message) {
+	s.t.Helper()
+	id, data := s.next()
+	message := Message{}
+	require.NoError(s.t, json.Unmarshal([]byte(data), &message))
+	return id, message
+}
+
+func (s *sseEventStream) close() {
+	s.cancel()
+	s.res.Body.Close()
+}
+
+func TestServeSSE(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", func(w http.ResponseWriter, r *http.Request) {
+		ServeSSE(hub, w, r)
+	})
+	mux.HandleFunc("POST /events/{connection}", func(w http.ResponseWriter, r *http.Request) {
+		ServeSessionAction(hub, r.PathValue("connection"), w, r)
+	})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDContextKey, r.URL.Query().Get("user"))
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	defer s.Close()
+
+	open := func(t *testing.T, query, lastEventID string) *sseEventStream {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/events?"+query, nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+		return &sseEventStream{t: t, res: res, reader: bufio.NewReader(res.Body), cancel: cancel}
+	}
+	post := func(t *testing.T, connID, user, body string) int {
+		t.Helper()
+		res, err := http.Post(s.URL+"/events/"+connID+"?user="+user, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	connect := func(t *testing.T, query string) (*sseEventStream, string) {
+		t.Helper()
+		stream := open(t, query, "")
+		_, event := stream.nextEvent()
+		require.Equal(t, eventConnected, event.Event)
+		connID := event.Data.(map[string]any)["connectionId"].(string)
+		require.NotEmpty(t, connID)
+		return stream, connID
+	}
+
+	t.Run("should deliver room and private messages", func(t *testing.T) {
+		stream, connID := connect(t, "user=alice&room=orders")
+		defer stream.close()
+		time.Sleep(50 * time.Millisecond)
+
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "room"}, RoomName: ptr("orders")}
+		id, message := stream.nextMessage()
+		assert.Equal(t, connID+":2", id)
+		assert.Equal(t, "room", message.EntityID)
+
+		hub.Private <- &MessageWithUser{Message: Message{EntityID: "private"}, UserID: "alice"}
+		_, message = stream.nextMessage()
+		assert.Equal(t, "private", message.EntityID)
+	})
+
+	t.Run("should change rooms through posted frames", func(t *testing.T) {
+		stream, connID := connect(t, "user=bob")
+		defer stream.close()
+
+		assert.Equal(t, http.StatusAccepted, post(t, connID, "bob", `{"action":"enter","room":"invoices"}`))
+		time.Sleep(50 * time.Millisecond)
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "invoice"}, RoomName: ptr("invoices")}
+		_, message := stream.nextMessage()
+		assert.Equal(t, "invoice", message.EntityID)
+
+		assert.Equal(t, http.StatusBadRequest, post(t, connID, "bob", `{"action":"dance","room":"invoices"}`))
+		assert.Equal(t, http.StatusNotFound, post(t, connID, "mallory", `{"action":"leave","room":"invoices"}`))
+		assert.Equal(t, http.StatusNotFound, post(t, "unknown", "bob", `{"action":"leave","room":"invoices"}`))
+	})
+
+	t.Run("should resume through the last event ID", func(t *testing.T) {
+		stream, connID := connect(t, "user=carol&room=orders")
+		time.Sleep(50 * time.Millisecond)
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "first"}, RoomName: ptr("orders")}
+		lastID, _ := stream.nextMessage()
+		stream.close()
+		time.Sleep(50 * time.Millisecond)
+
+		// Published while the stream is down.
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "missed"}, RoomName: ptr("orders")}
+
+		resumed := open(t, "user=carol", lastID)
+		defer resumed.close()
+		id, message := resumed.nextMessage()
+		assert.Equal(t, connID+":3", id)
+		assert.Equal(t, "missed", message.EntityID)
+	})
+
+	t.Run("should replay events the peer did not receive", func(t *testing.T) {
+		stream, connID := connect(t, "user=dave&room=orders")
+		time.Sleep(50 * time.Millisecond)
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "lost"}, RoomName: ptr("orders")}
+		_, _ = stream.nextMessage()
+		stream.close()
+		time.Sleep(50 * time.Millisecond)
+
+		resumed := open(t, "user=dave", connID+":1")
+		defer resumed.close()
+		_, message := resumed.nextMessage()
+		assert.Equal(t, "lost", message.EntityID)
+	})
+
+	t.Run("should not resume the stream of another user", func(t *testing.T) {
+		stream, connID := connect(t, "user=erin")
+		defer stream.close()
+
+		other := open(t, "user=mallory", connID+":1")
+		defer other.close()
+		_, event := other.nextEvent()
+		assert.Equal(t, eventConnected, event.Event)
+		assert.NotEqual(t, connID, event.Data.(map[string]any)["connectionId"])
+	})
+
+	t.Run("should tell the peer why the stream is closed", func(t *testing.T) {
+		stream, connID := connect(t, "user=frank")
+		defer stream.close()
+
+		require.NoError(t, hub.DisconnectConnection(context.Background(), connID))
+		_, event := stream.nextEvent()
+		assert.Equal(t, eventClosed, event.Event)
+		assert.InDelta(t, closeDisconnected, event.Data.(map[string]any)["code"], 0)
+
+		// The client is gone for good.
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, http.StatusNotFound, post(t, connID, "frank", `{"action":"enter","room":"orders"}`))
+	})
+
+	t.Run("should unregister a stream revoked while down", func(t *testing.T) {
+		stream, connID := connect(t, "user=grace&room=refunds")
+		stream.close()
+		time.Sleep(50 * time.Millisecond)
+
+		n, err := hub.RevokeUser(context.Background(), "", "grace")
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		_, err = hub.Room(context.Background(), "", "refunds")
+		require.ErrorIs(t, err, ErrRoomNotFound)
+		assert.Equal(t, http.StatusNotFound, post(t, connID, "grace", `{"action":"enter","room":"orders"}`))
+
+		// Only the closed event is left for the peer resuming the stream.
+		resumed := open(t, "user=grace", connID+":1")
+		defer resumed.close()
+		id, event := resumed.nextEvent()
+		assert.Equal(t, connID+":2", id)
+		assert.Equal(t, eventClosed, event.Event)
+		assert.InDelta(t, closeTokenRevoked, event.Data.(map[string]any)["code"], 0)
+	})
+}
+
+func TestServeSSE_afterClose(t *testing.T) {
+	hub := stoppedHub()
+	rec := httptest.NewRecorder()
+	assert.NotPanics(t, func() {
+		ServeSSE(hub, rec, httptest.NewRequest(http.MethodGet, "/events?room=orders", nil))
+	})
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Zero(t, hub.conns.total)
+}
+
+func TestServeSSE_drain(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer cancel()
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeSSE(hub, w, r)
+	}))
+	s.Config.RegisterOnShutdown(hub.Drain)
+	s.Start()
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/events")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	stream := &sseEventStream{t: t, res: res, reader: bufio.NewReader(res.Body), cancel: func() {}}
+	_, event := stream.nextEvent()
+	require.Equal(t, eventConnected, event.Event)
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancelShutdown()
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Config.Shutdown(shutdownCtx) }()
+
+	_, event = stream.nextEvent()
+	assert.Equal(t, eventClosed, event.Event)
+	assert.InDelta(t, drainedRequest.code, event.Data.(map[string]any)["code"], 0)
+	require.NoError(t, <-shutdown, "the open stream should not hold the shutdown")
+}
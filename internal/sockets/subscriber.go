@@ -0,0 +1,116 @@
+package sockets
+
+import (
+	"context"
+	"time"
+)
+
+// Subscriber is a delivery target of the hub, such as a websocket or an event
+// stream. The hub routes messages to the subscribers of its users and rooms
+// without knowing the transport they are written to.
+type Subscriber interface {
+	// ConnectionID identifies the subscriber, as opposed to its user.
+	ConnectionID() string
+
+	// User returns the tenant and the user ID of the subscriber. The user ID
+	// is empty for anonymous subscribers.
+	User() (tenant, userID string)
+
+	// Format is the wire format the messages delivered are encoded in.
+	Format() Format
+
+	// Deliver queues an encoded message without blocking. The message is
+	// dropped once expiresAt passed, unless it is zero. Deliver returns false
+	// when the subscriber cannot keep up, upon which the hub unregisters it.
+	Deliver(data []byte, expiresAt time.Time) bool
+
+	// Close asks the subscriber to end, with a websocket close code and
+	// text. It must not block.
+	Close(code int, text string)
+}
+
+// member is the hub state of a registered subscriber. It is only accessed
+// from the hub loop.
+type member struct {
+	sub            Subscriber
+	tenant, userID string
+	ns             *namespace
+
+	// registeredAt is when the subscriber joined the hub.
+	registeredAt time.Time
+
+	// Rooms the subscriber is in, and the rooms it cannot leave.
+	rooms  map[string]struct{}
+	pinned map[string]struct{}
+}
+
+// isPinned reports whether the subscriber cannot leave the room.
+func (m *member) isPinned(room string) bool {
+	_, ok := m.pinned[room]
+	return ok
+}
+
+func newMember(sub Subscriber, pinned map[string]struct{}) *member {
+	tenant, userID := sub.User()
+	return &member{
+		sub:          sub,
+		tenant:       tenant,
+		userID:       userID,
+		registeredAt: time.Now(),
+		rooms:        make(map[string]struct{}),
+		pinned:       pinned,
+	}
+}
+
+// Register adds a subscriber to the hub, in the rooms assigned to its user.
+func (h *Hub) Register(ctx context.Context, sub Subscriber) error {
+	return h.call(ctx, func() {
+		if _, ok := h.clients[sub]; !ok {
+			h.registerClient(newMember(sub, nil))
+		}
+	})
+}
+
+// Unregister removes a subscriber from the hub and from its rooms.
+func (h *Hub) Unregister(ctx context.Context, sub Subscriber) error {
+	return h.call(ctx, func() {
+		if _, ok := h.clients[sub]; ok {
+			h.unRegisterClient(sub)
+		}
+	})
+}
+
+// Enter puts a registered subscriber in a room of its tenant, within the
+// subscription limits.
+func (h *Hub) Enter(ctx context.Context, sub Subscriber, room string) error {
+	if err := validateRoomName(room); err != nil {
+		return err
+	}
+	var err error
+	if callErr := h.call(ctx, func() { err = h.joinRoom(room, sub) }); callErr != nil {
+		return callErr
+	}
+	return err
+}
+
+// Leave takes a registered subscriber out of a room.
+func (h *Hub) Leave(ctx context.Context, sub Subscriber, room string) error {
+	var err error
+	callErr := h.call(ctx, func() {
+		if m, ok := h.clients[sub]; ok && m.isPinned(room) {
+			err = errRoomPinned
+			return
+		}
+		h.leaveRoom(room, sub)
+	})
+	if callErr != nil {
+		return callErr
+	}
+	return err
+}
+
+// describer is implemented by subscribers that know more about their peer
+// than the hub does.
+type describer interface {
+	describe(info *ConnectionInfo)
+}
@@ -0,0 +1,99 @@
+package sockets
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSubscriber records what the hub delivers to it.
+type fakeSubscriber struct {
+	id        string
+	userID    string
+	delivered chan []byte
+	closed    chan int
+}
+
+func newFakeSubscriber(id, userID string, buffer int) *fakeSubscriber {
+	return &fakeSubscriber{
+		id:        id,
+		userID:    userID,
+		delivered: make(chan []byte, buffer),
+		closed:    make(chan int, 1),
+	}
+}
+
+func (f *fakeSubscriber) ConnectionID() string { return f.id }
+
+func (f *fakeSubscriber) User() (string, string) { return "", f.userID }
+
+func (f *fakeSubscriber) Format() Format { return FormatJSON }
+
+func (f *fakeSubscriber) Deliver(data []byte, _ time.Time) bool {
+	select {
+	case f.delivered <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *fakeSubscriber) Close(code int, _ string) {
+	select {
+	case f.closed <- code:
+	default:
+	}
+}
+
+func TestHub_Subscriber(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer cancel()
+
+	room := "orders"
+	fast := newFakeSubscriber("fast", "user", 4)
+	slow := newFakeSubscriber("slow", "other", 0)
+	for _, sub := range []*fakeSubscriber{fast, slow} {
+		require.NoError(t, hub.Register(ctx, sub))
+		require.NoError(t, hub.Enter(ctx, sub, room))
+	}
+
+	t.Run("should find the subscriber of a user", func(t *testing.T) {
+		var sub Subscriber
+		require.NoError(t, hub.call(ctx, func() { sub = hub.GetUser("", "user") }))
+		assert.Equal(t, fast, sub)
+	})
+
+	t.Run("should deliver room messages", func(t *testing.T) {
+		hub.Broadcast <- &MessageWithRoom{Message: Message{Type: "order", EntityID: "1"}, RoomName: &room}
+		select {
+		case data := <-fast.delivered:
+			assert.Contains(t, string(data), `"entityId":"1"`)
+		case <-time.After(time.Second):
+			t.Fatal("message not delivered")
+		}
+	})
+
+	t.Run("should close subscribers that cannot keep up", func(t *testing.T) {
+		select {
+		case code := <-slow.closed:
+			assert.Equal(t, closeSlowConsumer, code)
+		case <-time.After(time.Second):
+			t.Fatal("slow subscriber not closed")
+		}
+		var registered bool
+		require.NoError(t, hub.call(ctx, func() { _, registered = hub.clients[slow] }))
+		assert.False(t, registered)
+	})
+
+	t.Run("should leave rooms on unregister", func(t *testing.T) {
+		require.NoError(t, hub.Unregister(ctx, fast))
+		var members int
+		require.NoError(t, hub.call(ctx, func() { members = len(hub.lookupNamespace("").roomMembers(room)) }))
+		assert.Zero(t, members)
+	})
+}
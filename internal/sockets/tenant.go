@@ -0,0 +1,71 @@
+package sockets
+
+// namespace holds the users and rooms of one tenant. Room names, user IDs and
+// broadcasts are scoped to a namespace, so that tenants never reach each
+// other's clients. Clients without tenant share the namespace of the empty
+// tenant. Namespaces are only accessed from the hub loop.
+type namespace struct {
+	tenant string
+
+	// Registered subscribers of the tenant.
+	clients map[Subscriber]struct{}
+
+	// map of subscribers per user id, one per session of the user.
+	users map[string]map[Subscriber]struct{}
+
+	// map of rooms for events notifications.
+	rooms map[string]map[Subscriber]struct{}
+
+	// index of room names for matching hierarchical and wildcard rooms.
+	roomIndex *roomTrie
+
+	// rooms assigned to users by the server, joined by each of their sessions.
+	userRooms map[string]map[string]struct{}
+}
+
+func newNamespace(tenant string) *namespace {
+	return &namespace{
+		tenant:    tenant,
+		clients:   make(map[Subscriber]struct{}),
+		users:     make(map[string]map[Subscriber]struct{}),
+		rooms:     make(map[string]map[Subscriber]struct{}),
+		roomIndex: newRoomTrie(),
+		userRooms: make(map[string]map[string]struct{}),
+	}
+}
+
+// namespace returns the namespace of a tenant, creating it when needed.
+func (h *Hub) namespace(tenant string) *namespace {
+	ns, ok := h.namespaces[tenant]
+	if !ok {
+		ns = newNamespace(tenant)
+		h.namespaces[tenant] = ns
+	}
+	return ns
+}
+
+// lookupNamespace returns the namespace of a tenant, or an empty one when the
+// tenant has no state, without creating it.
+func (h *Hub) lookupNamespace(tenant string) *namespace {
+	if ns, ok := h.namespaces[tenant]; ok {
+		return ns
+	}
+	return newNamespace(tenant)
+}
+
+// releaseNamespace drops the namespace once it holds no client and no room
+// assignment.
+func (h *Hub) releaseNamespace(ns *namespace) {
+	if len(ns.clients) == 0 && len(ns.userRooms) == 0 {
+		delete(h.namespaces, ns.tenant)
+	}
+}
+
+// userKey identifies the user of the client across tenants, e.g. for the
+// limiters shared by the connections of a user.
+func (c *Client) userKey() string {
+	if c.Tenant == "" {
+		return c.ID
+	}
+	return c.Tenant + "\x00" + c.ID
+}
@@ -0,0 +1,114 @@
+package sockets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yiannis54/go-socket-server/internal/metrics"
+	"github.com/yiannis54/go-socket-server/internal/middleware"
+)
+
+func TestHub_TenantIsolation(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), middleware.UserIDContextKey, "user")
+		ctx = context.WithValue(ctx, middleware.TenantContextKey, r.URL.Query().Get("tenant"))
+		ServeWs(hub, w, r.WithContext(ctx))
+	}))
+	defer s.Close()
+
+	dial := func(t *testing.T, tenant string) *websocket.Conn {
+		t.Helper()
+		ws, res, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"?tenant="+tenant, nil)
+		require.NoError(t, err)
+		res.Body.Close()
+		require.NoError(t, ws.WriteJSON(IncomingSubscription{Action: subscribeAction, Room: "orders"}))
+		return ws
+	}
+	// next returns the entity ID of the next message received on the
+	// connection. A read timeout breaks the connection, so isolation is
+	// asserted by the other tenant receiving a later message first.
+	pending := map[*websocket.Conn][]string{}
+	next := func(t *testing.T, ws *websocket.Conn) string {
+		t.Helper()
+		for len(pending[ws]) == 0 {
+			require.NoError(t, ws.SetReadDeadline(time.Now().Add(2*time.Second)))
+			_, incoming, err := ws.ReadMessage()
+			require.NoError(t, err)
+			decoder := json.NewDecoder(bytes.NewReader(incoming))
+			for decoder.More() {
+				msg := Message{}
+				require.NoError(t, decoder.Decode(&msg))
+				pending[ws] = append(pending[ws], msg.EntityID)
+			}
+		}
+		id := pending[ws][0]
+		pending[ws] = pending[ws][1:]
+		return id
+	}
+	private := func(tenant, entityID string) {
+		hub.Private <- &MessageWithUser{Message: Message{EntityID: entityID}, UserID: "user", Tenant: tenant}
+	}
+
+	acme := dial(t, "acme")
+	defer acme.Close()
+	globex := dial(t, "globex")
+	defer globex.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("should scope rooms to the tenant", func(t *testing.T) {
+		hub.Broadcast <- &MessageWithRoom{Message: Message{EntityID: "room"}, RoomName: ptr("orders"), Tenant: "acme"}
+		private("globex", "marker")
+		assert.Equal(t, "room", next(t, acme))
+		assert.Equal(t, "marker", next(t, globex))
+	})
+
+	t.Run("should scope users to the tenant", func(t *testing.T) {
+		private("globex", "private")
+		private("acme", "marker")
+		assert.Equal(t, "private", next(t, globex))
+		assert.Equal(t, "marker", next(t, acme))
+	})
+
+	t.Run("should scope broadcasts to the tenant", func(t *testing.T) {
+		batch := NewMessageBatch(Message{EntityID: "broadcast"}, nil, nil)
+		batch.Broadcast = true
+		batch.Tenant = "acme"
+		hub.Batch <- batch
+		report, err := batch.Report(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.Broadcast)
+		private("globex", "marker")
+		assert.Equal(t, "broadcast", next(t, acme))
+		assert.Equal(t, "marker", next(t, globex))
+	})
+
+	t.Run("should report metrics per tenant", func(t *testing.T) {
+		counters, ok := metrics.Tenants.Get("acme").(*expvar.Map)
+		require.True(t, ok)
+		assert.Equal(t, "1", counters.Get(metrics.TenantConnections).String())
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
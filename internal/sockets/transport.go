@@ -0,0 +1,138 @@
+package sockets
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/yiannis54/go-socket-server/internal/config"
+)
+
+// Transport holds the timeouts and buffer sizes of the hub connections.
+type Transport struct {
+	// WriteWait is the time allowed to write a message to the peer.
+	WriteWait time.Duration
+
+	// PongWait is the time allowed to read the next pong message from the
+	// peer.
+	PongWait time.Duration
+
+	// PingPeriod is how often the peer is pinged. Must be less than PongWait.
+	PingPeriod time.Duration
+
+	// MaxMessageSize is the maximum size in bytes of a frame read from the peer.
+	MaxMessageSize int64
+
+	// SendBuffer is the number of messages queued for a connection, past
+	// which it is closed as a slow consumer.
+	SendBuffer int
+
+	// ReadBufferSize and WriteBufferSize are the websocket I/O buffer sizes in bytes.
+	ReadBufferSize  int
+	WriteBufferSize int
+
+	// PollTimeout is how long a long poll is held open while no message arrives.
+	PollTimeout time.Duration
+
+	// PollSessionWindow is how long a long-polling client stays registered
+	// between two polls.
+	PollSessionWindow time.Duration
+
+	// SSEResumeWindow is how long a client whose event stream dropped stays
+	// registered, waiting for a reconnect to resume it.
+	SSEResumeWindow time.Duration
+}
+
+// defaultTransport is the transport of a hub without WithTransport.
+var defaultTransport = NewTransport(config.DefaultTransport())
+
+// NewTransport returns the transport of the configured settings.
+func NewTransport(c config.TransportConfig) Transport {
+	return Transport{
+		WriteWait:       c.WriteWait,
+		PongWait:        c.PongWait,
+		PingPeriod:      c.PingPeriod,
+		MaxMessageSize:  c.MaxMessageSize,
+		SendBuffer:      c.SendBuffer,
+		ReadBufferSize:  c.ReadBufferSize,
+		WriteBufferSize: c.WriteBufferSize,
+
+		PollTimeout:       c.PollTimeout,
+		PollSessionWindow: c.PollSessionWindow,
+		SSEResumeWindow:   c.SSEResumeWindow,
+	}
+}
+
+// WithTransport sets the timeouts and buffer sizes of the hub connections.
+// Zero fields keep their default.
+func WithTransport(transport Transport) Option {
+	return func(h *Hub) {
+		h.transport = h.transport.override(transport)
+	}
+}
+
+// override returns the transport with the non-zero fields of o.
+func (t Transport) override(o Transport) Transport {
+	if o.WriteWait > 0 {
+		t.WriteWait = o.WriteWait
+	}
+	if o.PongWait > 0 {
+		t.PongWait = o.PongWait
+	}
+	if o.PingPeriod > 0 {
+		t.PingPeriod = o.PingPeriod
+	}
+	if o.MaxMessageSize > 0 {
+		t.MaxMessageSize = o.MaxMessageSize
+	}
+	if o.SendBuffer > 0 {
+		t.SendBuffer = o.SendBuffer
+	}
+	if o.ReadBufferSize > 0 {
+		t.ReadBufferSize = o.ReadBufferSize
+	}
+	if o.WriteBufferSize > 0 {
+		t.WriteBufferSize = o.WriteBufferSize
+	}
+	if o.PollTimeout > 0 {
+		t.PollTimeout = o.PollTimeout
+	}
+	if o.PollSessionWindow > 0 {
+		t.PollSessionWindow = o.PollSessionWindow
+	}
+	if o.SSEResumeWindow > 0 {
+		t.SSEResumeWindow = o.SSEResumeWindow
+	}
+	return t
+}
+
+// settings returns the configured settings of the transport, so that it is
+// validated by the rules of the configuration.
+func (t Transport) settings() config.TransportConfig {
+	return config.TransportConfig{
+		WriteWait:       t.WriteWait,
+		PongWait:        t.PongWait,
+		PingPeriod:      t.PingPeriod,
+		MaxMessageSize:  t.MaxMessageSize,
+		SendBuffer:      t.SendBuffer,
+		ReadBufferSize:  t.ReadBufferSize,
+		WriteBufferSize: t.WriteBufferSize,
+
+		PollTimeout:       t.PollTimeout,
+		PollSessionWindow: t.PollSessionWindow,
+		SSEResumeWindow:   t.SSEResumeWindow,
+	}
+}
+
+// WsHandler returns the handler of a websocket route whose connections use
+// their own transport settings. Zero fields of transport keep the hub ones.
+// It fails when the merged settings are invalid, e.g. a PingPeriod past the
+// PongWait of the hub.
+func WsHandler(hub *Hub, transport Transport) (http.Handler, error) {
+	merged := hub.transport.override(transport)
+	if err := merged.settings().Validate(); err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWs(hub, merged, w, r)
+	}), nil
+}
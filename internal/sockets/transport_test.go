@@ -0,0 +1,87 @@
+package sockets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport_override(t *testing.T) {
+	hub := NewHub(WithTransport(Transport{MaxMessageSize: 4096, SendBuffer: 16}))
+	assert.Equal(t, int64(4096), hub.transport.MaxMessageSize)
+	assert.Equal(t, 16, hub.transport.SendBuffer)
+	assert.Equal(t, defaultTransport.PongWait, hub.transport.PongWait)
+
+	route := hub.transport.override(Transport{MaxMessageSize: 64})
+	assert.Equal(t, int64(64), route.MaxMessageSize)
+	assert.Equal(t, 16, route.SendBuffer)
+}
+
+func TestWsHandler(t *testing.T) {
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+	defer func() {
+		// Let the closed connections unregister before stopping the hub.
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		ServeWs(hub, w, r)
+	})
+	small, err := WsHandler(hub, Transport{MaxMessageSize: 16})
+	require.NoError(t, err)
+	mux.Handle("/ws/small", small)
+	s := httptest.NewServer(mux)
+	defer s.Close()
+	wsURL := "ws" + strings.TrimPrefix(s.URL, "http")
+	frame := []byte(`{"action":"enter","room":"order-updates"}`)
+
+	t.Run("should apply the read limit of the route", func(t *testing.T) {
+		ws, res, err := websocket.DefaultDialer.Dial(wsURL+"/ws/small", nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		res.Body.Close()
+
+		require.NoError(t, ws.WriteMessage(websocket.TextMessage, frame))
+		_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+		_, _, err = ws.ReadMessage()
+		assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "got %v", err)
+	})
+
+	t.Run("should keep the hub transport on other routes", func(t *testing.T) {
+		ws, res, err := websocket.DefaultDialer.Dial(wsURL+"/ws", nil)
+		require.NoError(t, err)
+		defer ws.Close()
+		res.Body.Close()
+
+		require.NoError(t, ws.WriteMessage(websocket.TextMessage, frame))
+		room := "order-updates"
+		require.Eventually(t, func() bool {
+			var members int
+			_ = hub.call(ctx, func() { members = len(hub.lookupNamespace("").roomMembers(room)) })
+			return members == 1
+		}, time.Second, 10*time.Millisecond)
+		hub.Broadcast <- NewRoomMessage(TypeInfo, "order-1", room, nil)
+
+		_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+		_, data, err := ws.ReadMessage()
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "order-1")
+	})
+
+	t.Run("should reject a route whose merged transport is invalid", func(t *testing.T) {
+		// Pinged after the pong wait of the hub runs out.
+		_, err := WsHandler(hub, Transport{PingPeriod: 2 * defaultTransport.PongWait})
+		assert.ErrorContains(t, err, "pingPeriod")
+	})
+}
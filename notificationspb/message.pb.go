@@ -8,9 +8,12 @@ package notificationspb
 
 import (
 	any1 "github.com/golang/protobuf/ptypes/any"
+	duration "github.com/golang/protobuf/ptypes/duration"
 	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -80,9 +83,23 @@ type Message struct {
 	Type     MessageType            `protobuf:"varint,1,opt,name=type,proto3,enum=notifications.MessageType" json:"type,omitempty"`
 	EntityId string                 `protobuf:"bytes,2,opt,name=entityId,proto3" json:"entityId,omitempty"`
 	// Use Any for flexible/dynamic message content
-	Message       *any1.Any `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	Message *any1.Any `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// Optional delivery time. When set in the future the message is held by
+	// the server and released at that time. deliverAt takes precedence over delay.
+	DeliverAt *timestamp.Timestamp `protobuf:"bytes,4,opt,name=deliverAt,proto3" json:"deliverAt,omitempty"`
+	Delay     *duration.Duration   `protobuf:"bytes,5,opt,name=delay,proto3" json:"delay,omitempty"`
+	// Optional ID of the scheduled notification, generated when empty.
+	ScheduleId string `protobuf:"bytes,6,opt,name=scheduleId,proto3" json:"scheduleId,omitempty"`
+	// Optional expiry. A message not delivered by then is dropped. expiresAt
+	// takes precedence over ttl, which counts from when the message is published
+	// to the hub, i.e. from its delivery time when scheduled.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=expiresAt,proto3" json:"expiresAt,omitempty"`
+	Ttl       *duration.Duration   `protobuf:"bytes,8,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// Optional key identifying the publish. A retried publish carrying a key
+	// already seen within the deduplication window is acknowledged but not sent again.
+	IdempotencyKey string `protobuf:"bytes,9,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Message) Reset() {
@@ -136,6 +153,48 @@ func (x *Message) GetMessage() *any1.Any {
 	return nil
 }
 
+func (x *Message) GetDeliverAt() *timestamp.Timestamp {
+	if x != nil {
+		return x.DeliverAt
+	}
+	return nil
+}
+
+func (x *Message) GetDelay() *duration.Duration {
+	if x != nil {
+		return x.Delay
+	}
+	return nil
+}
+
+func (x *Message) GetScheduleId() string {
+	if x != nil {
+		return x.ScheduleId
+	}
+	return ""
+}
+
+func (x *Message) GetExpiresAt() *timestamp.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *Message) GetTtl() *duration.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+func (x *Message) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 // Message with a room field.
 type MessageWithRoom struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -243,88 +302,1959 @@ func (x *MessageWithUser) GetUserId() string {
 	return ""
 }
 
-var File_notificationspb_message_proto protoreflect.FileDescriptor
+// Message with a list of user IDs.
+type MessageWithUsers struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Base          *Message               `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	UserIds       []string               `protobuf:"bytes,2,rep,name=userIds,proto3" json:"userIds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_notificationspb_message_proto_rawDesc = "" +
-	"\n" +
-	"\x1dnotificationspb/message.proto\x12\rnotifications\x1a\x19google/protobuf/any.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x85\x01\n" +
-	"\aMessage\x12.\n" +
-	"\x04type\x18\x01 \x01(\x0e2\x1a.notifications.MessageTypeR\x04type\x12\x1a\n" +
-	"\bentityId\x18\x02 \x01(\tR\bentityId\x12.\n" +
-	"\amessage\x18\x03 \x01(\v2\x14.google.protobuf.AnyR\amessage\"_\n" +
-	"\x0fMessageWithRoom\x12*\n" +
-	"\x04base\x18\x01 \x01(\v2\x16.notifications.MessageR\x04base\x12\x17\n" +
-	"\x04room\x18\x02 \x01(\tH\x00R\x04room\x88\x01\x01B\a\n" +
-	"\x05_room\"U\n" +
-	"\x0fMessageWithUser\x12*\n" +
-	"\x04base\x18\x01 \x01(\v2\x16.notifications.MessageR\x04base\x12\x16\n" +
-	"\x06userId\x18\x02 \x01(\tR\x06userId*J\n" +
-	"\vMessageType\x12\x1c\n" +
-	"\x18MESSAGE_TYPE_UNSPECIFIED\x10\x00\x12\x0e\n" +
-	"\n" +
-	"TYPE_ERROR\x10\x01\x12\r\n" +
-	"\tTYPE_INFO\x10\x022\xe1\x01\n" +
-	"\x13NotificationService\x12;\n" +
-	"\tBroadcast\x12\x16.notifications.Message\x1a\x16.google.protobuf.Empty\x12D\n" +
-	"\n" +
-	"NotifyRoom\x12\x1e.notifications.MessageWithRoom\x1a\x16.google.protobuf.Empty\x12G\n" +
-	"\rPrivateNotify\x12\x1e.notifications.MessageWithUser\x1a\x16.google.protobuf.EmptyB\x13Z\x11./notificationspbb\x06proto3"
+func (x *MessageWithUsers) Reset() {
+	*x = MessageWithUsers{}
+	mi := &file_notificationspb_message_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
 
-var (
-	file_notificationspb_message_proto_rawDescOnce sync.Once
-	file_notificationspb_message_proto_rawDescData []byte
-)
+func (x *MessageWithUsers) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
 
-func file_notificationspb_message_proto_rawDescGZIP() []byte {
-	file_notificationspb_message_proto_rawDescOnce.Do(func() {
-		file_notificationspb_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_notificationspb_message_proto_rawDesc), len(file_notificationspb_message_proto_rawDesc)))
-	})
-	return file_notificationspb_message_proto_rawDescData
+func (*MessageWithUsers) ProtoMessage() {}
+
+func (x *MessageWithUsers) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-var file_notificationspb_message_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_notificationspb_message_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
-var file_notificationspb_message_proto_goTypes = []any{
-	(MessageType)(0),        // 0: notifications.MessageType
-	(*Message)(nil),         // 1: notifications.Message
-	(*MessageWithRoom)(nil), // 2: notifications.MessageWithRoom
-	(*MessageWithUser)(nil), // 3: notifications.MessageWithUser
-	(*any1.Any)(nil),        // 4: google.protobuf.Any
-	(*empty.Empty)(nil),     // 5: google.protobuf.Empty
+// Deprecated: Use MessageWithUsers.ProtoReflect.Descriptor instead.
+func (*MessageWithUsers) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{3}
 }
-var file_notificationspb_message_proto_depIdxs = []int32{
-	0, // 0: notifications.Message.type:type_name -> notifications.MessageType
-	4, // 1: notifications.Message.message:type_name -> google.protobuf.Any
-	1, // 2: notifications.MessageWithRoom.base:type_name -> notifications.Message
-	1, // 3: notifications.MessageWithUser.base:type_name -> notifications.Message
-	1, // 4: notifications.NotificationService.Broadcast:input_type -> notifications.Message
-	2, // 5: notifications.NotificationService.NotifyRoom:input_type -> notifications.MessageWithRoom
-	3, // 6: notifications.NotificationService.PrivateNotify:input_type -> notifications.MessageWithUser
-	5, // 7: notifications.NotificationService.Broadcast:output_type -> google.protobuf.Empty
-	5, // 8: notifications.NotificationService.NotifyRoom:output_type -> google.protobuf.Empty
-	5, // 9: notifications.NotificationService.PrivateNotify:output_type -> google.protobuf.Empty
-	7, // [7:10] is the sub-list for method output_type
-	4, // [4:7] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+
+func (x *MessageWithUsers) GetBase() *Message {
+	if x != nil {
+		return x.Base
+	}
+	return nil
 }
 
-func init() { file_notificationspb_message_proto_init() }
-func file_notificationspb_message_proto_init() {
-	if File_notificationspb_message_proto != nil {
-		return
+func (x *MessageWithUsers) GetUserIds() []string {
+	if x != nil {
+		return x.UserIds
+	}
+	return nil
+}
+
+// Message with a list of rooms.
+type MessageWithRooms struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Base          *Message               `protobuf:"bytes,1,opt,name=base,proto3" json:"base,omitempty"`
+	Rooms         []string               `protobuf:"bytes,2,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageWithRooms) Reset() {
+	*x = MessageWithRooms{}
+	mi := &file_notificationspb_message_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageWithRooms) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageWithRooms) ProtoMessage() {}
+
+func (x *MessageWithRooms) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageWithRooms.ProtoReflect.Descriptor instead.
+func (*MessageWithRooms) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MessageWithRooms) GetBase() *Message {
+	if x != nil {
+		return x.Base
+	}
+	return nil
+}
+
+func (x *MessageWithRooms) GetRooms() []string {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+// A frame written to websocket clients that negotiated the protobuf
+// subprotocol: either a notification or a server event.
+type SocketFrame struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Frame:
+	//
+	//	*SocketFrame_Notification
+	//	*SocketFrame_Event
+	Frame         isSocketFrame_Frame `protobuf_oneof:"frame"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SocketFrame) Reset() {
+	*x = SocketFrame{}
+	mi := &file_notificationspb_message_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SocketFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SocketFrame) ProtoMessage() {}
+
+func (x *SocketFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SocketFrame.ProtoReflect.Descriptor instead.
+func (*SocketFrame) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SocketFrame) GetFrame() isSocketFrame_Frame {
+	if x != nil {
+		return x.Frame
+	}
+	return nil
+}
+
+func (x *SocketFrame) GetNotification() *Message {
+	if x != nil {
+		if x, ok := x.Frame.(*SocketFrame_Notification); ok {
+			return x.Notification
+		}
+	}
+	return nil
+}
+
+func (x *SocketFrame) GetEvent() *SocketEvent {
+	if x != nil {
+		if x, ok := x.Frame.(*SocketFrame_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+type isSocketFrame_Frame interface {
+	isSocketFrame_Frame()
+}
+
+type SocketFrame_Notification struct {
+	Notification *Message `protobuf:"bytes,1,opt,name=notification,proto3,oneof"`
+}
+
+type SocketFrame_Event struct {
+	Event *SocketEvent `protobuf:"bytes,2,opt,name=event,proto3,oneof"`
+}
+
+func (*SocketFrame_Notification) isSocketFrame_Frame() {}
+
+func (*SocketFrame_Event) isSocketFrame_Frame() {}
+
+// A server event sent to a single connection, e.g. "token_expiring".
+type SocketEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Event         string                 `protobuf:"bytes,1,opt,name=event,proto3" json:"event,omitempty"`
+	Data          *structpb.Struct       `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SocketEvent) Reset() {
+	*x = SocketEvent{}
+	mi := &file_notificationspb_message_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SocketEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SocketEvent) ProtoMessage() {}
+
+func (x *SocketEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SocketEvent.ProtoReflect.Descriptor instead.
+func (*SocketEvent) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SocketEvent) GetEvent() string {
+	if x != nil {
+		return x.Event
+	}
+	return ""
+}
+
+func (x *SocketEvent) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// A single publish on a PublishStream.
+type PublishRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Target:
+	//
+	//	*PublishRequest_Broadcast
+	//	*PublishRequest_Room
+	//	*PublishRequest_User
+	//	*PublishRequest_Users
+	//	*PublishRequest_Rooms
+	Target        isPublishRequest_Target `protobuf_oneof:"target"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishRequest) Reset() {
+	*x = PublishRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishRequest) ProtoMessage() {}
+
+func (x *PublishRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishRequest.ProtoReflect.Descriptor instead.
+func (*PublishRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PublishRequest) GetTarget() isPublishRequest_Target {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetBroadcast() *Message {
+	if x != nil {
+		if x, ok := x.Target.(*PublishRequest_Broadcast); ok {
+			return x.Broadcast
+		}
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetRoom() *MessageWithRoom {
+	if x != nil {
+		if x, ok := x.Target.(*PublishRequest_Room); ok {
+			return x.Room
+		}
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetUser() *MessageWithUser {
+	if x != nil {
+		if x, ok := x.Target.(*PublishRequest_User); ok {
+			return x.User
+		}
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetUsers() *MessageWithUsers {
+	if x != nil {
+		if x, ok := x.Target.(*PublishRequest_Users); ok {
+			return x.Users
+		}
+	}
+	return nil
+}
+
+func (x *PublishRequest) GetRooms() *MessageWithRooms {
+	if x != nil {
+		if x, ok := x.Target.(*PublishRequest_Rooms); ok {
+			return x.Rooms
+		}
+	}
+	return nil
+}
+
+type isPublishRequest_Target interface {
+	isPublishRequest_Target()
+}
+
+type PublishRequest_Broadcast struct {
+	Broadcast *Message `protobuf:"bytes,1,opt,name=broadcast,proto3,oneof"`
+}
+
+type PublishRequest_Room struct {
+	Room *MessageWithRoom `protobuf:"bytes,2,opt,name=room,proto3,oneof"`
+}
+
+type PublishRequest_User struct {
+	User *MessageWithUser `protobuf:"bytes,3,opt,name=user,proto3,oneof"`
+}
+
+type PublishRequest_Users struct {
+	Users *MessageWithUsers `protobuf:"bytes,4,opt,name=users,proto3,oneof"`
+}
+
+type PublishRequest_Rooms struct {
+	Rooms *MessageWithRooms `protobuf:"bytes,5,opt,name=rooms,proto3,oneof"`
+}
+
+func (*PublishRequest_Broadcast) isPublishRequest_Target() {}
+
+func (*PublishRequest_Room) isPublishRequest_Target() {}
+
+func (*PublishRequest_User) isPublishRequest_Target() {}
+
+func (*PublishRequest_Users) isPublishRequest_Target() {}
+
+func (*PublishRequest_Rooms) isPublishRequest_Target() {}
+
+// Number of connections a message was delivered to for one target.
+type Delivery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        string                 `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Delivered     int32                  `protobuf:"varint,2,opt,name=delivered,proto3" json:"delivered,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Delivery) Reset() {
+	*x = Delivery{}
+	mi := &file_notificationspb_message_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Delivery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Delivery) ProtoMessage() {}
+
+func (x *Delivery) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Delivery.ProtoReflect.Descriptor instead.
+func (*Delivery) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Delivery) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *Delivery) GetDelivered() int32 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+// Delivery counts per target of a publish.
+type DeliveryReport struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Users     []*Delivery            `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	Rooms     []*Delivery            `protobuf:"bytes,2,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	Broadcast int32                  `protobuf:"varint,3,opt,name=broadcast,proto3" json:"broadcast,omitempty"`
+	// IDs of the publishes held for later delivery instead of being sent.
+	Scheduled     []string `protobuf:"bytes,4,rep,name=scheduled,proto3" json:"scheduled,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeliveryReport) Reset() {
+	*x = DeliveryReport{}
+	mi := &file_notificationspb_message_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeliveryReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeliveryReport) ProtoMessage() {}
+
+func (x *DeliveryReport) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeliveryReport.ProtoReflect.Descriptor instead.
+func (*DeliveryReport) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeliveryReport) GetUsers() []*Delivery {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+func (x *DeliveryReport) GetRooms() []*Delivery {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+func (x *DeliveryReport) GetBroadcast() int32 {
+	if x != nil {
+		return x.Broadcast
+	}
+	return 0
+}
+
+func (x *DeliveryReport) GetScheduled() []string {
+	if x != nil {
+		return x.Scheduled
+	}
+	return nil
+}
+
+// A notification held by the server until its delivery time.
+type ScheduledNotification struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DeliverAt *timestamp.Timestamp   `protobuf:"bytes,2,opt,name=deliverAt,proto3" json:"deliverAt,omitempty"`
+	Publish   *PublishRequest        `protobuf:"bytes,3,opt,name=publish,proto3" json:"publish,omitempty"`
+	// Tenant of the caller that scheduled the notification.
+	Tenant        string `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScheduledNotification) Reset() {
+	*x = ScheduledNotification{}
+	mi := &file_notificationspb_message_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduledNotification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduledNotification) ProtoMessage() {}
+
+func (x *ScheduledNotification) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduledNotification.ProtoReflect.Descriptor instead.
+func (*ScheduledNotification) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ScheduledNotification) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ScheduledNotification) GetDeliverAt() *timestamp.Timestamp {
+	if x != nil {
+		return x.DeliverAt
+	}
+	return nil
+}
+
+func (x *ScheduledNotification) GetPublish() *PublishRequest {
+	if x != nil {
+		return x.Publish
+	}
+	return nil
+}
+
+func (x *ScheduledNotification) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type ScheduledList struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Notifications []*ScheduledNotification `protobuf:"bytes,1,rep,name=notifications,proto3" json:"notifications,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScheduledList) Reset() {
+	*x = ScheduledList{}
+	mi := &file_notificationspb_message_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduledList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduledList) ProtoMessage() {}
+
+func (x *ScheduledList) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduledList.ProtoReflect.Descriptor instead.
+func (*ScheduledList) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ScheduledList) GetNotifications() []*ScheduledNotification {
+	if x != nil {
+		return x.Notifications
+	}
+	return nil
+}
+
+type CancelScheduledRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelScheduledRequest) Reset() {
+	*x = CancelScheduledRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelScheduledRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelScheduledRequest) ProtoMessage() {}
+
+func (x *CancelScheduledRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelScheduledRequest.ProtoReflect.Descriptor instead.
+func (*CancelScheduledRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CancelScheduledRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RevokeUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeUserRequest) Reset() {
+	*x = RevokeUserRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeUserRequest) ProtoMessage() {}
+
+func (x *RevokeUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeUserRequest.ProtoReflect.Descriptor instead.
+func (*RevokeUserRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RevokeUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+// Number of connections of the user that were closed.
+type RevokeUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Disconnected  int32                  `protobuf:"varint,1,opt,name=disconnected,proto3" json:"disconnected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RevokeUserResponse) Reset() {
+	*x = RevokeUserResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RevokeUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevokeUserResponse) ProtoMessage() {}
+
+func (x *RevokeUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevokeUserResponse.ProtoReflect.Descriptor instead.
+func (*RevokeUserResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *RevokeUserResponse) GetDisconnected() int32 {
+	if x != nil {
+		return x.Disconnected
+	}
+	return 0
+}
+
+// Rooms assigned to, or removed from, every session of a user.
+type UserRoomsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Rooms         []string               `protobuf:"bytes,2,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserRoomsRequest) Reset() {
+	*x = UserRoomsRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserRoomsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserRoomsRequest) ProtoMessage() {}
+
+func (x *UserRoomsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserRoomsRequest.ProtoReflect.Descriptor instead.
+func (*UserRoomsRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UserRoomsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UserRoomsRequest) GetRooms() []string {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+// Number of live sessions of the user the change was applied to.
+type UserRoomsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      int32                  `protobuf:"varint,1,opt,name=sessions,proto3" json:"sessions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserRoomsResponse) Reset() {
+	*x = UserRoomsResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserRoomsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserRoomsResponse) ProtoMessage() {}
+
+func (x *UserRoomsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserRoomsResponse.ProtoReflect.Descriptor instead.
+func (*UserRoomsResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UserRoomsResponse) GetSessions() int32 {
+	if x != nil {
+		return x.Sessions
+	}
+	return 0
+}
+
+// A websocket connection held by the hub.
+type Connection struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId      string                 `protobuf:"bytes,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	Ip          string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
+	ConnectedAt *timestamp.Timestamp   `protobuf:"bytes,4,opt,name=connectedAt,proto3" json:"connectedAt,omitempty"`
+	Rooms       []string               `protobuf:"bytes,5,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	// Number of messages waiting to be written to the connection.
+	QueueDepth    int32  `protobuf:"varint,6,opt,name=queueDepth,proto3" json:"queueDepth,omitempty"`
+	Tenant        string `protobuf:"bytes,7,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Connection) Reset() {
+	*x = Connection{}
+	mi := &file_notificationspb_message_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Connection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Connection) ProtoMessage() {}
+
+func (x *Connection) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Connection.ProtoReflect.Descriptor instead.
+func (*Connection) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Connection) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Connection) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Connection) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Connection) GetConnectedAt() *timestamp.Timestamp {
+	if x != nil {
+		return x.ConnectedAt
+	}
+	return nil
+}
+
+func (x *Connection) GetRooms() []string {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+func (x *Connection) GetQueueDepth() int32 {
+	if x != nil {
+		return x.QueueDepth
+	}
+	return 0
+}
+
+func (x *Connection) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type ListConnectionsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Maximum number of connections returned, 100 when unset.
+	PageSize int32 `protobuf:"varint,1,opt,name=pageSize,proto3" json:"pageSize,omitempty"`
+	// nextPageToken of the previous page.
+	PageToken string `protobuf:"bytes,2,opt,name=pageToken,proto3" json:"pageToken,omitempty"`
+	// Only list the connections of this user.
+	UserId        string `protobuf:"bytes,3,opt,name=userId,proto3" json:"userId,omitempty"`
+	Tenant        string `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConnectionsRequest) Reset() {
+	*x = ListConnectionsRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConnectionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConnectionsRequest) ProtoMessage() {}
+
+func (x *ListConnectionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConnectionsRequest.ProtoReflect.Descriptor instead.
+func (*ListConnectionsRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListConnectionsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListConnectionsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListConnectionsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListConnectionsRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type ListConnectionsResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Connections []*Connection          `protobuf:"bytes,1,rep,name=connections,proto3" json:"connections,omitempty"`
+	// Token of the next page, empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=nextPageToken,proto3" json:"nextPageToken,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConnectionsResponse) Reset() {
+	*x = ListConnectionsResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConnectionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConnectionsResponse) ProtoMessage() {}
+
+func (x *ListConnectionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConnectionsResponse.ProtoReflect.Descriptor instead.
+func (*ListConnectionsResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListConnectionsResponse) GetConnections() []*Connection {
+	if x != nil {
+		return x.Connections
+	}
+	return nil
+}
+
+func (x *ListConnectionsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type RoomSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Members       int32                  `protobuf:"varint,2,opt,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoomSummary) Reset() {
+	*x = RoomSummary{}
+	mi := &file_notificationspb_message_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoomSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoomSummary) ProtoMessage() {}
+
+func (x *RoomSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoomSummary.ProtoReflect.Descriptor instead.
+func (*RoomSummary) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RoomSummary) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RoomSummary) GetMembers() int32 {
+	if x != nil {
+		return x.Members
+	}
+	return 0
+}
+
+type ListRoomsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tenant        string                 `protobuf:"bytes,1,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoomsRequest) Reset() {
+	*x = ListRoomsRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoomsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoomsRequest) ProtoMessage() {}
+
+func (x *ListRoomsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoomsRequest.ProtoReflect.Descriptor instead.
+func (*ListRoomsRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListRoomsRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type ListRoomsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rooms         []*RoomSummary         `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoomsResponse) Reset() {
+	*x = ListRoomsResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoomsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoomsResponse) ProtoMessage() {}
+
+func (x *ListRoomsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoomsResponse.ProtoReflect.Descriptor instead.
+func (*ListRoomsResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListRoomsResponse) GetRooms() []*RoomSummary {
+	if x != nil {
+		return x.Rooms
+	}
+	return nil
+}
+
+type GetRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Tenant        string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoomRequest) Reset() {
+	*x = GetRoomRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoomRequest) ProtoMessage() {}
+
+func (x *GetRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoomRequest.ProtoReflect.Descriptor instead.
+func (*GetRoomRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *GetRoomRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetRoomRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type Room struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Members       []*Connection          `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Room) Reset() {
+	*x = Room{}
+	mi := &file_notificationspb_message_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Room) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Room) ProtoMessage() {}
+
+func (x *Room) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Room.ProtoReflect.Descriptor instead.
+func (*Room) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *Room) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Room) GetMembers() []*Connection {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type DisconnectConnectionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectConnectionRequest) Reset() {
+	*x = DisconnectConnectionRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectConnectionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectConnectionRequest) ProtoMessage() {}
+
+func (x *DisconnectConnectionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectConnectionRequest.ProtoReflect.Descriptor instead.
+func (*DisconnectConnectionRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DisconnectConnectionRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DisconnectUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=userId,proto3" json:"userId,omitempty"`
+	Tenant        string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectUserRequest) Reset() {
+	*x = DisconnectUserRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectUserRequest) ProtoMessage() {}
+
+func (x *DisconnectUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectUserRequest.ProtoReflect.Descriptor instead.
+func (*DisconnectUserRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *DisconnectUserRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *DisconnectUserRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type DisconnectUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Disconnected  int32                  `protobuf:"varint,1,opt,name=disconnected,proto3" json:"disconnected,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectUserResponse) Reset() {
+	*x = DisconnectUserResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectUserResponse) ProtoMessage() {}
+
+func (x *DisconnectUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectUserResponse.ProtoReflect.Descriptor instead.
+func (*DisconnectUserResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DisconnectUserResponse) GetDisconnected() int32 {
+	if x != nil {
+		return x.Disconnected
+	}
+	return 0
+}
+
+// Removes a connection, or all connections of a user, from a room.
+type KickFromRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Room          string                 `protobuf:"bytes,1,opt,name=room,proto3" json:"room,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=userId,proto3" json:"userId,omitempty"`
+	ConnectionId  string                 `protobuf:"bytes,3,opt,name=connectionId,proto3" json:"connectionId,omitempty"`
+	Tenant        string                 `protobuf:"bytes,4,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KickFromRoomRequest) Reset() {
+	*x = KickFromRoomRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KickFromRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KickFromRoomRequest) ProtoMessage() {}
+
+func (x *KickFromRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KickFromRoomRequest.ProtoReflect.Descriptor instead.
+func (*KickFromRoomRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *KickFromRoomRequest) GetRoom() string {
+	if x != nil {
+		return x.Room
+	}
+	return ""
+}
+
+func (x *KickFromRoomRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *KickFromRoomRequest) GetConnectionId() string {
+	if x != nil {
+		return x.ConnectionId
+	}
+	return ""
+}
+
+func (x *KickFromRoomRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type KickFromRoomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       int32                  `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KickFromRoomResponse) Reset() {
+	*x = KickFromRoomResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KickFromRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KickFromRoomResponse) ProtoMessage() {}
+
+func (x *KickFromRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KickFromRoomResponse.ProtoReflect.Descriptor instead.
+func (*KickFromRoomResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *KickFromRoomResponse) GetRemoved() int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+type CloseRoomRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Tenant        string                 `protobuf:"bytes,2,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseRoomRequest) Reset() {
+	*x = CloseRoomRequest{}
+	mi := &file_notificationspb_message_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseRoomRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseRoomRequest) ProtoMessage() {}
+
+func (x *CloseRoomRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseRoomRequest.ProtoReflect.Descriptor instead.
+func (*CloseRoomRequest) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *CloseRoomRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CloseRoomRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type CloseRoomResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Removed       int32                  `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloseRoomResponse) Reset() {
+	*x = CloseRoomResponse{}
+	mi := &file_notificationspb_message_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloseRoomResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseRoomResponse) ProtoMessage() {}
+
+func (x *CloseRoomResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notificationspb_message_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseRoomResponse.ProtoReflect.Descriptor instead.
+func (*CloseRoomResponse) Descriptor() ([]byte, []int) {
+	return file_notificationspb_message_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *CloseRoomResponse) GetRemoved() int32 {
+	if x != nil {
+		return x.Removed
+	}
+	return 0
+}
+
+var File_notificationspb_message_proto protoreflect.FileDescriptor
+
+const file_notificationspb_message_proto_rawDesc = "" +
+	"\n" +
+	"\x1dnotificationspb/message.proto\x12\rnotifications\x1a\x19google/protobuf/any.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9f\x03\n" +
+	"\aMessage\x12.\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1a.notifications.MessageTypeR\x04type\x12\x1a\n" +
+	"\bentityId\x18\x02 \x01(\tR\bentityId\x12.\n" +
+	"\amessage\x18\x03 \x01(\v2\x14.google.protobuf.AnyR\amessage\x128\n" +
+	"\tdeliverAt\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tdeliverAt\x12/\n" +
+	"\x05delay\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\x05delay\x12\x1e\n" +
+	"\n" +
+	"scheduleId\x18\x06 \x01(\tR\n" +
+	"scheduleId\x128\n" +
+	"\texpiresAt\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12+\n" +
+	"\x03ttl\x18\b \x01(\v2\x19.google.protobuf.DurationR\x03ttl\x12&\n" +
+	"\x0eidempotencyKey\x18\t \x01(\tR\x0eidempotencyKey\"_\n" +
+	"\x0fMessageWithRoom\x12*\n" +
+	"\x04base\x18\x01 \x01(\v2\x16.notifications.MessageR\x04base\x12\x17\n" +
+	"\x04room\x18\x02 \x01(\tH\x00R\x04room\x88\x01\x01B\a\n" +
+	"\x05_room\"U\n" +
+	"\x0fMessageWithUser\x12*\n" +
+	"\x04base\x18\x01 \x01(\v2\x16.notifications.MessageR\x04base\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\tR\x06userId\"X\n" +
+	"\x10MessageWithUsers\x12*\n" +
+	"\x04base\x18\x01 \x01(\v2\x16.notifications.MessageR\x04base\x12\x18\n" +
+	"\auserIds\x18\x02 \x03(\tR\auserIds\"T\n" +
+	"\x10MessageWithRooms\x12*\n" +
+	"\x04base\x18\x01 \x01(\v2\x16.notifications.MessageR\x04base\x12\x14\n" +
+	"\x05rooms\x18\x02 \x03(\tR\x05rooms\"\x88\x01\n" +
+	"\vSocketFrame\x12<\n" +
+	"\fnotification\x18\x01 \x01(\v2\x16.notifications.MessageH\x00R\fnotification\x122\n" +
+	"\x05event\x18\x02 \x01(\v2\x1a.notifications.SocketEventH\x00R\x05eventB\a\n" +
+	"\x05frame\"P\n" +
+	"\vSocketEvent\x12\x14\n" +
+	"\x05event\x18\x01 \x01(\tR\x05event\x12+\n" +
+	"\x04data\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x04data\"\xb0\x02\n" +
+	"\x0ePublishRequest\x126\n" +
+	"\tbroadcast\x18\x01 \x01(\v2\x16.notifications.MessageH\x00R\tbroadcast\x124\n" +
+	"\x04room\x18\x02 \x01(\v2\x1e.notifications.MessageWithRoomH\x00R\x04room\x124\n" +
+	"\x04user\x18\x03 \x01(\v2\x1e.notifications.MessageWithUserH\x00R\x04user\x127\n" +
+	"\x05users\x18\x04 \x01(\v2\x1f.notifications.MessageWithUsersH\x00R\x05users\x127\n" +
+	"\x05rooms\x18\x05 \x01(\v2\x1f.notifications.MessageWithRoomsH\x00R\x05roomsB\b\n" +
+	"\x06target\"@\n" +
+	"\bDelivery\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x1c\n" +
+	"\tdelivered\x18\x02 \x01(\x05R\tdelivered\"\xaa\x01\n" +
+	"\x0eDeliveryReport\x12-\n" +
+	"\x05users\x18\x01 \x03(\v2\x17.notifications.DeliveryR\x05users\x12-\n" +
+	"\x05rooms\x18\x02 \x03(\v2\x17.notifications.DeliveryR\x05rooms\x12\x1c\n" +
+	"\tbroadcast\x18\x03 \x01(\x05R\tbroadcast\x12\x1c\n" +
+	"\tscheduled\x18\x04 \x03(\tR\tscheduled\"\xb2\x01\n" +
+	"\x15ScheduledNotification\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x128\n" +
+	"\tdeliverAt\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tdeliverAt\x127\n" +
+	"\apublish\x18\x03 \x01(\v2\x1d.notifications.PublishRequestR\apublish\x12\x16\n" +
+	"\x06tenant\x18\x04 \x01(\tR\x06tenant\"[\n" +
+	"\rScheduledList\x12J\n" +
+	"\rnotifications\x18\x01 \x03(\v2$.notifications.ScheduledNotificationR\rnotifications\"(\n" +
+	"\x16CancelScheduledRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"+\n" +
+	"\x11RevokeUserRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\tR\x06userId\"8\n" +
+	"\x12RevokeUserResponse\x12\"\n" +
+	"\fdisconnected\x18\x01 \x01(\x05R\fdisconnected\"@\n" +
+	"\x10UserRoomsRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05rooms\x18\x02 \x03(\tR\x05rooms\"/\n" +
+	"\x11UserRoomsResponse\x12\x1a\n" +
+	"\bsessions\x18\x01 \x01(\x05R\bsessions\"\xd0\x01\n" +
+	"\n" +
+	"Connection\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\tR\x06userId\x12\x0e\n" +
+	"\x02ip\x18\x03 \x01(\tR\x02ip\x12<\n" +
+	"\vconnectedAt\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\vconnectedAt\x12\x14\n" +
+	"\x05rooms\x18\x05 \x03(\tR\x05rooms\x12\x1e\n" +
+	"\n" +
+	"queueDepth\x18\x06 \x01(\x05R\n" +
+	"queueDepth\x12\x16\n" +
+	"\x06tenant\x18\a \x01(\tR\x06tenant\"\x82\x01\n" +
+	"\x16ListConnectionsRequest\x12\x1a\n" +
+	"\bpageSize\x18\x01 \x01(\x05R\bpageSize\x12\x1c\n" +
+	"\tpageToken\x18\x02 \x01(\tR\tpageToken\x12\x16\n" +
+	"\x06userId\x18\x03 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06tenant\x18\x04 \x01(\tR\x06tenant\"|\n" +
+	"\x17ListConnectionsResponse\x12;\n" +
+	"\vconnections\x18\x01 \x03(\v2\x19.notifications.ConnectionR\vconnections\x12$\n" +
+	"\rnextPageToken\x18\x02 \x01(\tR\rnextPageToken\";\n" +
+	"\vRoomSummary\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\amembers\x18\x02 \x01(\x05R\amembers\"*\n" +
+	"\x10ListRoomsRequest\x12\x16\n" +
+	"\x06tenant\x18\x01 \x01(\tR\x06tenant\"E\n" +
+	"\x11ListRoomsResponse\x120\n" +
+	"\x05rooms\x18\x01 \x03(\v2\x1a.notifications.RoomSummaryR\x05rooms\"<\n" +
+	"\x0eGetRoomRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\"O\n" +
+	"\x04Room\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x123\n" +
+	"\amembers\x18\x02 \x03(\v2\x19.notifications.ConnectionR\amembers\"-\n" +
+	"\x1bDisconnectConnectionRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"G\n" +
+	"\x15DisconnectUserRequest\x12\x16\n" +
+	"\x06userId\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\"<\n" +
+	"\x16DisconnectUserResponse\x12\"\n" +
+	"\fdisconnected\x18\x01 \x01(\x05R\fdisconnected\"}\n" +
+	"\x13KickFromRoomRequest\x12\x12\n" +
+	"\x04room\x18\x01 \x01(\tR\x04room\x12\x16\n" +
+	"\x06userId\x18\x02 \x01(\tR\x06userId\x12\"\n" +
+	"\fconnectionId\x18\x03 \x01(\tR\fconnectionId\x12\x16\n" +
+	"\x06tenant\x18\x04 \x01(\tR\x06tenant\"0\n" +
+	"\x14KickFromRoomResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\x05R\aremoved\">\n" +
+	"\x10CloseRoomRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06tenant\x18\x02 \x01(\tR\x06tenant\"-\n" +
+	"\x11CloseRoomResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\x05R\aremoved*J\n" +
+	"\vMessageType\x12\x1c\n" +
+	"\x18MESSAGE_TYPE_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"TYPE_ERROR\x10\x01\x12\r\n" +
+	"\tTYPE_INFO\x10\x022\xe6\x06\n" +
+	"\x13NotificationService\x12;\n" +
+	"\tBroadcast\x12\x16.notifications.Message\x1a\x16.google.protobuf.Empty\x12D\n" +
+	"\n" +
+	"NotifyRoom\x12\x1e.notifications.MessageWithRoom\x1a\x16.google.protobuf.Empty\x12G\n" +
+	"\rPrivateNotify\x12\x1e.notifications.MessageWithUser\x1a\x16.google.protobuf.Empty\x12M\n" +
+	"\vNotifyUsers\x12\x1f.notifications.MessageWithUsers\x1a\x1d.notifications.DeliveryReport\x12M\n" +
+	"\vNotifyRooms\x12\x1f.notifications.MessageWithRooms\x1a\x1d.notifications.DeliveryReport\x12O\n" +
+	"\rPublishStream\x12\x1d.notifications.PublishRequest\x1a\x1d.notifications.DeliveryReport(\x01\x12P\n" +
+	"\x0fCancelScheduled\x12%.notifications.CancelScheduledRequest\x1a\x16.google.protobuf.Empty\x12E\n" +
+	"\rListScheduled\x12\x16.google.protobuf.Empty\x1a\x1c.notifications.ScheduledList\x12Q\n" +
+	"\n" +
+	"RevokeUser\x12 .notifications.RevokeUserRequest\x1a!.notifications.RevokeUserResponse\x12R\n" +
+	"\rSubscribeUser\x12\x1f.notifications.UserRoomsRequest\x1a .notifications.UserRoomsResponse\x12T\n" +
+	"\x0fUnsubscribeUser\x12\x1f.notifications.UserRoomsRequest\x1a .notifications.UserRoomsResponse2\xe3\x04\n" +
+	"\fAdminService\x12`\n" +
+	"\x0fListConnections\x12%.notifications.ListConnectionsRequest\x1a&.notifications.ListConnectionsResponse\x12N\n" +
+	"\tListRooms\x12\x1f.notifications.ListRoomsRequest\x1a .notifications.ListRoomsResponse\x12=\n" +
+	"\aGetRoom\x12\x1d.notifications.GetRoomRequest\x1a\x13.notifications.Room\x12Z\n" +
+	"\x14DisconnectConnection\x12*.notifications.DisconnectConnectionRequest\x1a\x16.google.protobuf.Empty\x12]\n" +
+	"\x0eDisconnectUser\x12$.notifications.DisconnectUserRequest\x1a%.notifications.DisconnectUserResponse\x12W\n" +
+	"\fKickFromRoom\x12\".notifications.KickFromRoomRequest\x1a#.notifications.KickFromRoomResponse\x12N\n" +
+	"\tCloseRoom\x12\x1f.notifications.CloseRoomRequest\x1a .notifications.CloseRoomResponseB\x13Z\x11./notificationspbb\x06proto3"
+
+var (
+	file_notificationspb_message_proto_rawDescOnce sync.Once
+	file_notificationspb_message_proto_rawDescData []byte
+)
+
+func file_notificationspb_message_proto_rawDescGZIP() []byte {
+	file_notificationspb_message_proto_rawDescOnce.Do(func() {
+		file_notificationspb_message_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_notificationspb_message_proto_rawDesc), len(file_notificationspb_message_proto_rawDesc)))
+	})
+	return file_notificationspb_message_proto_rawDescData
+}
+
+var file_notificationspb_message_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_notificationspb_message_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
+var file_notificationspb_message_proto_goTypes = []any{
+	(MessageType)(0),                    // 0: notifications.MessageType
+	(*Message)(nil),                     // 1: notifications.Message
+	(*MessageWithRoom)(nil),             // 2: notifications.MessageWithRoom
+	(*MessageWithUser)(nil),             // 3: notifications.MessageWithUser
+	(*MessageWithUsers)(nil),            // 4: notifications.MessageWithUsers
+	(*MessageWithRooms)(nil),            // 5: notifications.MessageWithRooms
+	(*SocketFrame)(nil),                 // 6: notifications.SocketFrame
+	(*SocketEvent)(nil),                 // 7: notifications.SocketEvent
+	(*PublishRequest)(nil),              // 8: notifications.PublishRequest
+	(*Delivery)(nil),                    // 9: notifications.Delivery
+	(*DeliveryReport)(nil),              // 10: notifications.DeliveryReport
+	(*ScheduledNotification)(nil),       // 11: notifications.ScheduledNotification
+	(*ScheduledList)(nil),               // 12: notifications.ScheduledList
+	(*CancelScheduledRequest)(nil),      // 13: notifications.CancelScheduledRequest
+	(*RevokeUserRequest)(nil),           // 14: notifications.RevokeUserRequest
+	(*RevokeUserResponse)(nil),          // 15: notifications.RevokeUserResponse
+	(*UserRoomsRequest)(nil),            // 16: notifications.UserRoomsRequest
+	(*UserRoomsResponse)(nil),           // 17: notifications.UserRoomsResponse
+	(*Connection)(nil),                  // 18: notifications.Connection
+	(*ListConnectionsRequest)(nil),      // 19: notifications.ListConnectionsRequest
+	(*ListConnectionsResponse)(nil),     // 20: notifications.ListConnectionsResponse
+	(*RoomSummary)(nil),                 // 21: notifications.RoomSummary
+	(*ListRoomsRequest)(nil),            // 22: notifications.ListRoomsRequest
+	(*ListRoomsResponse)(nil),           // 23: notifications.ListRoomsResponse
+	(*GetRoomRequest)(nil),              // 24: notifications.GetRoomRequest
+	(*Room)(nil),                        // 25: notifications.Room
+	(*DisconnectConnectionRequest)(nil), // 26: notifications.DisconnectConnectionRequest
+	(*DisconnectUserRequest)(nil),       // 27: notifications.DisconnectUserRequest
+	(*DisconnectUserResponse)(nil),      // 28: notifications.DisconnectUserResponse
+	(*KickFromRoomRequest)(nil),         // 29: notifications.KickFromRoomRequest
+	(*KickFromRoomResponse)(nil),        // 30: notifications.KickFromRoomResponse
+	(*CloseRoomRequest)(nil),            // 31: notifications.CloseRoomRequest
+	(*CloseRoomResponse)(nil),           // 32: notifications.CloseRoomResponse
+	(*any1.Any)(nil),                    // 33: google.protobuf.Any
+	(*timestamp.Timestamp)(nil),         // 34: google.protobuf.Timestamp
+	(*duration.Duration)(nil),           // 35: google.protobuf.Duration
+	(*structpb.Struct)(nil),             // 36: google.protobuf.Struct
+	(*empty.Empty)(nil),                 // 37: google.protobuf.Empty
+}
+var file_notificationspb_message_proto_depIdxs = []int32{
+	0,  // 0: notifications.Message.type:type_name -> notifications.MessageType
+	33, // 1: notifications.Message.message:type_name -> google.protobuf.Any
+	34, // 2: notifications.Message.deliverAt:type_name -> google.protobuf.Timestamp
+	35, // 3: notifications.Message.delay:type_name -> google.protobuf.Duration
+	34, // 4: notifications.Message.expiresAt:type_name -> google.protobuf.Timestamp
+	35, // 5: notifications.Message.ttl:type_name -> google.protobuf.Duration
+	1,  // 6: notifications.MessageWithRoom.base:type_name -> notifications.Message
+	1,  // 7: notifications.MessageWithUser.base:type_name -> notifications.Message
+	1,  // 8: notifications.MessageWithUsers.base:type_name -> notifications.Message
+	1,  // 9: notifications.MessageWithRooms.base:type_name -> notifications.Message
+	1,  // 10: notifications.SocketFrame.notification:type_name -> notifications.Message
+	7,  // 11: notifications.SocketFrame.event:type_name -> notifications.SocketEvent
+	36, // 12: notifications.SocketEvent.data:type_name -> google.protobuf.Struct
+	1,  // 13: notifications.PublishRequest.broadcast:type_name -> notifications.Message
+	2,  // 14: notifications.PublishRequest.room:type_name -> notifications.MessageWithRoom
+	3,  // 15: notifications.PublishRequest.user:type_name -> notifications.MessageWithUser
+	4,  // 16: notifications.PublishRequest.users:type_name -> notifications.MessageWithUsers
+	5,  // 17: notifications.PublishRequest.rooms:type_name -> notifications.MessageWithRooms
+	9,  // 18: notifications.DeliveryReport.users:type_name -> notifications.Delivery
+	9,  // 19: notifications.DeliveryReport.rooms:type_name -> notifications.Delivery
+	34, // 20: notifications.ScheduledNotification.deliverAt:type_name -> google.protobuf.Timestamp
+	8,  // 21: notifications.ScheduledNotification.publish:type_name -> notifications.PublishRequest
+	11, // 22: notifications.ScheduledList.notifications:type_name -> notifications.ScheduledNotification
+	34, // 23: notifications.Connection.connectedAt:type_name -> google.protobuf.Timestamp
+	18, // 24: notifications.ListConnectionsResponse.connections:type_name -> notifications.Connection
+	21, // 25: notifications.ListRoomsResponse.rooms:type_name -> notifications.RoomSummary
+	18, // 26: notifications.Room.members:type_name -> notifications.Connection
+	1,  // 27: notifications.NotificationService.Broadcast:input_type -> notifications.Message
+	2,  // 28: notifications.NotificationService.NotifyRoom:input_type -> notifications.MessageWithRoom
+	3,  // 29: notifications.NotificationService.PrivateNotify:input_type -> notifications.MessageWithUser
+	4,  // 30: notifications.NotificationService.NotifyUsers:input_type -> notifications.MessageWithUsers
+	5,  // 31: notifications.NotificationService.NotifyRooms:input_type -> notifications.MessageWithRooms
+	8,  // 32: notifications.NotificationService.PublishStream:input_type -> notifications.PublishRequest
+	13, // 33: notifications.NotificationService.CancelScheduled:input_type -> notifications.CancelScheduledRequest
+	37, // 34: notifications.NotificationService.ListScheduled:input_type -> google.protobuf.Empty
+	14, // 35: notifications.NotificationService.RevokeUser:input_type -> notifications.RevokeUserRequest
+	16, // 36: notifications.NotificationService.SubscribeUser:input_type -> notifications.UserRoomsRequest
+	16, // 37: notifications.NotificationService.UnsubscribeUser:input_type -> notifications.UserRoomsRequest
+	19, // 38: notifications.AdminService.ListConnections:input_type -> notifications.ListConnectionsRequest
+	22, // 39: notifications.AdminService.ListRooms:input_type -> notifications.ListRoomsRequest
+	24, // 40: notifications.AdminService.GetRoom:input_type -> notifications.GetRoomRequest
+	26, // 41: notifications.AdminService.DisconnectConnection:input_type -> notifications.DisconnectConnectionRequest
+	27, // 42: notifications.AdminService.DisconnectUser:input_type -> notifications.DisconnectUserRequest
+	29, // 43: notifications.AdminService.KickFromRoom:input_type -> notifications.KickFromRoomRequest
+	31, // 44: notifications.AdminService.CloseRoom:input_type -> notifications.CloseRoomRequest
+	37, // 45: notifications.NotificationService.Broadcast:output_type -> google.protobuf.Empty
+	37, // 46: notifications.NotificationService.NotifyRoom:output_type -> google.protobuf.Empty
+	37, // 47: notifications.NotificationService.PrivateNotify:output_type -> google.protobuf.Empty
+	10, // 48: notifications.NotificationService.NotifyUsers:output_type -> notifications.DeliveryReport
+	10, // 49: notifications.NotificationService.NotifyRooms:output_type -> notifications.DeliveryReport
+	10, // 50: notifications.NotificationService.PublishStream:output_type -> notifications.DeliveryReport
+	37, // 51: notifications.NotificationService.CancelScheduled:output_type -> google.protobuf.Empty
+	12, // 52: notifications.NotificationService.ListScheduled:output_type -> notifications.ScheduledList
+	15, // 53: notifications.NotificationService.RevokeUser:output_type -> notifications.RevokeUserResponse
+	17, // 54: notifications.NotificationService.SubscribeUser:output_type -> notifications.UserRoomsResponse
+	17, // 55: notifications.NotificationService.UnsubscribeUser:output_type -> notifications.UserRoomsResponse
+	20, // 56: notifications.AdminService.ListConnections:output_type -> notifications.ListConnectionsResponse
+	23, // 57: notifications.AdminService.ListRooms:output_type -> notifications.ListRoomsResponse
+	25, // 58: notifications.AdminService.GetRoom:output_type -> notifications.Room
+	37, // 59: notifications.AdminService.DisconnectConnection:output_type -> google.protobuf.Empty
+	28, // 60: notifications.AdminService.DisconnectUser:output_type -> notifications.DisconnectUserResponse
+	30, // 61: notifications.AdminService.KickFromRoom:output_type -> notifications.KickFromRoomResponse
+	32, // 62: notifications.AdminService.CloseRoom:output_type -> notifications.CloseRoomResponse
+	45, // [45:63] is the sub-list for method output_type
+	27, // [27:45] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
+}
+
+func init() { file_notificationspb_message_proto_init() }
+func file_notificationspb_message_proto_init() {
+	if File_notificationspb_message_proto != nil {
+		return
+	}
+	file_notificationspb_message_proto_msgTypes[1].OneofWrappers = []any{}
+	file_notificationspb_message_proto_msgTypes[5].OneofWrappers = []any{
+		(*SocketFrame_Notification)(nil),
+		(*SocketFrame_Event)(nil),
+	}
+	file_notificationspb_message_proto_msgTypes[7].OneofWrappers = []any{
+		(*PublishRequest_Broadcast)(nil),
+		(*PublishRequest_Room)(nil),
+		(*PublishRequest_User)(nil),
+		(*PublishRequest_Users)(nil),
+		(*PublishRequest_Rooms)(nil),
 	}
-	file_notificationspb_message_proto_msgTypes[1].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_notificationspb_message_proto_rawDesc), len(file_notificationspb_message_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   3,
+			NumMessages:   32,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_notificationspb_message_proto_goTypes,
 		DependencyIndexes: file_notificationspb_message_proto_depIdxs,
@@ -20,9 +20,17 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	NotificationService_Broadcast_FullMethodName     = "/notifications.NotificationService/Broadcast"
-	NotificationService_NotifyRoom_FullMethodName    = "/notifications.NotificationService/NotifyRoom"
-	NotificationService_PrivateNotify_FullMethodName = "/notifications.NotificationService/PrivateNotify"
+	NotificationService_Broadcast_FullMethodName       = "/notifications.NotificationService/Broadcast"
+	NotificationService_NotifyRoom_FullMethodName      = "/notifications.NotificationService/NotifyRoom"
+	NotificationService_PrivateNotify_FullMethodName   = "/notifications.NotificationService/PrivateNotify"
+	NotificationService_NotifyUsers_FullMethodName     = "/notifications.NotificationService/NotifyUsers"
+	NotificationService_NotifyRooms_FullMethodName     = "/notifications.NotificationService/NotifyRooms"
+	NotificationService_PublishStream_FullMethodName   = "/notifications.NotificationService/PublishStream"
+	NotificationService_CancelScheduled_FullMethodName = "/notifications.NotificationService/CancelScheduled"
+	NotificationService_ListScheduled_FullMethodName   = "/notifications.NotificationService/ListScheduled"
+	NotificationService_RevokeUser_FullMethodName      = "/notifications.NotificationService/RevokeUser"
+	NotificationService_SubscribeUser_FullMethodName   = "/notifications.NotificationService/SubscribeUser"
+	NotificationService_UnsubscribeUser_FullMethodName = "/notifications.NotificationService/UnsubscribeUser"
 )
 
 // NotificationServiceClient is the client API for NotificationService service.
@@ -32,6 +40,14 @@ type NotificationServiceClient interface {
 	Broadcast(ctx context.Context, in *Message, opts ...grpc.CallOption) (*empty.Empty, error)
 	NotifyRoom(ctx context.Context, in *MessageWithRoom, opts ...grpc.CallOption) (*empty.Empty, error)
 	PrivateNotify(ctx context.Context, in *MessageWithUser, opts ...grpc.CallOption) (*empty.Empty, error)
+	NotifyUsers(ctx context.Context, in *MessageWithUsers, opts ...grpc.CallOption) (*DeliveryReport, error)
+	NotifyRooms(ctx context.Context, in *MessageWithRooms, opts ...grpc.CallOption) (*DeliveryReport, error)
+	PublishStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PublishRequest, DeliveryReport], error)
+	CancelScheduled(ctx context.Context, in *CancelScheduledRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListScheduled(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ScheduledList, error)
+	RevokeUser(ctx context.Context, in *RevokeUserRequest, opts ...grpc.CallOption) (*RevokeUserResponse, error)
+	SubscribeUser(ctx context.Context, in *UserRoomsRequest, opts ...grpc.CallOption) (*UserRoomsResponse, error)
+	UnsubscribeUser(ctx context.Context, in *UserRoomsRequest, opts ...grpc.CallOption) (*UserRoomsResponse, error)
 }
 
 type notificationServiceClient struct {
@@ -72,6 +88,89 @@ func (c *notificationServiceClient) PrivateNotify(ctx context.Context, in *Messa
 	return out, nil
 }
 
+func (c *notificationServiceClient) NotifyUsers(ctx context.Context, in *MessageWithUsers, opts ...grpc.CallOption) (*DeliveryReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeliveryReport)
+	err := c.cc.Invoke(ctx, NotificationService_NotifyUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) NotifyRooms(ctx context.Context, in *MessageWithRooms, opts ...grpc.CallOption) (*DeliveryReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeliveryReport)
+	err := c.cc.Invoke(ctx, NotificationService_NotifyRooms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) PublishStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[PublishRequest, DeliveryReport], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &NotificationService_ServiceDesc.Streams[0], NotificationService_PublishStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[PublishRequest, DeliveryReport]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NotificationService_PublishStreamClient = grpc.ClientStreamingClient[PublishRequest, DeliveryReport]
+
+func (c *notificationServiceClient) CancelScheduled(ctx context.Context, in *CancelScheduledRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, NotificationService_CancelScheduled_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) ListScheduled(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*ScheduledList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ScheduledList)
+	err := c.cc.Invoke(ctx, NotificationService_ListScheduled_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) RevokeUser(ctx context.Context, in *RevokeUserRequest, opts ...grpc.CallOption) (*RevokeUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevokeUserResponse)
+	err := c.cc.Invoke(ctx, NotificationService_RevokeUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) SubscribeUser(ctx context.Context, in *UserRoomsRequest, opts ...grpc.CallOption) (*UserRoomsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserRoomsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_SubscribeUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *notificationServiceClient) UnsubscribeUser(ctx context.Context, in *UserRoomsRequest, opts ...grpc.CallOption) (*UserRoomsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserRoomsResponse)
+	err := c.cc.Invoke(ctx, NotificationService_UnsubscribeUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NotificationServiceServer is the server API for NotificationService service.
 // All implementations must embed UnimplementedNotificationServiceServer
 // for forward compatibility.
@@ -79,6 +178,14 @@ type NotificationServiceServer interface {
 	Broadcast(context.Context, *Message) (*empty.Empty, error)
 	NotifyRoom(context.Context, *MessageWithRoom) (*empty.Empty, error)
 	PrivateNotify(context.Context, *MessageWithUser) (*empty.Empty, error)
+	NotifyUsers(context.Context, *MessageWithUsers) (*DeliveryReport, error)
+	NotifyRooms(context.Context, *MessageWithRooms) (*DeliveryReport, error)
+	PublishStream(grpc.ClientStreamingServer[PublishRequest, DeliveryReport]) error
+	CancelScheduled(context.Context, *CancelScheduledRequest) (*empty.Empty, error)
+	ListScheduled(context.Context, *empty.Empty) (*ScheduledList, error)
+	RevokeUser(context.Context, *RevokeUserRequest) (*RevokeUserResponse, error)
+	SubscribeUser(context.Context, *UserRoomsRequest) (*UserRoomsResponse, error)
+	UnsubscribeUser(context.Context, *UserRoomsRequest) (*UserRoomsResponse, error)
 	mustEmbedUnimplementedNotificationServiceServer()
 }
 
@@ -98,6 +205,30 @@ func (UnimplementedNotificationServiceServer) NotifyRoom(context.Context, *Messa
 func (UnimplementedNotificationServiceServer) PrivateNotify(context.Context, *MessageWithUser) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method PrivateNotify not implemented")
 }
+func (UnimplementedNotificationServiceServer) NotifyUsers(context.Context, *MessageWithUsers) (*DeliveryReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NotifyUsers not implemented")
+}
+func (UnimplementedNotificationServiceServer) NotifyRooms(context.Context, *MessageWithRooms) (*DeliveryReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NotifyRooms not implemented")
+}
+func (UnimplementedNotificationServiceServer) PublishStream(grpc.ClientStreamingServer[PublishRequest, DeliveryReport]) error {
+	return status.Errorf(codes.Unimplemented, "method PublishStream not implemented")
+}
+func (UnimplementedNotificationServiceServer) CancelScheduled(context.Context, *CancelScheduledRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelScheduled not implemented")
+}
+func (UnimplementedNotificationServiceServer) ListScheduled(context.Context, *empty.Empty) (*ScheduledList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListScheduled not implemented")
+}
+func (UnimplementedNotificationServiceServer) RevokeUser(context.Context, *RevokeUserRequest) (*RevokeUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeUser not implemented")
+}
+func (UnimplementedNotificationServiceServer) SubscribeUser(context.Context, *UserRoomsRequest) (*UserRoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubscribeUser not implemented")
+}
+func (UnimplementedNotificationServiceServer) UnsubscribeUser(context.Context, *UserRoomsRequest) (*UserRoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnsubscribeUser not implemented")
+}
 func (UnimplementedNotificationServiceServer) mustEmbedUnimplementedNotificationServiceServer() {}
 func (UnimplementedNotificationServiceServer) testEmbeddedByValue()                             {}
 
@@ -173,6 +304,139 @@ func _NotificationService_PrivateNotify_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NotificationService_NotifyUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MessageWithUsers)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).NotifyUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_NotifyUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).NotifyUsers(ctx, req.(*MessageWithUsers))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_NotifyRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MessageWithRooms)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).NotifyRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_NotifyRooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).NotifyRooms(ctx, req.(*MessageWithRooms))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_PublishStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(NotificationServiceServer).PublishStream(&grpc.GenericServerStream[PublishRequest, DeliveryReport]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type NotificationService_PublishStreamServer = grpc.ClientStreamingServer[PublishRequest, DeliveryReport]
+
+func _NotificationService_CancelScheduled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelScheduledRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).CancelScheduled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_CancelScheduled_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).CancelScheduled(ctx, req.(*CancelScheduledRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_ListScheduled_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).ListScheduled(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_ListScheduled_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).ListScheduled(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_RevokeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).RevokeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_RevokeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).RevokeUser(ctx, req.(*RevokeUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_SubscribeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).SubscribeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_SubscribeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).SubscribeUser(ctx, req.(*UserRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NotificationService_UnsubscribeUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NotificationServiceServer).UnsubscribeUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NotificationService_UnsubscribeUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NotificationServiceServer).UnsubscribeUser(ctx, req.(*UserRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NotificationService_ServiceDesc is the grpc.ServiceDesc for NotificationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -192,6 +456,376 @@ var NotificationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PrivateNotify",
 			Handler:    _NotificationService_PrivateNotify_Handler,
 		},
+		{
+			MethodName: "NotifyUsers",
+			Handler:    _NotificationService_NotifyUsers_Handler,
+		},
+		{
+			MethodName: "NotifyRooms",
+			Handler:    _NotificationService_NotifyRooms_Handler,
+		},
+		{
+			MethodName: "CancelScheduled",
+			Handler:    _NotificationService_CancelScheduled_Handler,
+		},
+		{
+			MethodName: "ListScheduled",
+			Handler:    _NotificationService_ListScheduled_Handler,
+		},
+		{
+			MethodName: "RevokeUser",
+			Handler:    _NotificationService_RevokeUser_Handler,
+		},
+		{
+			MethodName: "SubscribeUser",
+			Handler:    _NotificationService_SubscribeUser_Handler,
+		},
+		{
+			MethodName: "UnsubscribeUser",
+			Handler:    _NotificationService_UnsubscribeUser_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "PublishStream",
+			Handler:       _NotificationService_PublishStream_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "notificationspb/message.proto",
+}
+
+const (
+	AdminService_ListConnections_FullMethodName      = "/notifications.AdminService/ListConnections"
+	AdminService_ListRooms_FullMethodName            = "/notifications.AdminService/ListRooms"
+	AdminService_GetRoom_FullMethodName              = "/notifications.AdminService/GetRoom"
+	AdminService_DisconnectConnection_FullMethodName = "/notifications.AdminService/DisconnectConnection"
+	AdminService_DisconnectUser_FullMethodName       = "/notifications.AdminService/DisconnectUser"
+	AdminService_KickFromRoom_FullMethodName         = "/notifications.AdminService/KickFromRoom"
+	AdminService_CloseRoom_FullMethodName            = "/notifications.AdminService/CloseRoom"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Operator service for inspecting and managing the hub, guarded by the admin
+// token. Requests act on the tenant they name, the default one when empty.
+type AdminServiceClient interface {
+	ListConnections(ctx context.Context, in *ListConnectionsRequest, opts ...grpc.CallOption) (*ListConnectionsResponse, error)
+	ListRooms(ctx context.Context, in *ListRoomsRequest, opts ...grpc.CallOption) (*ListRoomsResponse, error)
+	GetRoom(ctx context.Context, in *GetRoomRequest, opts ...grpc.CallOption) (*Room, error)
+	DisconnectConnection(ctx context.Context, in *DisconnectConnectionRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error)
+	KickFromRoom(ctx context.Context, in *KickFromRoomRequest, opts ...grpc.CallOption) (*KickFromRoomResponse, error)
+	CloseRoom(ctx context.Context, in *CloseRoomRequest, opts ...grpc.CallOption) (*CloseRoomResponse, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) ListConnections(ctx context.Context, in *ListConnectionsRequest, opts ...grpc.CallOption) (*ListConnectionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListConnectionsResponse)
+	err := c.cc.Invoke(ctx, AdminService_ListConnections_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) ListRooms(ctx context.Context, in *ListRoomsRequest, opts ...grpc.CallOption) (*ListRoomsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRoomsResponse)
+	err := c.cc.Invoke(ctx, AdminService_ListRooms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetRoom(ctx context.Context, in *GetRoomRequest, opts ...grpc.CallOption) (*Room, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Room)
+	err := c.cc.Invoke(ctx, AdminService_GetRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DisconnectConnection(ctx context.Context, in *DisconnectConnectionRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, AdminService_DisconnectConnection_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DisconnectUser(ctx context.Context, in *DisconnectUserRequest, opts ...grpc.CallOption) (*DisconnectUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DisconnectUserResponse)
+	err := c.cc.Invoke(ctx, AdminService_DisconnectUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) KickFromRoom(ctx context.Context, in *KickFromRoomRequest, opts ...grpc.CallOption) (*KickFromRoomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KickFromRoomResponse)
+	err := c.cc.Invoke(ctx, AdminService_KickFromRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CloseRoom(ctx context.Context, in *CloseRoomRequest, opts ...grpc.CallOption) (*CloseRoomResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CloseRoomResponse)
+	err := c.cc.Invoke(ctx, AdminService_CloseRoom_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// Operator service for inspecting and managing the hub, guarded by the admin
+// token. Requests act on the tenant they name, the default one when empty.
+type AdminServiceServer interface {
+	ListConnections(context.Context, *ListConnectionsRequest) (*ListConnectionsResponse, error)
+	ListRooms(context.Context, *ListRoomsRequest) (*ListRoomsResponse, error)
+	GetRoom(context.Context, *GetRoomRequest) (*Room, error)
+	DisconnectConnection(context.Context, *DisconnectConnectionRequest) (*empty.Empty, error)
+	DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error)
+	KickFromRoom(context.Context, *KickFromRoomRequest) (*KickFromRoomResponse, error)
+	CloseRoom(context.Context, *CloseRoomRequest) (*CloseRoomResponse, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) ListConnections(context.Context, *ListConnectionsRequest) (*ListConnectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConnections not implemented")
+}
+func (UnimplementedAdminServiceServer) ListRooms(context.Context, *ListRoomsRequest) (*ListRoomsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRooms not implemented")
+}
+func (UnimplementedAdminServiceServer) GetRoom(context.Context, *GetRoomRequest) (*Room, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRoom not implemented")
+}
+func (UnimplementedAdminServiceServer) DisconnectConnection(context.Context, *DisconnectConnectionRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectConnection not implemented")
+}
+func (UnimplementedAdminServiceServer) DisconnectUser(context.Context, *DisconnectUserRequest) (*DisconnectUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisconnectUser not implemented")
+}
+func (UnimplementedAdminServiceServer) KickFromRoom(context.Context, *KickFromRoomRequest) (*KickFromRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KickFromRoom not implemented")
+}
+func (UnimplementedAdminServiceServer) CloseRoom(context.Context, *CloseRoomRequest) (*CloseRoomResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseRoom not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_ListConnections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListConnectionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListConnections(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ListConnections_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListConnections(ctx, req.(*ListConnectionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_ListRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRoomsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).ListRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_ListRooms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).ListRooms(ctx, req.(*ListRoomsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetRoom(ctx, req.(*GetRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DisconnectConnection_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectConnectionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DisconnectConnection(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DisconnectConnection_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DisconnectConnection(ctx, req.(*DisconnectConnectionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DisconnectUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisconnectUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DisconnectUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_DisconnectUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DisconnectUser(ctx, req.(*DisconnectUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_KickFromRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KickFromRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).KickFromRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_KickFromRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).KickFromRoom(ctx, req.(*KickFromRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CloseRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseRoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CloseRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_CloseRoom_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CloseRoom(ctx, req.(*CloseRoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "notifications.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListConnections",
+			Handler:    _AdminService_ListConnections_Handler,
+		},
+		{
+			MethodName: "ListRooms",
+			Handler:    _AdminService_ListRooms_Handler,
+		},
+		{
+			MethodName: "GetRoom",
+			Handler:    _AdminService_GetRoom_Handler,
+		},
+		{
+			MethodName: "DisconnectConnection",
+			Handler:    _AdminService_DisconnectConnection_Handler,
+		},
+		{
+			MethodName: "DisconnectUser",
+			Handler:    _AdminService_DisconnectUser_Handler,
+		},
+		{
+			MethodName: "KickFromRoom",
+			Handler:    _AdminService_KickFromRoom_Handler,
+		},
+		{
+			MethodName: "CloseRoom",
+			Handler:    _AdminService_CloseRoom_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "notificationspb/message.proto",